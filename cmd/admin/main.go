@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"linkshortener/internal/database/clickhouse"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: admin <command>
+
+Commands:
+  storage    report ClickHouse storage per table partition
+`
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("Error loading .env file", "error", err)
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "storage":
+		err = reportStorage(context.Background())
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("Command failed", "command", os.Args[1], "error", err)
+		os.Exit(1)
+	}
+}
+
+func reportStorage(ctx context.Context) error {
+	analytics, err := clickhouse.Connect(clickhouse.Config{
+		Addr:     os.Getenv("CLICKHOUSE_ADDR"),
+		User:     os.Getenv("CLICKHOUSE_USER"),
+		Password: os.Getenv("CLICKHOUSE_PASSWORD"),
+		Database: os.Getenv("CLICKHOUSE_DB"),
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer analytics.Close()
+
+	stats, err := analytics.PartitionStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "TABLE\tPARTITION\tPARTS\tROWS\tSIZE\t")
+	var totalRows, totalBytes uint64
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t\n", s.Table, s.Partition, s.Parts, s.Rows, formatBytes(s.BytesOnDisk))
+		totalRows += s.Rows
+		totalBytes += s.BytesOnDisk
+	}
+	fmt.Fprintf(w, "total\t\t\t%d\t%s\t\n", totalRows, formatBytes(totalBytes))
+	return w.Flush()
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatUint(n, 10) + " B"
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
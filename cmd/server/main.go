@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"linkshortener/internal/alerts"
 	"linkshortener/internal/database"
-	"linkshortener/internal/database/clickhouse"
-	"linkshortener/internal/database/postgresql"
-	"linkshortener/internal/database/redis"
+	"linkshortener/internal/digest"
+	"linkshortener/internal/geoip"
 	"linkshortener/internal/service"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"linkshortener/internal/bot"
 
@@ -30,69 +32,110 @@ func main() {
 
 	slog.Info("Starting LinkShortener service...", "port", os.Getenv("PORT"))
 
-	clickhouseAddr := os.Getenv("CLICKHOUSE_ADDR")
-	clickhouseUser := os.Getenv("CLICKHOUSE_USER")
-	clickhousePassword := os.Getenv("CLICKHOUSE_PASSWORD")
-	clickhouseDb := os.Getenv("CLICKHOUSE_DB")
-	postgresURL := os.Getenv("DB_URL")
-	redisUrl := os.Getenv("REDIS_ADDR")
-	redisPassword := os.Getenv("REDIS_PASSWORD")
+	storage := getEnv("STORAGE", storageExternal)
 	tgToken := os.Getenv("TELEGRAM_API_TOKEN")
 	port := os.Getenv("PORT")
 	baseLink := os.Getenv("BASE_LINK")
 
-	if clickhouseAddr == "" ||
-		clickhouseUser == "" ||
-		clickhousePassword == "" ||
-		clickhouseDb == "" ||
-		postgresURL == "" ||
-		redisUrl == "" ||
-		redisPassword == "" ||
-		tgToken == "" ||
+	if tgToken == "" ||
 		port == "" ||
 		baseLink == "" {
 		slog.Error("Missing required environment variables")
 		return
 	}
+	if storage != storageExternal && storage != storageMemory {
+		slog.Error("Unknown storage mode", "storage", storage)
+		return
+	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
-	sql, err := postgresql.Connect(ctx, postgresURL)
+	ipMode, err := service.ParseIPMode(getEnv("PRIVACY_IP_MODE", string(service.IPModeTruncate)))
 	if err != nil {
-		slog.Error("Could not connect to Postgres", "error", err)
+		slog.Error("Invalid privacy configuration", "error", err)
 		return
 	}
-	defer sql.Close()
-
-	cache, err := redis.Connect(redisUrl, redisPassword)
+	trustedProxies, err := service.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
 	if err != nil {
-		slog.Error("Could not connect to Redis", "error", err)
+		slog.Error("Invalid trusted proxies configuration", "error", err)
 		return
 	}
-	defer cache.Close()
-
-	analytics, err := clickhouse.Connect(clickhouseAddr, clickhouseUser, clickhousePassword, clickhouseDb)
+	forwardedHeader, err := service.ParseForwardedHeader(getEnv("TRUSTED_PROXY_HEADER", string(service.HeaderXForwardedFor)))
 	if err != nil {
-		slog.Error("Could not connect to ClickHouse", "error", err)
+		slog.Error("Invalid trusted proxy header", "error", err)
 		return
 	}
-	defer analytics.Close()
-	analytics.Start(ctx)
 
-	db := database.CreateDatabase(ctx, analytics, sql, cache)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	geo := geoip.Open(getEnv("GEOIP_CITY_PATH", "GeoLite2-City.mmdb"), os.Getenv("GEOIP_ASN_PATH"))
+	defer geo.Close()
+	go geo.Watch(ctx, getEnvDuration("GEOIP_RELOAD_INTERVAL", time.Minute))
+
+	var store *backends
+	if storage == storageMemory {
+		slog.Warn("Using in-memory storage, nothing will survive a restart")
+		store = openMemory(geo)
+	} else {
+		store, err = openExternal(ctx, geo)
+		if err != nil {
+			return
+		}
+	}
+	defer store.close()
+
+	db := database.CreateDatabase(ctx, store.analytics, store.sql, store.cache, database.CacheConfig{
+		TTL:         getEnvDuration("CACHE_TTL", 10*time.Minute),
+		NotFoundTTL: getEnvDuration("CACHE_NOT_FOUND_TTL", 30*time.Second),
+	})
+
+	if limit := getEnvInt("CACHE_WARMUP_LIMIT", 1000); limit > 0 {
+		warmCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		warmed, err := db.WarmUp(warmCtx, time.Now().Add(-getEnvDuration("CACHE_WARMUP_WINDOW", 24*time.Hour)), limit)
+		cancel()
+		if err != nil {
+			slog.Warn("Cache warm-up incomplete", "warmed", warmed, "error", err)
+		} else {
+			slog.Info("Cache warmed up", "links", warmed)
+		}
+	}
 
 	shortener := service.NewShortener(db)
 
+	broker := store.broker
+	go broker.Run(ctx)
+
 	tgBot, err := bot.NewTelegramBot(baseLink, tgToken, db, shortener)
 	if err != nil {
 		slog.Error("Could not initialize bot", "error", err)
 		return
 	}
+	monitor := alerts.NewMonitor(alerts.Config{
+		Interval: getEnvDuration("ALERTS_INTERVAL", 15*time.Minute),
+		Window:   getEnvDuration("ALERTS_WINDOW", time.Hour),
+		Baseline: getEnvDuration("ALERTS_BASELINE", 24*time.Hour),
+		Cooldown: getEnvDuration("ALERTS_COOLDOWN", 6*time.Hour),
+	}, db, tgBot)
+	go monitor.Run(ctx)
+
+	digests := digest.NewScheduler(getEnvDuration("DIGEST_INTERVAL", time.Minute), db, db, tgBot)
+	go digests.Run(ctx)
+
 	botErr := make(chan error, 1)
 	go func() { botErr <- tgBot.Start(ctx) }()
 
-	server := service.NewServer(port, db, shortener)
+	server := service.NewServer(service.ServerConfig{
+		Port:      port,
+		AdminAddr: getEnv("ADMIN_ADDR", "127.0.0.1:9090"),
+		Privacy: service.PrivacyConfig{
+			IPMode:   ipMode,
+			HonorDNT: getEnvBool("PRIVACY_HONOR_DNT", true),
+		},
+		TrustedProxies:  trustedProxies,
+		ForwardedHeader: forwardedHeader,
+		ExportMaxRows:   getEnvInt("EXPORT_MAX_ROWS", 1_000_000),
+		Live:            broker,
+		Geo:             geo,
+	}, db, shortener)
 	serverErr := make(chan error, 1)
 	go func() { serverErr <- server.Start(ctx) }()
 
@@ -115,3 +158,49 @@ func main() {
 
 	slog.Info("Shutting down gracefully...")
 }
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("Invalid integer in environment, using default", "key", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		slog.Warn("Invalid duration in environment, using default", "key", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return d
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		slog.Warn("Invalid boolean in environment, using default", "key", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return b
+}
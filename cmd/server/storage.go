@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/clickhouse"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/database/memory"
+	"linkshortener/internal/database/pganalytics"
+	"linkshortener/internal/database/postgresql"
+	"linkshortener/internal/database/redis"
+	"linkshortener/internal/database/sqlite"
+	"linkshortener/internal/database/tiered"
+	"linkshortener/internal/geoip"
+	"linkshortener/internal/live"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// storageExternal keeps links in PostgreSQL or SQLite (see openSQL),
+	// caches them in Redis and stores clicks in ClickHouse or PostgreSQL
+	// (see openAnalytics).
+	storageExternal = "external"
+	// storageMemory keeps everything in process memory, for local
+	// development without any of the above.
+	storageMemory = "memory"
+
+	analyticsClickHouse = "clickhouse"
+	analyticsPostgres   = "postgres"
+)
+
+var errBadConfig = errors.New("missing or invalid storage configuration")
+
+type backends struct {
+	analytics database.Analytics
+	sql       database.SQL
+	cache     database.Cache
+	broker    *live.Broker
+	closers   []func()
+}
+
+// close releases the backends in the reverse order of opening.
+func (b *backends) close() {
+	for _, c := range slices.Backward(b.closers) {
+		c()
+	}
+}
+
+// openMemory registers closers like openExternal, so that the shutdown
+// path is the same in both modes. The in-process broker has no Redis
+// subscription and needs no closer.
+func openMemory(geo *geoip.Resolver) *backends {
+	b := &backends{
+		analytics: memory.NewAnalytics(geo),
+		sql:       memory.NewSQL(),
+		cache:     memory.NewCache(),
+		broker:    live.NewBroker(nil, nil),
+	}
+	b.closers = append(b.closers,
+		func() { b.sql.Close() },
+		func() { b.cache.Close() },
+		func() { b.analytics.Close() },
+	)
+	return b
+}
+
+// openSQL picks the repository by the scheme of url: SQLite for
+// sqlite://, PostgreSQL otherwise.
+func openSQL(ctx context.Context, url string) (database.SQL, error) {
+	if strings.HasPrefix(url, sqlite.Scheme) {
+		sql, err := sqlite.Connect(ctx, url)
+		if err != nil {
+			slog.Error("Could not open SQLite database", "error", err)
+			return nil, err
+		}
+		return sql, nil
+	}
+	sql, err := postgresql.Connect(ctx, url)
+	if err != nil {
+		slog.Error("Could not connect to Postgres", "error", err)
+		return nil, err
+	}
+	return sql, nil
+}
+
+// ingestConfig reads the batching and spool settings of an analytics
+// backend from <prefix>_* variables.
+func ingestConfig(prefix string) ingest.Config {
+	return ingest.Config{
+		SpoolDir:      getEnv(prefix+"_SPOOL_DIR", "spool"),
+		SpoolMaxBytes: int64(getEnvInt(prefix+"_SPOOL_MAX_MB", 256)) << 20,
+		BatchSize:     getEnvInt(prefix+"_BATCH_SIZE", 1000),
+		FlushInterval: getEnvDuration(prefix+"_FLUSH_INTERVAL", 5*time.Second),
+		BufferSize:    getEnvInt(prefix+"_BUFFER_SIZE", 10000),
+		Workers:       getEnvInt(prefix+"_ENRICH_WORKERS", 0),
+	}
+}
+
+// openAnalytics stores clicks in ClickHouse, or in PostgreSQL when
+// ANALYTICS_BACKEND is "postgres". The PostgreSQL store defaults to the
+// database of DB_URL.
+func openAnalytics(ctx context.Context, dbURL string, geo *geoip.Resolver) (database.Analytics, func(), error) {
+	switch backend := getEnv("ANALYTICS_BACKEND", analyticsClickHouse); backend {
+	case analyticsPostgres:
+		url := getEnv("ANALYTICS_DB_URL", dbURL)
+		if strings.HasPrefix(url, sqlite.Scheme) {
+			slog.Error("ANALYTICS_DB_URL must point to PostgreSQL", "backend", backend)
+			return nil, nil, errBadConfig
+		}
+		analytics, err := pganalytics.Connect(ctx, pganalytics.Config{
+			URL:           url,
+			RetentionDays: getEnvInt("ANALYTICS_RETENTION_DAYS", 90),
+			Ingest:        ingestConfig("ANALYTICS"),
+		}, geo)
+		if err != nil {
+			slog.Error("Could not connect to PostgreSQL analytics", "error", err)
+			return nil, nil, err
+		}
+		return analytics, func() { analytics.Close() }, nil
+
+	case analyticsClickHouse:
+		clickhouseAddr := os.Getenv("CLICKHOUSE_ADDR")
+		clickhouseUser := os.Getenv("CLICKHOUSE_USER")
+		clickhousePassword := os.Getenv("CLICKHOUSE_PASSWORD")
+		clickhouseDb := os.Getenv("CLICKHOUSE_DB")
+
+		if clickhouseAddr == "" ||
+			clickhouseUser == "" ||
+			clickhousePassword == "" ||
+			clickhouseDb == "" {
+			slog.Error("Missing required environment variables")
+			return nil, nil, errBadConfig
+		}
+
+		analytics, err := clickhouse.Connect(clickhouse.Config{
+			Addr:     clickhouseAddr,
+			User:     clickhouseUser,
+			Password: clickhousePassword,
+			Database: clickhouseDb,
+			Ingest:   ingestConfig("CLICKHOUSE"),
+		}, geo)
+		if err != nil {
+			slog.Error("Could not connect to ClickHouse", "error", err)
+			return nil, nil, err
+		}
+
+		if err := analytics.ApplyRetention(ctx, getEnvInt("CLICKHOUSE_RAW_TTL_DAYS", 0), getEnvInt("CLICKHOUSE_ROLLUP_TTL_DAYS", 0)); err != nil {
+			slog.Error("Could not apply ClickHouse retention policy", "error", err)
+			analytics.Close()
+			return nil, nil, err
+		}
+		return analytics, func() { analytics.Close() }, nil
+
+	default:
+		slog.Error("Unknown analytics backend", "backend", backend)
+		return nil, nil, errBadConfig
+	}
+}
+
+// openExternal connects to PostgreSQL (or SQLite), Redis and the analytics
+// store. Errors are logged here; whatever was opened before a failure is
+// closed.
+func openExternal(ctx context.Context, geo *geoip.Resolver) (*backends, error) {
+	dbURL := os.Getenv("DB_URL")
+	redisAddr := os.Getenv("REDIS_ADDR")
+
+	if dbURL == "" ||
+		redisAddr == "" {
+		slog.Error("Missing required environment variables")
+		return nil, errBadConfig
+	}
+
+	b := &backends{}
+	ok := false
+	defer func() {
+		if !ok {
+			b.close()
+		}
+	}()
+
+	sql, err := openSQL(ctx, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	b.closers = append(b.closers, func() { sql.Close() })
+	b.sql = sql
+
+	cache, err := redis.Connect(redis.Config{
+		Addrs:            strings.FieldsFunc(redisAddr, func(r rune) bool { return r == ',' || r == ' ' }),
+		MasterName:       os.Getenv("REDIS_MASTER_NAME"),
+		Username:         os.Getenv("REDIS_USERNAME"),
+		Password:         os.Getenv("REDIS_PASSWORD"),
+		SentinelPassword: os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		DB:               getEnvInt("REDIS_DB", 0),
+		TLS:              getEnvBool("REDIS_TLS", false),
+		TLSCAFile:        os.Getenv("REDIS_TLS_CA_FILE"),
+		KeyPrefix:        os.Getenv("REDIS_KEY_PREFIX"),
+	})
+	if err != nil {
+		slog.Error("Could not connect to Redis", "error", err)
+		return nil, err
+	}
+	b.closers = append(b.closers, func() { cache.Close() })
+
+	analytics, closeAnalytics, err := openAnalytics(ctx, dbURL, geo)
+	if err != nil {
+		return nil, err
+	}
+	b.closers = append(b.closers, closeAnalytics)
+	b.analytics = analytics
+
+	invalidations := cache.SubscribeInvalidations(ctx)
+	b.closers = append(b.closers, func() { invalidations.Close() })
+	linkCache := tiered.New(tiered.Config{
+		Size: getEnvInt("CACHE_LOCAL_SIZE", 10000),
+		TTL:  getEnvDuration("CACHE_LOCAL_TTL", 5*time.Second),
+	}, cache, cache)
+	go linkCache.Run(ctx, invalidations.Codes())
+	b.cache = linkCache
+
+	clickStream := cache.SubscribeClicks(ctx)
+	b.closers = append(b.closers, func() { clickStream.Close() })
+	b.broker = live.NewBroker(cache, clickStream)
+
+	ok = true
+	return b, nil
+}
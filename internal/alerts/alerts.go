@@ -0,0 +1,150 @@
+// Package alerts watches per-link click rates and reports sudden spikes and
+// drops. Rates are read from the hourly rollup, so only whole hours are
+// evaluated.
+package alerts
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// dropMinRate is the baseline rate, in clicks per window, a link needs before
+// silence on it is worth reporting.
+const dropMinRate = 5
+
+type Source interface {
+	GetLinkRates(ctx context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error)
+	ListLinkAlerts(ctx context.Context) ([]types.LinkAlert, error)
+	ClaimAlert(ctx context.Context, userId int64, shortCode string, cutoff time.Time) (bool, error)
+}
+
+type Notifier interface {
+	NotifyAlert(ctx context.Context, alert types.Alert) error
+}
+
+type Config struct {
+	Interval time.Duration
+	Window   time.Duration
+	Baseline time.Duration
+	Cooldown time.Duration
+}
+
+type Monitor struct {
+	cfg      Config
+	src      Source
+	notifier Notifier
+	now      func() time.Time
+}
+
+func NewMonitor(cfg Config, src Source, notifier Notifier) *Monitor {
+	if cfg.Window < time.Hour {
+		cfg.Window = time.Hour
+	}
+	if cfg.Baseline < cfg.Window {
+		cfg.Baseline = 24 * cfg.Window
+	}
+	return &Monitor{cfg: cfg, src: src, notifier: notifier, now: time.Now}
+}
+
+// Run checks click rates every Interval until ctx is done. A non-positive
+// interval disables the monitor.
+func (m *Monitor) Run(ctx context.Context) {
+	if m.cfg.Interval <= 0 {
+		slog.Info("Click alerts are disabled")
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx); err != nil {
+				slog.Error("Click alert check failed", "error", err)
+			}
+		}
+	}
+}
+
+func (m *Monitor) Check(ctx context.Context) error {
+	now := m.now()
+	windowEnd := now.Truncate(time.Hour)
+	windowFrom := windowEnd.Add(-m.cfg.Window)
+	baselineFrom := windowFrom.Add(-m.cfg.Baseline)
+
+	rates, err := m.src.GetLinkRates(ctx, baselineFrom, windowFrom, windowEnd)
+	if err != nil {
+		return err
+	}
+	stored, err := m.src.ListLinkAlerts(ctx)
+	if err != nil {
+		return err
+	}
+	settings := make(map[string]types.LinkAlert, len(stored))
+	for _, s := range stored {
+		settings[key(s.UserId, s.ShortCode)] = s
+	}
+
+	windows := float64(m.cfg.Baseline) / float64(m.cfg.Window)
+	for _, rate := range rates {
+		s, ok := settings[key(rate.UserId, rate.ShortCode)]
+		if !ok {
+			s = types.DefaultLinkAlert(rate.UserId, rate.ShortCode)
+		}
+		kind, fire := Evaluate(rate, s, windows, now)
+		if !fire {
+			continue
+		}
+
+		claimed, err := m.src.ClaimAlert(ctx, rate.UserId, rate.ShortCode, now.Add(-m.cfg.Cooldown))
+		if err != nil {
+			slog.Error("Failed to claim click alert", "user_id", rate.UserId, "short_code", rate.ShortCode, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		alert := types.Alert{
+			UserId:       rate.UserId,
+			ShortCode:    rate.ShortCode,
+			Kind:         kind,
+			Recent:       rate.Recent,
+			BaselineRate: float64(rate.Baseline) / windows,
+			WindowStart:  windowFrom,
+			WindowEnd:    windowEnd,
+		}
+		if err := m.notifier.NotifyAlert(ctx, alert); err != nil {
+			slog.Error("Failed to send click alert", "user_id", rate.UserId, "short_code", rate.ShortCode, "error", err)
+			continue
+		}
+		slog.Info("Click alert sent", "user_id", rate.UserId, "short_code", rate.ShortCode, "kind", kind, "recent", rate.Recent)
+	}
+	return nil
+}
+
+// Evaluate decides whether rate crosses the thresholds in settings. windows
+// is the length of the baseline period measured in recent windows.
+func Evaluate(rate types.LinkRate, settings types.LinkAlert, windows float64, now time.Time) (string, bool) {
+	if !settings.Enabled || settings.Muted(now) || windows <= 0 {
+		return "", false
+	}
+
+	baselineRate := float64(rate.Baseline) / windows
+	if rate.Recent >= settings.MinClicks && float64(rate.Recent) >= settings.SpikeFactor*max(baselineRate, 1) {
+		return types.AlertSpike, true
+	}
+	if settings.DropAlerts && rate.Recent == 0 && baselineRate >= dropMinRate {
+		return types.AlertDrop, true
+	}
+	return "", false
+}
+
+func key(userId int64, shortCode string) string {
+	return strconv.FormatInt(userId, 10) + "|" + shortCode
+}
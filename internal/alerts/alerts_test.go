@@ -0,0 +1,127 @@
+package alerts
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	muted := now.Add(time.Hour)
+	defaults := types.DefaultLinkAlert(1, "abc")
+
+	tests := []struct {
+		name     string
+		rate     types.LinkRate
+		settings func(*types.LinkAlert)
+		want     string
+	}{
+		{name: "quiet link", rate: types.LinkRate{Recent: 3, Baseline: 48}},
+		{name: "spike", rate: types.LinkRate{Recent: 60, Baseline: 240}, want: types.AlertSpike},
+		{name: "spike on a new link", rate: types.LinkRate{Recent: 25}, want: types.AlertSpike},
+		{name: "below min clicks", rate: types.LinkRate{Recent: 15}},
+		{name: "below factor", rate: types.LinkRate{Recent: 50, Baseline: 480}},
+		{name: "drop", rate: types.LinkRate{Recent: 0, Baseline: 240}, want: types.AlertDrop},
+		{name: "drop on a low traffic link", rate: types.LinkRate{Recent: 0, Baseline: 24}},
+		{
+			name:     "drop alerts off",
+			rate:     types.LinkRate{Recent: 0, Baseline: 240},
+			settings: func(a *types.LinkAlert) { a.DropAlerts = false },
+		},
+		{
+			name:     "disabled",
+			rate:     types.LinkRate{Recent: 60, Baseline: 240},
+			settings: func(a *types.LinkAlert) { a.Enabled = false },
+		},
+		{
+			name:     "muted",
+			rate:     types.LinkRate{Recent: 60, Baseline: 240},
+			settings: func(a *types.LinkAlert) { a.MutedUntil = &muted },
+		},
+		{
+			name:     "custom threshold",
+			rate:     types.LinkRate{Recent: 12, Baseline: 48},
+			settings: func(a *types.LinkAlert) { a.MinClicks, a.SpikeFactor = 10, 5 },
+			want:     types.AlertSpike,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := defaults
+			if tt.settings != nil {
+				tt.settings(&settings)
+			}
+			got, fire := Evaluate(tt.rate, settings, 24, now)
+			if got != tt.want || fire != (tt.want != "") {
+				t.Errorf("Evaluate() = %q, %v, want %q", got, fire, tt.want)
+			}
+		})
+	}
+}
+
+type fakeSource struct {
+	rates   []types.LinkRate
+	stored  []types.LinkAlert
+	claimed map[string]bool
+	window  [3]time.Time
+}
+
+func (f *fakeSource) GetLinkRates(_ context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error) {
+	f.window = [3]time.Time{baselineFrom, windowFrom, to}
+	return f.rates, nil
+}
+
+func (f *fakeSource) ListLinkAlerts(context.Context) ([]types.LinkAlert, error) {
+	return f.stored, nil
+}
+
+func (f *fakeSource) ClaimAlert(_ context.Context, userId int64, shortCode string, _ time.Time) (bool, error) {
+	k := key(userId, shortCode)
+	if f.claimed[k] {
+		return false, nil
+	}
+	f.claimed[k] = true
+	return true, nil
+}
+
+type fakeNotifier []types.Alert
+
+func (f *fakeNotifier) NotifyAlert(_ context.Context, alert types.Alert) error {
+	*f = append(*f, alert)
+	return nil
+}
+
+func TestMonitorCheck(t *testing.T) {
+	disabled := types.DefaultLinkAlert(2, "off")
+	disabled.Enabled = false
+	src := &fakeSource{
+		rates: []types.LinkRate{
+			{UserId: 1, ShortCode: "viral", Recent: 100, Baseline: 24},
+			{UserId: 2, ShortCode: "off", Recent: 100, Baseline: 24},
+			{UserId: 3, ShortCode: "calm", Recent: 2, Baseline: 48},
+		},
+		stored:  []types.LinkAlert{disabled},
+		claimed: map[string]bool{},
+	}
+	var sent fakeNotifier
+	m := NewMonitor(Config{Interval: time.Minute}, src, &sent)
+	m.now = func() time.Time { return time.Date(2026, 5, 1, 12, 40, 0, 0, time.UTC) }
+
+	for range 2 {
+		if err := m.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sent) != 1 || sent[0].ShortCode != "viral" || sent[0].Kind != types.AlertSpike {
+		t.Fatalf("sent = %+v, want a single spike alert for viral", sent)
+	}
+	if want := time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC); !sent[0].WindowStart.Equal(want) {
+		t.Errorf("window start = %v, want %v", sent[0].WindowStart, want)
+	}
+	if want := time.Date(2026, 4, 30, 11, 0, 0, 0, time.UTC); !src.window[0].Equal(want) {
+		t.Errorf("baseline start = %v, want %v", src.window[0], want)
+	}
+}
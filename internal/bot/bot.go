@@ -2,9 +2,9 @@ package bot
 
 import (
 	"context"
+	"linkshortener/internal/referrer"
 	"linkshortener/internal/types"
 	"log/slog"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,13 +15,33 @@ import (
 
 //go:generate mockgen -destination=mock_database_test.go -package=bot . Database
 type Database interface {
-	CreateUser(ctx context.Context, telegramID int64) error
+	CreateUser(ctx context.Context, telegramID int64, timezone string) error
 	UpdateLink(ctx context.Context, userId int64, shortCode, newLink string) error
 	GetUserIDByTelegramID(ctx context.Context, telegramID int64) (int64, error)
-	GetAnalyticByCode(ctx context.Context, code string, userId int64) ([]types.Analytic, error)
-	GetAllAnalytic(ctx context.Context, userId int64) ([]types.Analytic, error)
+	GetDailyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error)
+	GetHourlyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.HourlyClicks, error)
+	GetTotalClicks(ctx context.Context, filter types.AnalyticsFilter) (int64, error)
+	GetTopLinks(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopCountries(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopCities(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopChannels(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopSources(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetUniqueVisitors(ctx context.Context, filter types.AnalyticsFilter) (int64, error)
+	GetClickCounters(ctx context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error)
+	ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error
 	DeleteLinkByCode(ctx context.Context, userId int64, shortCode string) error
+	SetLinkCacheTTL(ctx context.Context, userId int64, shortCode string, ttl time.Duration) error
 	GetAllLinksByUser(ctx context.Context, userId int64) ([]types.LinkData, error)
+	GetUserSettings(ctx context.Context, userId int64) (*types.UserSettings, error)
+	SetCityOptOut(ctx context.Context, userId int64, optOut bool) error
+	SetTimezone(ctx context.Context, userId int64, timezone string, next func(types.DigestSubscription) time.Time) error
+	SetAPITokenHash(ctx context.Context, userId int64, tokenHash string) error
+	GetTelegramIDByUserID(ctx context.Context, userId int64) (int64, error)
+	GetLinkAlert(ctx context.Context, userId int64, shortCode string) (*types.LinkAlert, error)
+	SaveLinkAlert(ctx context.Context, alert types.LinkAlert) error
+	GetDigestSubscriptions(ctx context.Context, userId int64) ([]types.DigestSubscription, error)
+	SaveDigestSubscription(ctx context.Context, sub types.DigestSubscription) error
+	DeleteDigestSubscription(ctx context.Context, userId int64, period string) error
 }
 
 //go:generate mockgen -destination=mock_shortener_test.go -package=bot . Shortener
@@ -82,6 +102,10 @@ func (b *TelegramBot) Start(ctx context.Context) error {
 	b.tgBot.Handle("/create_custom", b.handleCustomLink)
 	b.tgBot.Handle("/my_links", b.handleMyLinks)
 	b.tgBot.Handle("/all_analytics", b.handleAllAnalytics)
+	b.tgBot.Handle("/export", b.handleExportCommand)
+	b.tgBot.Handle("/api_token", b.handleAPIToken)
+	b.tgBot.Handle("/cache_ttl", b.handleCacheTTL)
+	b.tgBot.Handle("/settings", b.handleSettings)
 	b.tgBot.Handle("/cancel", b.handleCancel)
 	b.tgBot.Handle(tele.OnText, b.handleLink)
 	b.tgBot.Handle(tele.OnCallback, b.handleCallback)
@@ -91,6 +115,10 @@ func (b *TelegramBot) Start(ctx context.Context) error {
 		{Text: "create_custom", Description: "Створити нове посилання з власним скороченням"},
 		{Text: "my_links", Description: "Список моїх посилань та окрема статистика"},
 		{Text: "all_analytics", Description: "Повна статистика переходів"},
+		{Text: "export", Description: "Експорт переходів у CSV або NDJSON"},
+		{Text: "api_token", Description: "Отримати новий токен для HTTP API"},
+		{Text: "cache_ttl", Description: "Змінити час кешування посилання"},
+		{Text: "settings", Description: "Налаштування приватності, часового поясу та дайджестів"},
 		{Text: "cancel", Description: "Відмінити нинішню дію"},
 	}
 
@@ -115,34 +143,163 @@ func (b *TelegramBot) Start(ctx context.Context) error {
 	return nil
 }
 
-func (b *TelegramBot) getTopStats(stats map[string]int, limit int) string {
-	type entry struct {
-		key   string
-		count int
+// clicksUA formats a click count with the matching Ukrainian plural form.
+func clicksUA(n int64) string {
+	word := "кліків"
+	switch mod100 := n % 100; {
+	case mod100 >= 11 && mod100 <= 14:
+	case n%10 == 1:
+		word = "клік"
+	case n%10 >= 2 && n%10 <= 4:
+		word = "кліки"
 	}
-	var sorted []entry
-	for k, v := range stats {
-		if k == "" {
-			k = "Unknown"
+	return strconv.FormatInt(n, 10) + " " + word
+}
+
+func (b *TelegramBot) formatCountStats(stats []types.CountStat) string {
+	if len(stats) == 0 {
+		return "  (немає даних)\n"
+	}
+	var sb strings.Builder
+	for _, stat := range stats {
+		key := stat.Key
+		if key == "" {
+			key = "Unknown"
 		}
-		sorted = append(sorted, entry{k, v})
+		sb.WriteString("  • ")
+		sb.WriteString(key)
+		sb.WriteString(": ")
+		sb.WriteString(strconv.FormatInt(stat.Clicks, 10))
+		sb.WriteByte('\n')
 	}
+	return sb.String()
+}
 
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].count > sorted[j].count
-	})
+func (b *TelegramBot) writeAudienceStats(ctx context.Context, sb *strings.Builder, userId int64, shortCode string, loc *time.Location) {
+	now := time.Now().In(loc)
+	allTime := types.AnalyticsFilter{UserId: userId, ShortCode: shortCode, From: time.Unix(0, 0), To: now, Timezone: loc.String()}
 
-	var sb strings.Builder
-	for i := 0; i < len(sorted) && i < limit; i++ {
+	visitors, err := b.db.GetUniqueVisitors(ctx, allTime)
+	if err != nil {
+		slog.Error("failed to get unique visitors", "user_id", userId, "short_code", shortCode, "error", err)
+	} else {
+		sb.WriteString("Унікальних відвідувачів: <code>")
+		sb.WriteString(strconv.FormatInt(visitors, 10))
+		sb.WriteString("</code>\n")
+	}
+
+	lastWeek := allTime
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	lastWeek.From = today.AddDate(0, 0, -6)
+	daily, err := b.db.GetDailyClicks(ctx, lastWeek)
+	if err != nil {
+		slog.Error("failed to get daily clicks", "user_id", userId, "short_code", shortCode, "error", err)
+		return
+	}
+
+	perDay := make(map[string]int64, len(daily))
+	var weekTotal int64
+	for _, d := range daily {
+		perDay[d.Day.Format(time.DateOnly)] += d.Clicks
+		weekTotal += d.Clicks
+	}
+	sb.WriteString("За останні 7 днів: <code>")
+	sb.WriteString(strconv.FormatInt(weekTotal, 10))
+	sb.WriteString("</code>\n")
+	for day := lastWeek.From; !day.After(today); day = day.AddDate(0, 0, 1) {
 		sb.WriteString("  • ")
-		sb.WriteString(sorted[i].key)
+		sb.WriteString(day.Format("02.01"))
 		sb.WriteString(": ")
-		sb.WriteString(strconv.Itoa(sorted[i].count))
+		sb.WriteString(strconv.FormatInt(perDay[day.Format(time.DateOnly)], 10))
 		sb.WriteByte('\n')
 	}
+}
 
-	if sb.Len() == 0 {
-		return "  (немає даних)\n"
+// writeLiveCounters adds the real-time counters, which include clicks not
+// yet written to ClickHouse. Today is the current date in loc.
+func (b *TelegramBot) writeLiveCounters(ctx context.Context, sb *strings.Builder, userId int64, shortCode string, loc *time.Location) {
+	counters, err := b.db.GetClickCounters(ctx, userId, []string{shortCode}, time.Now().In(loc))
+	if err != nil {
+		slog.Error("failed to get click counters", "user_id", userId, "short_code", shortCode, "error", err)
+		return
 	}
-	return sb.String()
+	c, ok := counters[shortCode]
+	if !ok {
+		return
+	}
+	sb.WriteString("⚡ Наживо: <code>")
+	sb.WriteString(clicksUA(c.Total))
+	sb.WriteString("</code>, сьогодні <code>")
+	sb.WriteString(strconv.FormatInt(c.Today, 10))
+	sb.WriteString("</code>, відвідувачів ≈<code>")
+	sb.WriteString(strconv.FormatInt(c.Visitors, 10))
+	sb.WriteString("</code>\n")
+}
+
+func (b *TelegramBot) writePeakHour(ctx context.Context, sb *strings.Builder, userId int64, shortCode string, loc *time.Location) {
+	hourly, err := b.db.GetHourlyClicks(ctx, types.AnalyticsFilter{
+		UserId: userId, ShortCode: shortCode, From: time.Unix(0, 0), To: time.Now(), Timezone: loc.String(),
+	})
+	if err != nil {
+		slog.Error("failed to get hourly clicks", "user_id", userId, "short_code", shortCode, "error", err)
+		return
+	}
+
+	var perHour [24]int64
+	for _, h := range hourly {
+		perHour[h.Hour] += h.Clicks
+	}
+	peakHour := 0
+	for hour, count := range perHour {
+		if count > perHour[peakHour] {
+			peakHour = hour
+		}
+	}
+	if perHour[peakHour] == 0 {
+		return
+	}
+
+	sb.WriteString("\n<b>⏰ Пікова година (")
+	sb.WriteString(loc.String())
+	sb.WriteString("):</b> ")
+	if peakHour < 10 {
+		sb.WriteByte('0')
+	}
+	sb.WriteString(strconv.Itoa(peakHour))
+	sb.WriteString(":00 (")
+	sb.WriteString(strconv.FormatInt(perHour[peakHour], 10))
+	sb.WriteString(" кліків)\n")
+}
+
+var channelNames = map[string]string{
+	referrer.ChannelDirect:    "Прямі переходи",
+	referrer.ChannelSearch:    "Пошукові системи",
+	referrer.ChannelSocial:    "Соцмережі",
+	referrer.ChannelMessenger: "Месенджери",
+	referrer.ChannelEmail:     "Email-розсилки",
+	referrer.ChannelReferral:  "Інші сайти",
+}
+
+// topChannels is GetTopChannels with the channels renamed for display.
+func (b *TelegramBot) topChannels(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	stats, err := b.db.GetTopChannels(ctx, filter, limit)
+	for i, stat := range stats {
+		if name, ok := channelNames[stat.Key]; ok {
+			stats[i].Key = name
+		}
+	}
+	return stats, err
+}
+
+type topStatsQuery func(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+
+func (b *TelegramBot) writeTopStats(ctx context.Context, sb *strings.Builder, title string, query topStatsQuery, filter types.AnalyticsFilter, limit int) {
+	stats, err := query(ctx, filter, limit)
+	if err != nil {
+		slog.Error("failed to get top stats", "user_id", filter.UserId, "short_code", filter.ShortCode, "section", title, "error", err)
+	}
+	sb.WriteString("\n<b>")
+	sb.WriteString(title)
+	sb.WriteString("</b>\n")
+	sb.WriteString(b.formatCountStats(stats))
 }
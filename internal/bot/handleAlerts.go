@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+var (
+	spikeFactors   = []float64{2, 3, 5, 10}
+	minClickSteps  = []int64{10, 20, 50, 100, 500}
+	muteDurations  = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+	muteDurationUA = map[time.Duration]string{time.Hour: "1 год", 24 * time.Hour: "24 год", 7 * 24 * time.Hour: "тиждень"}
+)
+
+// NotifyAlert sends a spike or drop alert to the owner of the link.
+func (b *TelegramBot) NotifyAlert(ctx context.Context, alert types.Alert) error {
+	telegramID, err := b.db.GetTelegramIDByUserID(ctx, alert.UserId)
+	if err != nil {
+		return err
+	}
+	loc := b.userLocation(ctx, alert.UserId)
+
+	var sb strings.Builder
+	if alert.Kind == types.AlertSpike {
+		sb.WriteString("🚀 <b>Сплеск переходів</b> за <code>")
+	} else {
+		sb.WriteString("📉 <b>Переходи зупинилися</b> за <code>")
+	}
+	sb.WriteString(alert.ShortCode)
+	sb.WriteString("</code>\n\n")
+	sb.WriteString(alert.WindowStart.In(loc).Format("15:04"))
+	sb.WriteString("–")
+	sb.WriteString(alert.WindowEnd.In(loc).Format("15:04"))
+	sb.WriteString(": <b>")
+	sb.WriteString(strconv.FormatInt(alert.Recent, 10))
+	sb.WriteString("</b> кліків (зазвичай ~")
+	sb.WriteString(strconv.FormatFloat(alert.BaselineRate, 'f', 1, 64))
+	sb.WriteString(")")
+
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(
+		menu.Row(menu.Data("🔕 Пауза на 24 год", "alert_mute", alert.ShortCode, (24*time.Hour).String())),
+		menu.Row(menu.Data("⚙️ Налаштування сповіщень", "alerts", alert.ShortCode)),
+	)
+	_, err = b.tgBot.Send(&tele.User{ID: telegramID}, sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+	return err
+}
+
+func (b *TelegramBot) sendAlertSettings(c tele.Context, shortCode string, edit bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+	alert, err := b.db.GetLinkAlert(ctx, userId, shortCode)
+	if err != nil {
+		slog.Error("failed to get link alert settings", "user_id", userId, "short_code", shortCode, "error", err)
+		return c.Send("Помилка отримання налаштувань.")
+	}
+
+	status := "✅ увімкнено"
+	if !alert.Enabled {
+		status = "🚫 вимкнено"
+	}
+	drops := "✅"
+	if !alert.DropAlerts {
+		drops = "🚫"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>🔔 Сповіщення для ")
+	sb.WriteString(shortCode)
+	sb.WriteString("</b>\n\nСтатус: ")
+	sb.WriteString(status)
+	sb.WriteString("\nСплеск: від <b>")
+	sb.WriteString(strconv.FormatInt(alert.MinClicks, 10))
+	sb.WriteString("</b> кліків за годину і у <b>")
+	sb.WriteString(strconv.FormatFloat(alert.SpikeFactor, 'f', -1, 64))
+	sb.WriteString("×</b> більше звичайного\nПадіння до нуля: ")
+	sb.WriteString(drops)
+	now := time.Now()
+	if alert.Muted(now) {
+		sb.WriteString("\n🔕 Пауза до ")
+		sb.WriteString(alert.MutedUntil.In(b.userLocation(ctx, userId)).Format("02.01 15:04"))
+	}
+
+	menu := &tele.ReplyMarkup{}
+	rows := []tele.Row{
+		menu.Row(menu.Data("🔔 Увімк./вимк.", "alert_set", shortCode, "enabled")),
+		menu.Row(
+			menu.Data("Поріг ×", "alert_set", shortCode, "factor"),
+			menu.Data("Мін. кліків", "alert_set", shortCode, "min"),
+		),
+		menu.Row(menu.Data("📉 Сповіщати про падіння", "alert_set", shortCode, "drop")),
+	}
+	if alert.Muted(now) {
+		rows = append(rows, menu.Row(menu.Data("🔔 Зняти паузу", "alert_mute", shortCode, "0s")))
+	} else {
+		var muteRow tele.Row
+		for _, d := range muteDurations {
+			muteRow = append(muteRow, menu.Data("🔕 "+muteDurationUA[d], "alert_mute", shortCode, d.String()))
+		}
+		rows = append(rows, muteRow)
+	}
+	menu.Inline(rows...)
+
+	if edit {
+		return c.Edit(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	return c.Send(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// updateLinkAlert applies change to the alert settings of a link and shows
+// the updated settings in place.
+func (b *TelegramBot) updateLinkAlert(c tele.Context, shortCode string, change func(*types.LinkAlert)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка звернення до бази даних."})
+	}
+	alert, err := b.db.GetLinkAlert(ctx, userId, shortCode)
+	if err != nil {
+		slog.Error("failed to get link alert settings", "user_id", userId, "short_code", shortCode, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка отримання налаштувань."})
+	}
+
+	change(alert)
+	if err := b.db.SaveLinkAlert(ctx, *alert); err != nil {
+		slog.Error("failed to save link alert settings", "user_id", userId, "short_code", shortCode, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Не вдалося зберегти налаштування."})
+	}
+
+	slog.Info("link alert settings changed", "user_id", userId, "short_code", shortCode)
+	_ = c.Respond(&tele.CallbackResponse{Text: "Збережено"})
+	return b.sendAlertSettings(c, shortCode, true)
+}
+
+func (b *TelegramBot) handleAlertSetting(c tele.Context, shortCode, field string) error {
+	return b.updateLinkAlert(c, shortCode, func(alert *types.LinkAlert) {
+		switch field {
+		case "enabled":
+			alert.Enabled = !alert.Enabled
+		case "drop":
+			alert.DropAlerts = !alert.DropAlerts
+		case "factor":
+			alert.SpikeFactor = nextStep(spikeFactors, alert.SpikeFactor)
+		case "min":
+			alert.MinClicks = nextStep(minClickSteps, alert.MinClicks)
+		}
+	})
+}
+
+func (b *TelegramBot) handleAlertMute(c tele.Context, shortCode, duration string) error {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return c.Respond()
+	}
+	return b.updateLinkAlert(c, shortCode, func(alert *types.LinkAlert) {
+		if d <= 0 {
+			alert.MutedUntil = nil
+			return
+		}
+		until := time.Now().Add(d)
+		alert.MutedUntil = &until
+	})
+}
+
+// nextStep returns the step after current, wrapping around to the first one.
+func nextStep[T int | int64 | float64](steps []T, current T) T {
+	i := slices.Index(steps, current)
+	return steps[(i+1)%len(steps)]
+}
@@ -3,6 +3,8 @@ package bot
 import (
 	"bytes"
 	"context"
+	"linkshortener/internal/export"
+	"linkshortener/internal/types"
 	"log/slog"
 	"strconv"
 	"strings"
@@ -40,6 +42,94 @@ func (b *TelegramBot) handleCallback(c tele.Context) error {
 		slog.Info("stats", "short_code", shortCode, "telegram_id", c.Sender().ID)
 		return b.handleLinkCallback(c, shortCode)
 
+	case "charts":
+		shortCode := ""
+		if len(parts) > 1 {
+			shortCode = parts[1]
+		}
+		slog.Info("charts", "short_code", shortCode, "telegram_id", c.Sender().ID)
+		return b.handleCharts(c, shortCode)
+
+	case "export":
+		if len(parts) < 3 {
+			return c.Respond()
+		}
+		format, err := export.ParseFormat(parts[2])
+		if err != nil {
+			return c.Respond()
+		}
+		slog.Info("export", "short_code", parts[1], "format", format, "telegram_id", c.Sender().ID)
+		return b.sendExport(c, parts[1], format, "", "")
+
+	case "alerts":
+		if len(parts) < 2 {
+			return c.Respond()
+		}
+		slog.Info("alerts", "short_code", parts[1], "telegram_id", c.Sender().ID)
+		_ = c.Respond()
+		return b.sendAlertSettings(c, parts[1], false)
+
+	case "alert_set":
+		if len(parts) < 3 {
+			return c.Respond()
+		}
+		slog.Info("alert_set", "short_code", parts[1], "field", parts[2], "telegram_id", c.Sender().ID)
+		return b.handleAlertSetting(c, parts[1], parts[2])
+
+	case "alert_mute":
+		if len(parts) < 3 {
+			return c.Respond()
+		}
+		slog.Info("alert_mute", "short_code", parts[1], "duration", parts[2], "telegram_id", c.Sender().ID)
+		return b.handleAlertMute(c, parts[1], parts[2])
+
+	case "settings_city":
+		slog.Info("settings_city", "telegram_id", c.Sender().ID)
+		return b.handleToggleCityGeo(c)
+
+	case "settings_tz":
+		slog.Info("settings_tz", "telegram_id", c.Sender().ID)
+		return b.sendTimezones(c)
+
+	case "set_tz":
+		if len(parts) < 2 {
+			return c.Respond()
+		}
+		slog.Info("set_tz", "timezone", parts[1], "telegram_id", c.Sender().ID)
+		return b.handleSetTimezone(c, parts[1])
+
+	case "settings":
+		_ = c.Respond()
+		return b.sendSettings(c)
+
+	case "digests":
+		slog.Info("digests", "telegram_id", c.Sender().ID)
+		_ = c.Respond()
+		// Opened from a digest message, the settings are sent as a new
+		// message instead of replacing the digest.
+		return b.sendDigestSettings(c, len(parts) < 2)
+
+	case "digest_toggle":
+		if len(parts) < 2 {
+			return c.Respond()
+		}
+		slog.Info("digest_toggle", "period", parts[1], "telegram_id", c.Sender().ID)
+		return b.handleDigestToggle(c, parts[1])
+
+	case "digest_hour":
+		if len(parts) < 2 {
+			return c.Respond()
+		}
+		slog.Info("digest_hour", "period", parts[1], "telegram_id", c.Sender().ID)
+		return b.handleDigestHour(c, parts[1])
+
+	case "digest_day":
+		if len(parts) < 2 {
+			return c.Respond()
+		}
+		slog.Info("digest_day", "period", parts[1], "telegram_id", c.Sender().ID)
+		return b.handleDigestDay(c, parts[1])
+
 	case "ignore":
 		slog.Info("Ignoring " + unique)
 		return c.Respond()
@@ -130,11 +220,23 @@ func (b *TelegramBot) sendLinksPage(c tele.Context, page int) error {
 	}
 	pageLinks := links[start:end]
 
+	codes := make([]string, 0, len(pageLinks))
+	for _, link := range pageLinks {
+		codes = append(codes, link.ShortCode)
+	}
+	counters, err := b.db.GetClickCounters(ctx, userId, codes, time.Now())
+	if err != nil {
+		slog.Error("failed to get click counters", "user_id", userId, "error", err)
+	}
+
 	menu := &tele.ReplyMarkup{}
 	var rows []tele.Row
 
 	for _, link := range pageLinks {
 		btnText := link.ShortCode
+		if cnt, ok := counters[link.ShortCode]; ok {
+			btnText += " · " + clicksUA(cnt.Total)
+		}
 		if len(link.OriginalLink) > 20 {
 			btnText += " (" + link.OriginalLink[:17] + "...)"
 		} else {
@@ -172,64 +274,42 @@ func (b *TelegramBot) handleLinkCallback(c tele.Context, shortCode string) error
 		slog.Error("failed to get user id from db", "user_id", userId)
 		return c.Send("Помилка звернення до бази даних.")
 	}
-	analytics, err := b.db.GetAnalyticByCode(ctx, shortCode, userId)
+	loc := b.userLocation(ctx, userId)
+	filter := types.AnalyticsFilter{UserId: userId, ShortCode: shortCode, From: time.Unix(0, 0), To: time.Now(), Timezone: loc.String()}
+	total, err := b.db.GetTotalClicks(ctx, filter)
 	if err != nil {
-		slog.Error("failed to get analytic from db", "user_id", userId)
+		slog.Error("failed to get total clicks", "user_id", userId, "short_code", shortCode, "error", err)
 		return c.Send("Помилка отримання аналітики.")
 	}
 
-	cityStats := make(map[string]int)
-	countryStats := make(map[string]int)
-	refererStats := make(map[string]int)
-	clickedAtStats := make(map[int]int)
-
-	for _, v := range analytics {
-		cityStats[v.City]++
-		countryStats[v.Country]++
-		refererStats[v.Referer]++
-		clickedAtStats[v.ClickedAt.Hour()]++
-	}
-
 	var sb strings.Builder
 	sb.WriteString("<b>📊 Ваша аналітика по " + shortCode + "</b>\n")
 	sb.WriteString("Всього переходів: <code>")
-	sb.WriteString(strconv.Itoa(len(analytics)))
-	sb.WriteString("</code>\n\n")
+	sb.WriteString(strconv.FormatInt(total, 10))
+	sb.WriteString("</code>\n")
+	b.writeLiveCounters(ctx, &sb, userId, shortCode, loc)
+	b.writeAudienceStats(ctx, &sb, userId, shortCode, loc)
 
-	sb.WriteString("\n<b>🌍 Географія:</b>\n")
-	sb.WriteString(b.getTopStats(countryStats, 8))
+	b.writeTopStats(ctx, &sb, "🌍 Географія:", b.db.GetTopCountries, filter, 8)
+	b.writeTopStats(ctx, &sb, "🏙 Міста:", b.db.GetTopCities, filter, 8)
+	b.writeTopStats(ctx, &sb, "📣 Канали:", b.topChannels, filter, 6)
+	b.writeTopStats(ctx, &sb, "🌐 Джерела:", b.db.GetTopSources, filter, 8)
 
-	sb.WriteString("\n<b>🏙 Міста:</b>\n")
-	sb.WriteString(b.getTopStats(cityStats, 8))
-
-	sb.WriteString("\n<b>🌐 Джерела (Referer):</b>\n")
-	sb.WriteString(b.getTopStats(refererStats, 8))
-
-	peakHour, peakCount := -1, 0
-	for hour, count := range clickedAtStats {
-		if count > peakCount {
-			peakCount, peakHour = count, hour
-		}
-	}
-
-	if peakHour != -1 {
-		sb.WriteString("\n<b>⏰ Пікова година (GMT):</b> ")
-		if peakHour < 10 {
-			sb.WriteByte('0')
-		}
-		sb.WriteString(strconv.Itoa(peakHour))
-		sb.WriteString(":00 (")
-		sb.WriteString(strconv.Itoa(peakCount))
-		sb.WriteString(" кліків)\n")
-	}
+	b.writePeakHour(ctx, &sb, userId, shortCode, loc)
 	menu := &tele.ReplyMarkup{}
 	updateBtn := menu.Data("✍️ Оновити оригінальне посилання", "update", shortCode)
 	deleteBtn := menu.Data("🗑️ Видалити це посилання", "delete", shortCode)
 	qrBtn := menu.Data("🖼 Отримати QR-код", "qr", shortCode)
+	chartsBtn := menu.Data("📈 Графіки", "charts", shortCode)
+	alertsBtn := menu.Data("🔔 Сповіщення", "alerts", shortCode)
+	csvBtn := menu.Data("📤 Експорт CSV", "export", shortCode, string(export.FormatCSV))
+	jsonBtn := menu.Data("📤 Експорт NDJSON", "export", shortCode, string(export.FormatNDJSON))
 	menu.Inline(
 		menu.Row(updateBtn),
 		menu.Row(deleteBtn),
 		menu.Row(qrBtn),
+		menu.Row(chartsBtn, alertsBtn),
+		menu.Row(csvBtn, jsonBtn),
 	)
 	slog.Info("show shortCode analytics info", "user_id", userId)
 	return c.Send(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML, ReplyMarkup: menu})
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"linkshortener/internal/charts"
+	"linkshortener/internal/types"
+	"log/slog"
+	"strconv"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const chartDays = 30
+
+func (b *TelegramBot) handleCharts(c tele.Context, shortCode string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = c.Respond(&tele.CallbackResponse{Text: "Будую графіки..."})
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+
+	album, err := b.getCharts(ctx, userId, shortCode, b.userLocation(ctx, userId))
+	if err != nil {
+		return c.Send("Помилка побудови графіків.")
+	}
+	if album == nil {
+		return c.Send("За останні " + strconv.Itoa(chartDays) + " днів переходів не було.")
+	}
+	slog.Info("show charts", "user_id", userId, "short_code", shortCode)
+	return c.SendAlbum(album)
+}
+
+// getCharts renders the charts for the last chartDays days. It returns a nil
+// album when there were no clicks in that period.
+func (b *TelegramBot) getCharts(ctx context.Context, userId int64, shortCode string, loc *time.Location) (tele.Album, error) {
+	now := time.Now().In(loc)
+	last := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	first := last.AddDate(0, 0, -(chartDays - 1))
+	filter := types.AnalyticsFilter{UserId: userId, ShortCode: shortCode, From: first, To: now, Timezone: loc.String()}
+
+	daily, err := b.db.GetDailyClicks(ctx, filter)
+	if err != nil {
+		slog.Error("failed to get daily clicks", "user_id", userId, "short_code", shortCode, "error", err)
+		return nil, err
+	}
+	if len(daily) == 0 {
+		return nil, nil
+	}
+	hourly, err := b.db.GetHourlyClicks(ctx, filter)
+	if err != nil {
+		slog.Error("failed to get hourly clicks", "user_id", userId, "short_code", shortCode, "error", err)
+		return nil, err
+	}
+	countries, err := b.db.GetTopCountries(ctx, filter, 10)
+	if err != nil {
+		slog.Error("failed to get top countries", "user_id", userId, "short_code", shortCode, "error", err)
+		return nil, err
+	}
+
+	timeline, err := charts.Timeline(daily, first, last)
+	if err != nil {
+		slog.Error("failed to render timeline chart", "user_id", userId, "error", err)
+		return nil, err
+	}
+	heatmap, err := charts.Heatmap(hourly)
+	if err != nil {
+		slog.Error("failed to render heatmap chart", "user_id", userId, "error", err)
+		return nil, err
+	}
+	bars, err := charts.Bars("Top countries", countries)
+	if err != nil {
+		slog.Error("failed to render countries chart", "user_id", userId, "error", err)
+		return nil, err
+	}
+
+	subject := "усіх посилань"
+	if shortCode != "" {
+		subject = shortCode
+	}
+	return tele.Album{
+		&tele.Photo{
+			File:    tele.FromReader(bytes.NewReader(timeline)),
+			Caption: "📈 Переходи по днях для " + subject + " (" + loc.String() + ")",
+		},
+		&tele.Photo{
+			File:    tele.FromReader(bytes.NewReader(heatmap)),
+			Caption: "🗓 Переходи за днями тижня та годинами (" + loc.String() + ")",
+		},
+		&tele.Photo{
+			File:    tele.FromReader(bytes.NewReader(bars)),
+			Caption: "🌍 Топ країн",
+		},
+	}, nil
+}
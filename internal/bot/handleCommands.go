@@ -2,6 +2,9 @@ package bot
 
 import (
 	"context"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
 	"log/slog"
 	"strconv"
 	"strings"
@@ -16,7 +19,7 @@ func (b *TelegramBot) handleStart(c tele.Context) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := b.db.CreateUser(ctx, c.Sender().ID)
+	err := b.db.CreateUser(ctx, c.Sender().ID, defaultTimezone(c.Sender().LanguageCode))
 	if err != nil {
 		slog.Error("failed to create user", "telegram_id", c.Sender().ID, "error", err)
 		return c.Send("Вибачте, виникла помилка при реєстрації. Спробуйте пізніше.")
@@ -55,63 +58,33 @@ func (b *TelegramBot) handleAllAnalytics(c tele.Context) error {
 		return c.Send("Помилка бази даних.")
 	}
 
-	analytics, err := b.db.GetAllAnalytic(ctx, userId)
+	loc := b.userLocation(ctx, userId)
+	filter := types.AnalyticsFilter{UserId: userId, From: time.Unix(0, 0), To: time.Now(), Timezone: loc.String()}
+	total, err := b.db.GetTotalClicks(ctx, filter)
 	if err != nil {
-		slog.Error("failed to get analytics from db", "user_id", userId)
+		slog.Error("failed to get total clicks", "user_id", userId, "error", err)
 		return c.Send("Помилка отримання аналітики.")
 	}
 
-	shortCodeStats := make(map[string]int)
-	cityStats := make(map[string]int)
-	countryStats := make(map[string]int)
-	refererStats := make(map[string]int)
-	clickedAtStats := make(map[int]int)
-
-	for _, v := range analytics {
-		shortCodeStats[v.ShortCode]++
-		cityStats[v.City]++
-		countryStats[v.Country]++
-		refererStats[v.Referer]++
-		clickedAtStats[v.ClickedAt.Hour()]++
-	}
-
 	var sb strings.Builder
 	sb.WriteString("<b>📊 Ваша загальна аналітика</b>\n")
 	sb.WriteString("Всього переходів: <code>")
-	sb.WriteString(strconv.Itoa(len(analytics)))
-	sb.WriteString("</code>\n\n")
-
-	sb.WriteString("<b>🔗 Популярні коди:</b>\n")
-	sb.WriteString(b.getTopStats(shortCodeStats, 8))
-
-	sb.WriteString("\n<b>🌍 Географія:</b>\n")
-	sb.WriteString(b.getTopStats(countryStats, 5))
+	sb.WriteString(strconv.FormatInt(total, 10))
+	sb.WriteString("</code>\n")
+	b.writeAudienceStats(ctx, &sb, userId, "", loc)
 
-	sb.WriteString("\n<b>🏙 Міста:</b>\n")
-	sb.WriteString(b.getTopStats(cityStats, 5))
+	b.writeTopStats(ctx, &sb, "🔗 Популярні коди:", b.db.GetTopLinks, filter, 8)
+	b.writeTopStats(ctx, &sb, "🌍 Географія:", b.db.GetTopCountries, filter, 5)
+	b.writeTopStats(ctx, &sb, "🏙 Міста:", b.db.GetTopCities, filter, 5)
+	b.writeTopStats(ctx, &sb, "📣 Канали:", b.topChannels, filter, 6)
+	b.writeTopStats(ctx, &sb, "🌐 Джерела:", b.db.GetTopSources, filter, 5)
 
-	sb.WriteString("\n<b>🌐 Джерела (Referer):</b>\n")
-	sb.WriteString(b.getTopStats(refererStats, 5))
+	b.writePeakHour(ctx, &sb, userId, "", loc)
 
-	peakHour, peakCount := -1, 0
-	for hour, count := range clickedAtStats {
-		if count > peakCount {
-			peakCount, peakHour = count, hour
-		}
-	}
-
-	if peakHour != -1 {
-		sb.WriteString("\n<b>⏰ Пікова година (GMT):</b> ")
-		if peakHour < 10 {
-			sb.WriteByte('0')
-		}
-		sb.WriteString(strconv.Itoa(peakHour))
-		sb.WriteString(":00 (")
-		sb.WriteString(strconv.Itoa(peakCount))
-		sb.WriteString(" кліків)\n")
-	}
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(menu.Data("📈 Графіки", "charts")))
 	slog.Info("show all analytics info", "user_id", userId)
-	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML})
+	return c.Send(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
 }
 
 func (b *TelegramBot) handleMyLinks(c tele.Context) error {
@@ -125,3 +98,50 @@ func (b *TelegramBot) handleCustomLink(c tele.Context) error {
 	b.mu.Unlock()
 	return c.Send("🔗 Надішліть довге посилання, яке хочете скоротити:")
 }
+
+const (
+	minLinkCacheTTL = time.Minute
+	maxLinkCacheTTL = 7 * 24 * time.Hour
+)
+
+// handleCacheTTL handles "/cache_ttl <code> <duration|default>".
+func (b *TelegramBot) handleCacheTTL(c tele.Context) error {
+	slog.Info("command /cache_ttl received", "telegram_id", c.Sender().ID, "args", c.Args())
+
+	usage := "Використання: <code>/cache_ttl код 1h</code> (від 1m до 168h) або <code>/cache_ttl код default</code>"
+	args := c.Args()
+	if len(args) != 2 {
+		return c.Send(usage, &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	shortCode := args[0]
+	var ttl time.Duration
+	if args[1] != "default" {
+		var err error
+		ttl, err = time.ParseDuration(args[1])
+		if err != nil || ttl < minLinkCacheTTL || ttl > maxLinkCacheTTL {
+			return c.Send(usage, &tele.SendOptions{ParseMode: tele.ModeHTML})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+	if err := b.db.SetLinkCacheTTL(ctx, userId, shortCode, ttl); err != nil {
+		if errors.Is(err, customerrs.ErrNoFound) {
+			return c.Send("Посилання не знайдено.")
+		}
+		slog.Error("failed to set link cache ttl", "user_id", userId, "short_code", shortCode, "error", err)
+		return c.Send("Не вдалося зберегти налаштування.")
+	}
+
+	slog.Info("link cache ttl changed", "user_id", userId, "short_code", shortCode, "ttl", ttl)
+	if ttl == 0 {
+		return c.Send("✅ Для <code>"+shortCode+"</code> знову діє стандартний час кешування.", &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	return c.Send("✅ <code>"+shortCode+"</code> тепер кешується на "+ttl.String()+".", &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"linkshortener/internal/digest"
+	"linkshortener/internal/types"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+var (
+	digestHours    = []int{7, 8, 9, 10, 12, 15, 18, 21}
+	weekdayNamesUA = [7]string{"понеділок", "вівторок", "середа", "четвер", "пʼятниця", "субота", "неділя"}
+	digestTitlesUA = map[string]string{types.DigestDaily: "Щоденний", types.DigestWeekly: "Щотижневий"}
+)
+
+const defaultDigestHour = 9
+
+// SendDigest sends a scheduled analytics summary to its subscriber.
+func (b *TelegramBot) SendDigest(ctx context.Context, d types.Digest) error {
+	telegramID, err := b.db.GetTelegramIDByUserID(ctx, d.UserId)
+	if err != nil {
+		return err
+	}
+
+	previous := "попереднього дня"
+	dates := d.From.Format("02.01")
+	if d.Period == types.DigestWeekly {
+		previous = "попереднього тижня"
+		dates += "–" + d.To.AddDate(0, 0, -1).Format("02.01")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📬 <b>")
+	sb.WriteString(digestTitlesUA[d.Period])
+	sb.WriteString(" дайджест</b> за ")
+	sb.WriteString(dates)
+	sb.WriteString("\n\nПереходів: <b>")
+	sb.WriteString(strconv.FormatInt(d.Total, 10))
+	sb.WriteString("</b> ")
+	if d.PreviousTotal == 0 {
+		sb.WriteString("(" + previous + " переходів не було)")
+	} else {
+		change := float64(d.Total-d.PreviousTotal) / float64(d.PreviousTotal) * 100
+		sb.WriteString(fmt.Sprintf("(%+.0f%% до %s)", change, previous))
+	}
+	sb.WriteString("\n\n<b>🔗 Топ посилань:</b>\n")
+	sb.WriteString(b.formatCountStats(d.TopLinks))
+	if len(d.NewCountries) > 0 {
+		sb.WriteString("\n<b>🆕 Нові країни:</b> ")
+		sb.WriteString(strings.Join(d.NewCountries, ", "))
+	}
+
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(menu.Data("⚙️ Налаштування дайджестів", "digests", "new")))
+	_, err = b.tgBot.Send(&tele.User{ID: telegramID}, sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+	return err
+}
+
+func (b *TelegramBot) sendDigestSettings(c tele.Context, edit bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+	subs, err := b.db.GetDigestSubscriptions(ctx, userId)
+	if err != nil {
+		slog.Error("failed to get digest subscriptions", "user_id", userId, "error", err)
+		return c.Send("Помилка отримання налаштувань.")
+	}
+	active := make(map[string]types.DigestSubscription, len(subs))
+	for _, sub := range subs {
+		active[sub.Period] = sub
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>📬 Дайджести</b>\n\n")
+	sb.WriteString("<i>Короткий підсумок переходів: топ посилань, загальна кількість, зміна до попереднього періоду та нові країни.</i>\n")
+
+	menu := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, period := range []string{types.DigestDaily, types.DigestWeekly} {
+		sb.WriteString("\n<b>")
+		sb.WriteString(digestTitlesUA[period])
+		sb.WriteString(":</b> ")
+		sub, ok := active[period]
+		if !ok {
+			sb.WriteString("🚫 вимкнено")
+			rows = append(rows, menu.Row(menu.Data("✅ Увімкнути "+strings.ToLower(digestTitlesUA[period]), "digest_toggle", period)))
+			continue
+		}
+
+		sb.WriteString("✅ ")
+		if period == types.DigestWeekly {
+			sb.WriteString(weekdayNamesUA[sub.Weekday-1])
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%02d:00", sub.Hour))
+
+		row := menu.Row(menu.Data("🕒 Година", "digest_hour", period))
+		if period == types.DigestWeekly {
+			row = append(row, menu.Data("📅 День", "digest_day", period))
+		}
+		row = append(row, menu.Data("🚫 Вимкнути", "digest_toggle", period))
+		rows = append(rows, row)
+	}
+	rows = append(rows, menu.Row(menu.Data("⬅️ Назад", "settings")))
+	menu.Inline(rows...)
+
+	if edit {
+		return c.Edit(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	return c.Send(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// updateDigest applies change to the subscription of the given period, or to
+// a new one if the user is not subscribed, and shows the updated settings.
+// The subscription is kept if change returns true and deleted otherwise.
+func (b *TelegramBot) updateDigest(c tele.Context, period string, change func(sub *types.DigestSubscription, subscribed bool) bool) error {
+	if _, ok := digestTitlesUA[period]; !ok {
+		return c.Respond()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка звернення до бази даних."})
+	}
+	subs, err := b.db.GetDigestSubscriptions(ctx, userId)
+	if err != nil {
+		slog.Error("failed to get digest subscriptions", "user_id", userId, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка отримання налаштувань."})
+	}
+
+	sub := types.DigestSubscription{UserId: userId, Period: period, Hour: defaultDigestHour, Weekday: 1}
+	subscribed := false
+	for _, s := range subs {
+		if s.Period == period {
+			sub, subscribed = s, true
+		}
+	}
+	if !subscribed {
+		sub.Timezone = b.userLocation(ctx, userId).String()
+	}
+
+	if change(&sub, subscribed) {
+		sub.NextRunAt = digest.NextRun(sub, time.Now())
+		err = b.db.SaveDigestSubscription(ctx, sub)
+	} else if subscribed {
+		err = b.db.DeleteDigestSubscription(ctx, userId, period)
+	}
+	if err != nil {
+		slog.Error("failed to save digest subscription", "user_id", userId, "period", period, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Не вдалося зберегти налаштування."})
+	}
+
+	slog.Info("digest subscription changed", "user_id", userId, "period", period)
+	_ = c.Respond(&tele.CallbackResponse{Text: "Збережено"})
+	return b.sendDigestSettings(c, true)
+}
+
+func (b *TelegramBot) handleDigestToggle(c tele.Context, period string) error {
+	return b.updateDigest(c, period, func(_ *types.DigestSubscription, subscribed bool) bool {
+		return !subscribed
+	})
+}
+
+func (b *TelegramBot) handleDigestHour(c tele.Context, period string) error {
+	return b.updateDigest(c, period, func(sub *types.DigestSubscription, subscribed bool) bool {
+		sub.Hour = nextStep(digestHours, sub.Hour)
+		return subscribed
+	})
+}
+
+func (b *TelegramBot) handleDigestDay(c tele.Context, period string) error {
+	return b.updateDigest(c, period, func(sub *types.DigestSubscription, subscribed bool) bool {
+		sub.Weekday = sub.Weekday%7 + 1
+		return subscribed
+	})
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"context"
+	"html"
+	"linkshortener/internal/apitoken"
+	"linkshortener/internal/export"
+	"linkshortener/internal/types"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	exportMaxRows = 100_000
+	exportTimeout = 2 * time.Minute
+)
+
+// handleExportCommand handles "/export [code] [csv|ndjson] [from] [to]".
+// Arguments may come in any order; dates are YYYY-MM-DD in the user's
+// timezone.
+func (b *TelegramBot) handleExportCommand(c tele.Context) error {
+	slog.Info("command /export received", "telegram_id", c.Sender().ID, "args", c.Args())
+
+	var shortCode, formatArg string
+	var dates []string
+	for _, arg := range c.Args() {
+		if _, err := time.Parse(time.DateOnly, arg); err == nil {
+			dates = append(dates, arg)
+			continue
+		}
+		if _, err := export.ParseFormat(arg); err == nil && formatArg == "" {
+			formatArg = arg
+			continue
+		}
+		if arg != "all" {
+			shortCode = arg
+		}
+	}
+	if len(dates) > 2 {
+		return c.Send("Використання: <code>/export [код] [csv|ndjson] [з YYYY-MM-DD] [по YYYY-MM-DD]</code>", &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	dates = append(dates, "", "")
+
+	format, _ := export.ParseFormat(formatArg)
+	return b.sendExport(c, shortCode, format, dates[0], dates[1])
+}
+
+func (b *TelegramBot) sendExport(c tele.Context, shortCode string, format export.Format, from, to string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	if c.Callback() != nil {
+		_ = c.Respond(&tele.CallbackResponse{Text: "Готую експорт..."})
+	}
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+
+	loc := b.userLocation(ctx, userId)
+	start, end, err := export.ParseRange(from, to, loc)
+	if err != nil {
+		return c.Send("❌ Невірний діапазон дат.")
+	}
+
+	// The export is spooled to a temporary file so that the row count is
+	// known before upload and a failed query never produces a partial file.
+	file, err := os.CreateTemp("", "export-*"+format.Extension())
+	if err != nil {
+		slog.Error("failed to create export file", "error", err)
+		return c.Send("Помилка підготовки експорту.")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	filter := types.AnalyticsFilter{UserId: userId, ShortCode: shortCode, From: start, To: end, Timezone: loc.String()}
+	rows, err := export.Write(ctx, file, b.db, filter, format, exportMaxRows)
+	if err != nil {
+		slog.Error("export failed", "user_id", userId, "short_code", shortCode, "rows", rows, "error", err)
+		return c.Send("Помилка експорту аналітики.")
+	}
+	if rows == 0 {
+		return c.Send("За вибраний період переходів немає.")
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return c.Send("Помилка підготовки експорту.")
+	}
+
+	name := "clicks-all"
+	if shortCode != "" {
+		name = "clicks-" + shortCode
+	}
+	caption := "📤 Експорт: " + strconv.Itoa(rows) + " рядків"
+	if rows == exportMaxRows {
+		caption += " (досягнуто ліміт, звузьте діапазон дат або скористайтеся API)"
+	}
+
+	slog.Info("export sent", "user_id", userId, "short_code", shortCode, "format", format, "rows", rows)
+	return c.Send(&tele.Document{
+		File:     tele.FromReader(file),
+		FileName: name + format.Extension(),
+		MIME:     format.ContentType(),
+		Caption:  caption,
+	})
+}
+
+func (b *TelegramBot) handleAPIToken(c tele.Context) error {
+	slog.Info("command /api_token received", "telegram_id", c.Sender().ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+
+	token, err := apitoken.Generate()
+	if err != nil {
+		slog.Error("failed to generate api token", "user_id", userId, "error", err)
+		return c.Send("Не вдалося створити токен.")
+	}
+	if err := b.db.SetAPITokenHash(ctx, userId, apitoken.Hash(token)); err != nil {
+		slog.Error("failed to save api token", "user_id", userId, "error", err)
+		return c.Send("Не вдалося зберегти токен.")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔑 <b>Ваш новий API-токен:</b>\n<code>")
+	sb.WriteString(token)
+	sb.WriteString("</code>\n\n<i>Збережіть його: повторно показати токен неможливо, а попередній токен більше не діє.</i>\n\n")
+	sb.WriteString("Приклад експорту:\n<code>curl -H \"Authorization: Bearer ")
+	sb.WriteString(token)
+	sb.WriteString("\" \"")
+	sb.WriteString(html.EscapeString(b.baseLink + "/api/v1/export?format=csv&from=2026-01-01"))
+	sb.WriteString("\"</code>")
+
+	slog.Info("api token rotated", "user_id", userId)
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"context"
+	"linkshortener/internal/digest"
+	"linkshortener/internal/types"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func (b *TelegramBot) handleSettings(c tele.Context) error {
+	slog.Info("command /settings received", "telegram_id", c.Sender().ID)
+	return b.sendSettings(c)
+}
+
+func (b *TelegramBot) sendSettings(c tele.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Send("Помилка звернення до бази даних.")
+	}
+
+	settings, err := b.db.GetUserSettings(ctx, userId)
+	if err != nil {
+		slog.Error("failed to get user settings", "user_id", userId, "error", err)
+		return c.Send("Помилка отримання налаштувань.")
+	}
+
+	cityStatus := "✅ увімкнено"
+	if settings.CityOptOut {
+		cityStatus = "🚫 вимкнено"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>⚙️ Налаштування</b>\n\n")
+	sb.WriteString("<b>📍 Геолокація до рівня міста:</b> ")
+	sb.WriteString(cityStatus)
+	sb.WriteString("\n<i>Якщо вимкнено, для переходів за вашими посиланнями зберігається лише країна.</i>\n\n")
+	sb.WriteString("<b>🕒 Часовий пояс:</b> <code>")
+	sb.WriteString(settings.Timezone)
+	sb.WriteString("</code>\n<i>У ньому рахуються дні та години в аналітиці.</i>")
+
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(
+		menu.Row(menu.Data("📍 Змінити геолокацію міст", "settings_city")),
+		menu.Row(menu.Data("🕒 Змінити часовий пояс", "settings_tz")),
+		menu.Row(menu.Data("📬 Дайджести", "digests")),
+	)
+
+	if c.Callback() != nil {
+		return c.Edit(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+	return c.Send(sb.String(), menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+func (b *TelegramBot) handleToggleCityGeo(c tele.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка звернення до бази даних."})
+	}
+
+	settings, err := b.db.GetUserSettings(ctx, userId)
+	if err != nil {
+		slog.Error("failed to get user settings", "user_id", userId, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка отримання налаштувань."})
+	}
+
+	if err := b.db.SetCityOptOut(ctx, userId, !settings.CityOptOut); err != nil {
+		slog.Error("failed to update city opt-out", "user_id", userId, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Не вдалося зберегти налаштування."})
+	}
+
+	slog.Info("city geolocation setting changed", "user_id", userId, "opt_out", !settings.CityOptOut)
+	_ = c.Respond(&tele.CallbackResponse{Text: "Збережено"})
+	return b.sendSettings(c)
+}
+
+// timezones are offered in the settings menu; the first one is also the
+// fallback for users whose language gives no hint.
+var timezones = []string{
+	"UTC",
+	"Europe/Kyiv",
+	"Europe/Warsaw",
+	"Europe/Berlin",
+	"Europe/London",
+	"America/New_York",
+	"America/Los_Angeles",
+	"Asia/Tokyo",
+}
+
+var languageTimezones = map[string]string{
+	"uk": "Europe/Kyiv",
+	"pl": "Europe/Warsaw",
+	"de": "Europe/Berlin",
+	"cs": "Europe/Prague",
+	"sk": "Europe/Bratislava",
+	"ro": "Europe/Bucharest",
+	"fr": "Europe/Paris",
+	"it": "Europe/Rome",
+	"es": "Europe/Madrid",
+	"ja": "Asia/Tokyo",
+}
+
+// defaultTimezone guesses a timezone from the Telegram language code of a new
+// user, e.g. "uk" or "pl-PL".
+func defaultTimezone(languageCode string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(languageCode), "-")
+	if tz, ok := languageTimezones[lang]; ok {
+		return tz
+	}
+	return timezones[0]
+}
+
+func (b *TelegramBot) userLocation(ctx context.Context, userId int64) *time.Location {
+	settings, err := b.db.GetUserSettings(ctx, userId)
+	if err != nil {
+		slog.Error("failed to get user settings", "user_id", userId, "error", err)
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		slog.Warn("unknown user timezone, falling back to UTC", "user_id", userId, "timezone", settings.Timezone, "error", err)
+		return time.UTC
+	}
+	return loc
+}
+
+func (b *TelegramBot) sendTimezones(c tele.Context) error {
+	_ = c.Respond()
+
+	menu := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, tz := range timezones {
+		rows = append(rows, menu.Row(menu.Data(tz, "set_tz", tz)))
+	}
+	rows = append(rows, menu.Row(menu.Data("⬅️ Назад", "settings")))
+	menu.Inline(rows...)
+
+	return c.Edit("<b>🕒 Оберіть часовий пояс:</b>", menu, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+func (b *TelegramBot) handleSetTimezone(c tele.Context, timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Невідомий часовий пояс."})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userId, err := b.db.GetUserIDByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		slog.Error("failed to get user id from db", "telegram_id", c.Sender().ID, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Помилка звернення до бази даних."})
+	}
+
+	now := time.Now()
+	next := func(sub types.DigestSubscription) time.Time { return digest.NextRun(sub, now) }
+	if err := b.db.SetTimezone(ctx, userId, timezone, next); err != nil {
+		slog.Error("failed to update timezone", "user_id", userId, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "Не вдалося зберегти налаштування."})
+	}
+
+	slog.Info("timezone changed", "user_id", userId, "timezone", timezone)
+	_ = c.Respond(&tele.CallbackResponse{Text: "Збережено"})
+	return b.sendSettings(c)
+}
@@ -8,6 +8,7 @@ import (
 	context "context"
 	types "linkshortener/internal/types"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,17 +37,31 @@ func (m *MockDatabase) EXPECT() *MockDatabaseMockRecorder {
 }
 
 // CreateUser mocks base method.
-func (m *MockDatabase) CreateUser(arg0 context.Context, arg1 int64) error {
+func (m *MockDatabase) CreateUser(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockDatabaseMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) CreateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockDatabase)(nil).CreateUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockDatabase)(nil).CreateUser), arg0, arg1, arg2)
+}
+
+// DeleteDigestSubscription mocks base method.
+func (m *MockDatabase) DeleteDigestSubscription(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDigestSubscription", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDigestSubscription indicates an expected call of DeleteDigestSubscription.
+func (mr *MockDatabaseMockRecorder) DeleteDigestSubscription(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDigestSubscription", reflect.TypeOf((*MockDatabase)(nil).DeleteDigestSubscription), arg0, arg1, arg2)
 }
 
 // DeleteLinkByCode mocks base method.
@@ -63,19 +78,18 @@ func (mr *MockDatabaseMockRecorder) DeleteLinkByCode(arg0, arg1, arg2 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLinkByCode", reflect.TypeOf((*MockDatabase)(nil).DeleteLinkByCode), arg0, arg1, arg2)
 }
 
-// GetAllAnalytic mocks base method.
-func (m *MockDatabase) GetAllAnalytic(arg0 context.Context, arg1 int64) ([]types.Analytic, error) {
+// ExportClicks mocks base method.
+func (m *MockDatabase) ExportClicks(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int, arg3 func(types.Analytic) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllAnalytic", arg0, arg1)
-	ret0, _ := ret[0].([]types.Analytic)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "ExportClicks", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetAllAnalytic indicates an expected call of GetAllAnalytic.
-func (mr *MockDatabaseMockRecorder) GetAllAnalytic(arg0, arg1 interface{}) *gomock.Call {
+// ExportClicks indicates an expected call of ExportClicks.
+func (mr *MockDatabaseMockRecorder) ExportClicks(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllAnalytic", reflect.TypeOf((*MockDatabase)(nil).GetAllAnalytic), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportClicks", reflect.TypeOf((*MockDatabase)(nil).ExportClicks), arg0, arg1, arg2, arg3)
 }
 
 // GetAllLinksByUser mocks base method.
@@ -93,19 +107,199 @@ func (mr *MockDatabaseMockRecorder) GetAllLinksByUser(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllLinksByUser", reflect.TypeOf((*MockDatabase)(nil).GetAllLinksByUser), arg0, arg1)
 }
 
-// GetAnalyticByCode mocks base method.
-func (m *MockDatabase) GetAnalyticByCode(arg0 context.Context, arg1 string, arg2 int64) ([]types.Analytic, error) {
+// GetClickCounters mocks base method.
+func (m *MockDatabase) GetClickCounters(arg0 context.Context, arg1 int64, arg2 []string, arg3 time.Time) (map[string]types.ClickCounters, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClickCounters", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(map[string]types.ClickCounters)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClickCounters indicates an expected call of GetClickCounters.
+func (mr *MockDatabaseMockRecorder) GetClickCounters(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClickCounters", reflect.TypeOf((*MockDatabase)(nil).GetClickCounters), arg0, arg1, arg2, arg3)
+}
+
+// GetDailyClicks mocks base method.
+func (m *MockDatabase) GetDailyClicks(arg0 context.Context, arg1 types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyClicks", arg0, arg1)
+	ret0, _ := ret[0].([]types.DailyClicks)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyClicks indicates an expected call of GetDailyClicks.
+func (mr *MockDatabaseMockRecorder) GetDailyClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyClicks", reflect.TypeOf((*MockDatabase)(nil).GetDailyClicks), arg0, arg1)
+}
+
+// GetDigestSubscriptions mocks base method.
+func (m *MockDatabase) GetDigestSubscriptions(arg0 context.Context, arg1 int64) ([]types.DigestSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDigestSubscriptions", arg0, arg1)
+	ret0, _ := ret[0].([]types.DigestSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDigestSubscriptions indicates an expected call of GetDigestSubscriptions.
+func (mr *MockDatabaseMockRecorder) GetDigestSubscriptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDigestSubscriptions", reflect.TypeOf((*MockDatabase)(nil).GetDigestSubscriptions), arg0, arg1)
+}
+
+// GetHourlyClicks mocks base method.
+func (m *MockDatabase) GetHourlyClicks(arg0 context.Context, arg1 types.AnalyticsFilter) ([]types.HourlyClicks, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHourlyClicks", arg0, arg1)
+	ret0, _ := ret[0].([]types.HourlyClicks)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHourlyClicks indicates an expected call of GetHourlyClicks.
+func (mr *MockDatabaseMockRecorder) GetHourlyClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHourlyClicks", reflect.TypeOf((*MockDatabase)(nil).GetHourlyClicks), arg0, arg1)
+}
+
+// GetLinkAlert mocks base method.
+func (m *MockDatabase) GetLinkAlert(arg0 context.Context, arg1 int64, arg2 string) (*types.LinkAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkAlert", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.LinkAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkAlert indicates an expected call of GetLinkAlert.
+func (mr *MockDatabaseMockRecorder) GetLinkAlert(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkAlert", reflect.TypeOf((*MockDatabase)(nil).GetLinkAlert), arg0, arg1, arg2)
+}
+
+// GetTelegramIDByUserID mocks base method.
+func (m *MockDatabase) GetTelegramIDByUserID(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelegramIDByUserID", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelegramIDByUserID indicates an expected call of GetTelegramIDByUserID.
+func (mr *MockDatabaseMockRecorder) GetTelegramIDByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelegramIDByUserID", reflect.TypeOf((*MockDatabase)(nil).GetTelegramIDByUserID), arg0, arg1)
+}
+
+// GetTopChannels mocks base method.
+func (m *MockDatabase) GetTopChannels(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopChannels", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopChannels indicates an expected call of GetTopChannels.
+func (mr *MockDatabaseMockRecorder) GetTopChannels(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopChannels", reflect.TypeOf((*MockDatabase)(nil).GetTopChannels), arg0, arg1, arg2)
+}
+
+// GetTopCities mocks base method.
+func (m *MockDatabase) GetTopCities(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAnalyticByCode", arg0, arg1, arg2)
-	ret0, _ := ret[0].([]types.Analytic)
+	ret := m.ctrl.Call(m, "GetTopCities", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAnalyticByCode indicates an expected call of GetAnalyticByCode.
-func (mr *MockDatabaseMockRecorder) GetAnalyticByCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+// GetTopCities indicates an expected call of GetTopCities.
+func (mr *MockDatabaseMockRecorder) GetTopCities(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnalyticByCode", reflect.TypeOf((*MockDatabase)(nil).GetAnalyticByCode), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopCities", reflect.TypeOf((*MockDatabase)(nil).GetTopCities), arg0, arg1, arg2)
+}
+
+// GetTopCountries mocks base method.
+func (m *MockDatabase) GetTopCountries(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopCountries", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopCountries indicates an expected call of GetTopCountries.
+func (mr *MockDatabaseMockRecorder) GetTopCountries(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopCountries", reflect.TypeOf((*MockDatabase)(nil).GetTopCountries), arg0, arg1, arg2)
+}
+
+// GetTopLinks mocks base method.
+func (m *MockDatabase) GetTopLinks(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopLinks", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopLinks indicates an expected call of GetTopLinks.
+func (mr *MockDatabaseMockRecorder) GetTopLinks(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopLinks", reflect.TypeOf((*MockDatabase)(nil).GetTopLinks), arg0, arg1, arg2)
+}
+
+// GetTopSources mocks base method.
+func (m *MockDatabase) GetTopSources(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopSources", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopSources indicates an expected call of GetTopSources.
+func (mr *MockDatabaseMockRecorder) GetTopSources(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopSources", reflect.TypeOf((*MockDatabase)(nil).GetTopSources), arg0, arg1, arg2)
+}
+
+// GetTotalClicks mocks base method.
+func (m *MockDatabase) GetTotalClicks(arg0 context.Context, arg1 types.AnalyticsFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalClicks", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalClicks indicates an expected call of GetTotalClicks.
+func (mr *MockDatabaseMockRecorder) GetTotalClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalClicks", reflect.TypeOf((*MockDatabase)(nil).GetTotalClicks), arg0, arg1)
+}
+
+// GetUniqueVisitors mocks base method.
+func (m *MockDatabase) GetUniqueVisitors(arg0 context.Context, arg1 types.AnalyticsFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUniqueVisitors", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUniqueVisitors indicates an expected call of GetUniqueVisitors.
+func (mr *MockDatabaseMockRecorder) GetUniqueVisitors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUniqueVisitors", reflect.TypeOf((*MockDatabase)(nil).GetUniqueVisitors), arg0, arg1)
 }
 
 // GetUserIDByTelegramID mocks base method.
@@ -123,6 +317,105 @@ func (mr *MockDatabaseMockRecorder) GetUserIDByTelegramID(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDByTelegramID", reflect.TypeOf((*MockDatabase)(nil).GetUserIDByTelegramID), arg0, arg1)
 }
 
+// GetUserSettings mocks base method.
+func (m *MockDatabase) GetUserSettings(arg0 context.Context, arg1 int64) (*types.UserSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", arg0, arg1)
+	ret0, _ := ret[0].(*types.UserSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockDatabaseMockRecorder) GetUserSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockDatabase)(nil).GetUserSettings), arg0, arg1)
+}
+
+// SaveDigestSubscription mocks base method.
+func (m *MockDatabase) SaveDigestSubscription(arg0 context.Context, arg1 types.DigestSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDigestSubscription", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDigestSubscription indicates an expected call of SaveDigestSubscription.
+func (mr *MockDatabaseMockRecorder) SaveDigestSubscription(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDigestSubscription", reflect.TypeOf((*MockDatabase)(nil).SaveDigestSubscription), arg0, arg1)
+}
+
+// SaveLinkAlert mocks base method.
+func (m *MockDatabase) SaveLinkAlert(arg0 context.Context, arg1 types.LinkAlert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLinkAlert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLinkAlert indicates an expected call of SaveLinkAlert.
+func (mr *MockDatabaseMockRecorder) SaveLinkAlert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLinkAlert", reflect.TypeOf((*MockDatabase)(nil).SaveLinkAlert), arg0, arg1)
+}
+
+// SetAPITokenHash mocks base method.
+func (m *MockDatabase) SetAPITokenHash(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAPITokenHash", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAPITokenHash indicates an expected call of SetAPITokenHash.
+func (mr *MockDatabaseMockRecorder) SetAPITokenHash(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAPITokenHash", reflect.TypeOf((*MockDatabase)(nil).SetAPITokenHash), arg0, arg1, arg2)
+}
+
+// SetCityOptOut mocks base method.
+func (m *MockDatabase) SetCityOptOut(arg0 context.Context, arg1 int64, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCityOptOut", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCityOptOut indicates an expected call of SetCityOptOut.
+func (mr *MockDatabaseMockRecorder) SetCityOptOut(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCityOptOut", reflect.TypeOf((*MockDatabase)(nil).SetCityOptOut), arg0, arg1, arg2)
+}
+
+// SetLinkCacheTTL mocks base method.
+func (m *MockDatabase) SetLinkCacheTTL(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkCacheTTL", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkCacheTTL indicates an expected call of SetLinkCacheTTL.
+func (mr *MockDatabaseMockRecorder) SetLinkCacheTTL(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkCacheTTL", reflect.TypeOf((*MockDatabase)(nil).SetLinkCacheTTL), arg0, arg1, arg2, arg3)
+}
+
+// SetTimezone mocks base method.
+func (m *MockDatabase) SetTimezone(arg0 context.Context, arg1 int64, arg2 string, arg3 func(types.DigestSubscription) time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTimezone", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTimezone indicates an expected call of SetTimezone.
+func (mr *MockDatabaseMockRecorder) SetTimezone(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTimezone", reflect.TypeOf((*MockDatabase)(nil).SetTimezone), arg0, arg1, arg2, arg3)
+}
+
 // UpdateLink mocks base method.
 func (m *MockDatabase) UpdateLink(arg0 context.Context, arg1 int64, arg2, arg3 string) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,280 @@
+// Package charts renders analytics as PNG images. It has no dependencies
+// beyond the standard library and x/image, so labels are limited to the
+// ASCII range of the built-in bitmap font.
+package charts
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"linkshortener/internal/types"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	foreground = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	grid       = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	accent     = color.RGBA{0x1f, 0x4e, 0x9e, 0xff}
+	emptyCell  = color.RGBA{0xf3, 0xf3, 0xf3, 0xff}
+	lightCell  = color.RGBA{0xd6, 0xe4, 0xf7, 0xff}
+)
+
+var weekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+const (
+	timelineWidth  = 800
+	timelineHeight = 400
+	heatmapCell    = 28
+	barRowHeight   = 28
+	barChartWidth  = 640
+	barLabelWidth  = 150
+	maxLabelChars  = 20
+	titleHeight    = 30
+)
+
+// Timeline draws clicks per day for every day from first to last inclusive.
+// Days missing from daily are drawn as zero.
+func Timeline(daily []types.DailyClicks, first, last time.Time) ([]byte, error) {
+	perDay := make(map[string]int64, len(daily))
+	for _, d := range daily {
+		perDay[d.Day.Format(time.DateOnly)] += d.Clicks
+	}
+
+	var days []time.Time
+	var values []int64
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+		values = append(values, perDay[day.Format(time.DateOnly)])
+	}
+
+	c := newCanvas(timelineWidth, timelineHeight)
+	c.text(10, 20, "Clicks per day", foreground)
+
+	left, right, top, bottom := 50, timelineWidth-20, titleHeight+10, timelineHeight-40
+	maxValue := niceMax(maxOf(values))
+
+	const gridLines = 4
+	for i := 0; i <= gridLines; i++ {
+		y := bottom - (bottom-top)*i/gridLines
+		c.hline(left, right, y, grid)
+		label := strconv.FormatInt(maxValue*int64(i)/gridLines, 10)
+		c.text(left-8-c.textWidth(label), y+4, label, foreground)
+	}
+
+	if len(values) == 0 {
+		return c.encode()
+	}
+
+	step := 0
+	if len(values) > 1 {
+		step = (right - left) / (len(values) - 1)
+	}
+	labelEvery := max(1, len(values)/10)
+
+	var prevX, prevY int
+	for i, v := range values {
+		x := left + step*i
+		y := bottom - int(int64(bottom-top)*v/maxValue)
+		if i > 0 {
+			c.line(prevX, prevY, x, y, accent)
+		}
+		c.rect(x-2, y-2, x+3, y+3, accent)
+		if i%labelEvery == 0 {
+			label := days[i].Format("02.01")
+			c.text(x-c.textWidth(label)/2, bottom+20, label, foreground)
+		}
+		prevX, prevY = x, y
+	}
+
+	return c.encode()
+}
+
+// Heatmap draws clicks by weekday (rows) and hour of day (columns).
+func Heatmap(hourly []types.HourlyClicks) ([]byte, error) {
+	var cells [7][24]int64
+	var maxValue int64
+	for _, h := range hourly {
+		if h.Weekday < 1 || h.Weekday > 7 || h.Hour < 0 || h.Hour > 23 {
+			continue
+		}
+		cells[h.Weekday-1][h.Hour] += h.Clicks
+		maxValue = max(maxValue, cells[h.Weekday-1][h.Hour])
+	}
+
+	left, top := 45, titleHeight+10
+	width := left + 24*heatmapCell + 10
+	height := top + 7*heatmapCell + 30
+
+	c := newCanvas(width, height)
+	c.text(10, 20, "Clicks by weekday and hour", foreground)
+
+	for day := range cells {
+		y := top + day*heatmapCell
+		c.text(8, y+heatmapCell/2+4, weekdays[day], foreground)
+		for hour, v := range cells[day] {
+			x := left + hour*heatmapCell
+			c.rect(x+1, y+1, x+heatmapCell-1, y+heatmapCell-1, heat(v, maxValue))
+		}
+	}
+	for hour := 0; hour < 24; hour += 3 {
+		label := strconv.Itoa(hour)
+		x := left + hour*heatmapCell + heatmapCell/2
+		c.text(x-c.textWidth(label)/2, top+7*heatmapCell+18, label, foreground)
+	}
+
+	return c.encode()
+}
+
+// Bars draws a horizontal bar per stat in the given order.
+func Bars(title string, stats []types.CountStat) ([]byte, error) {
+	top := titleHeight + 10
+	height := top + max(1, len(stats))*barRowHeight + 10
+
+	c := newCanvas(barChartWidth, height)
+	c.text(10, 20, title, foreground)
+
+	var maxValue int64
+	for _, s := range stats {
+		maxValue = max(maxValue, s.Clicks)
+	}
+
+	barLeft, barRight := barLabelWidth, barChartWidth-60
+	for i, s := range stats {
+		y := top + i*barRowHeight
+		label := s.Key
+		if label == "" {
+			label = "Unknown"
+		}
+		if runes := []rune(label); len(runes) > maxLabelChars {
+			label = string(runes[:maxLabelChars-3]) + "..."
+		}
+		c.text(10, y+barRowHeight/2+4, label, foreground)
+
+		barEnd := barLeft
+		if maxValue > 0 {
+			barEnd += int(int64(barRight-barLeft) * s.Clicks / maxValue)
+		}
+		c.rect(barLeft, y+4, max(barEnd, barLeft+1), y+barRowHeight-4, accent)
+		c.text(barEnd+6, y+barRowHeight/2+4, strconv.FormatInt(s.Clicks, 10), foreground)
+	}
+
+	return c.encode()
+}
+
+func heat(v, maxValue int64) color.RGBA {
+	if v == 0 || maxValue == 0 {
+		return emptyCell
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(int64(a) + (int64(b)-int64(a))*v/maxValue)
+	}
+	return color.RGBA{mix(lightCell.R, accent.R), mix(lightCell.G, accent.G), mix(lightCell.B, accent.B), 0xff}
+}
+
+func maxOf(values []int64) int64 {
+	var m int64
+	for _, v := range values {
+		m = max(m, v)
+	}
+	return m
+}
+
+// niceMax rounds the top of the y axis up to 1, 2 or 5 times a power of ten
+// so that grid labels stay readable.
+func niceMax(v int64) int64 {
+	if v <= 4 {
+		return 4
+	}
+	for magnitude := int64(1); ; magnitude *= 10 {
+		for _, m := range []int64{1, 2, 5} {
+			if m*magnitude >= v {
+				return m * magnitude
+			}
+		}
+	}
+}
+
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(width, height int) *canvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	return &canvas{img: img}
+}
+
+func (c *canvas) rect(x0, y0, x1, y1 int, col color.Color) {
+	draw.Draw(c.img, image.Rect(x0, y0, x1, y1), image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+func (c *canvas) hline(x0, x1, y int, col color.Color) {
+	c.rect(x0, y, x1, y+1, col)
+}
+
+// line draws a two pixel wide segment using Bresenham's algorithm.
+func (c *canvas) line(x0, y0, x1, y1 int, col color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		c.img.Set(x0, y0, col)
+		c.img.Set(x0, y0+1, col)
+		c.img.Set(x0+1, y0, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func (c *canvas) text(x, y int, s string, col color.Color) {
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(col),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+func (c *canvas) textWidth(s string) int {
+	return font.MeasureString(basicfont.Face7x13, s).Ceil()
+}
+
+func (c *canvas) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,43 @@
+package charts
+
+import (
+	"bytes"
+	"image/png"
+	"linkshortener/internal/types"
+	"testing"
+	"time"
+)
+
+func TestChartsEncodePNG(t *testing.T) {
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	daily := []types.DailyClicks{{Day: first, Clicks: 3}, {Day: first.AddDate(0, 0, 2), Clicks: 17}}
+	hourly := []types.HourlyClicks{{Weekday: 1, Hour: 9, Clicks: 5}, {Weekday: 7, Hour: 23, Clicks: 1}, {Weekday: 0, Hour: 30, Clicks: 1}}
+	countries := []types.CountStat{{Key: "Ukraine", Clicks: 10}, {Key: "", Clicks: 2}, {Key: "United Kingdom of Great Britain", Clicks: 1}}
+
+	tests := map[string]func() ([]byte, error){
+		"timeline":       func() ([]byte, error) { return Timeline(daily, first, first.AddDate(0, 0, 29)) },
+		"single day":     func() ([]byte, error) { return Timeline(daily, first, first) },
+		"empty timeline": func() ([]byte, error) { return Timeline(nil, first, first.AddDate(0, 0, -1)) },
+		"heatmap":        func() ([]byte, error) { return Heatmap(hourly) },
+		"bars":           func() ([]byte, error) { return Bars("Top countries", countries) },
+		"empty bars":     func() ([]byte, error) { return Bars("Top countries", nil) },
+	}
+	for name, render := range tests {
+		data, err := render()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+			t.Errorf("%s: invalid PNG: %v", name, err)
+		}
+	}
+}
+
+func TestNiceMax(t *testing.T) {
+	tests := map[int64]int64{0: 4, 3: 4, 7: 10, 11: 20, 20: 20, 21: 50, 501: 1000}
+	for in, want := range tests {
+		if got := niceMax(in); got != want {
+			t.Errorf("niceMax(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -1,7 +1,21 @@
 package customerrs
 
-import "errors"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrNoFound = errors.New("not found")
 )
+
+// NotFound maps sql.ErrNoRows to ErrNoFound, which is what repositories
+// return for missing users and links. The result still matches
+// sql.ErrNoRows for callers that have not moved to ErrNoFound yet.
+func NotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) && !errors.Is(err, ErrNoFound) {
+		return fmt.Errorf("%w: %w", ErrNoFound, err)
+	}
+	return err
+}
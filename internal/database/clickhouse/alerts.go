@@ -0,0 +1,26 @@
+package clickhouse
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"time"
+)
+
+// GetLinkRates returns, for every link clicked in [baselineFrom, to), its
+// clicks in [windowFrom, to) and in the baseline [baselineFrom, windowFrom).
+func (a *ClickHouse) GetLinkRates(ctx context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error) {
+	query := `
+		SELECT user_id, short_code,
+			toInt64(sumIf(clicks, hour >= ?)) AS recent,
+			toInt64(sumIf(clicks, hour < ?)) AS baseline
+		FROM clicks_hourly
+		WHERE hour >= ? AND hour < ?
+		GROUP BY user_id, short_code`
+
+	var rates []types.LinkRate
+	err := a.db.SelectContext(ctx, &rates, query, windowFrom.UTC(), windowFrom.UTC(), baselineFrom.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
@@ -3,88 +3,107 @@ package clickhouse
 import (
 	"context"
 	"embed"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/geoip"
 	"linkshortener/internal/types"
 	"log/slog"
-	"net"
 	"sync"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/golang-migrate/migrate/v4"
 	clickmigrations "github.com/golang-migrate/migrate/v4/database/clickhouse"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
-	"github.com/oschwald/geoip2-golang"
 )
 
 //go:embed migrations/*.sql
 var migrationsClickHouseFS embed.FS
 
-type ClickHouse struct {
-	db           *sqlx.DB
-	clicksBuffer chan types.ClickData
-	geo          *geoip2.Reader
-	workerCancel context.CancelFunc
-	workerWG     sync.WaitGroup
-	startOnce    sync.Once
-	closeOnce    sync.Once
+const (
+	analyticColumns = "user_id, short_code, country, region, city, asn, isp, user_agent, device, browser, os, " +
+		"referer, referer_host, channel, utm_source, utm_medium, utm_campaign, utm_term, utm_content, clicked_at"
+)
+
+type Config struct {
+	Addr     string
+	User     string
+	Password string
+	Database string
+	// Ingest configures batching and the spool; its Name defaults to
+	// "clickhouse".
+	Ingest ingest.Config
 }
 
-func Connect(addr, user, pass, dbName string) (*ClickHouse, error) {
-	db := clickhouse.OpenDB(&clickhouse.Options{
-		Addr: []string{addr},
+func (cfg Config) options() *clickhouse.Options {
+	return &clickhouse.Options{
+		Addr: []string{cfg.Addr},
 		Auth: clickhouse.Auth{
-			Database: dbName,
-			Username: user,
-			Password: pass,
+			Database: cfg.Database,
+			Username: cfg.User,
+			Password: cfg.Password,
 		},
 		DialTimeout: time.Second * 30,
 		Compression: &clickhouse.Compression{
 			Method: clickhouse.CompressionLZ4,
 		},
-	})
-	conn := sqlx.NewDb(db, "clickhouse")
+	}
+}
+
+type ClickHouse struct {
+	db        *sqlx.DB
+	conn      driver.Conn
+	pipeline  *ingest.Pipeline
+	closeOnce sync.Once
+}
+
+func Connect(cfg Config, geo *geoip.Resolver) (*ClickHouse, error) {
+	if cfg.Ingest.Name == "" {
+		cfg.Ingest.Name = "clickhouse"
+	}
+
+	db := sqlx.NewDb(clickhouse.OpenDB(cfg.options()), "clickhouse")
 	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := conn.PingContext(pingCtx); err != nil {
+	if err := db.PingContext(pingCtx); err != nil {
 		slog.Error("failed to ping database", "err", err)
 		return nil, err
 	}
 
-	geodatabase, err := geoip2.Open("GeoLite2-City.mmdb")
+	conn, err := clickhouse.Open(cfg.options())
 	if err != nil {
 		return nil, err
 	}
 
 	a := &ClickHouse{
-		db:           conn,
-		clicksBuffer: make(chan types.ClickData, 1000),
-		geo:          geodatabase,
+		db:   db,
+		conn: conn,
 	}
 
 	if err := a.runMigrations(); err != nil {
 		return nil, err
 	}
 
+	a.pipeline, err = ingest.NewPipeline(cfg.Ingest, geo, a)
+	if err != nil {
+		return nil, err
+	}
+
 	return a, nil
 }
 
 func (a *ClickHouse) Start(ctx context.Context) {
-	a.startOnce.Do(func() {
-		workerCtx, cancel := context.WithCancel(ctx)
-		a.workerCancel = cancel
-		a.workerWG.Add(1)
-		go a.worker(workerCtx)
-	})
+	a.pipeline.Start(ctx)
 }
 
 func (a *ClickHouse) runMigrations() error {
-	d, err := iofs.New(migrationsClickHouseFS, "migrations/clickhouse")
+	d, err := iofs.New(migrationsClickHouseFS, "migrations")
 	if err != nil {
 		return err
 	}
 
-	driver, err := clickmigrations.WithInstance(a.db.DB, &clickmigrations.Config{})
+	driver, err := clickmigrations.WithInstance(a.db.DB, &clickmigrations.Config{MultiStatementEnabled: true})
 	if err != nil {
 		return err
 	}
@@ -105,65 +124,14 @@ func (a *ClickHouse) runMigrations() error {
 	return nil
 }
 
-func (a *ClickHouse) worker(ctx context.Context) {
-	defer a.workerWG.Done()
-
-	var buffer []types.ClickData
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			if len(buffer) > 0 {
-				flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				if err := a.recordClicks(flushCtx, buffer); err != nil {
-					slog.Warn("RecordClicks flush on shutdown failed", "error", err, "count", len(buffer))
-				}
-				cancel()
-			}
-			return
-		case data := <-a.clicksBuffer:
-			buffer = append(buffer, data)
-			if len(buffer) >= 100 {
-				flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-				err := a.recordClicks(flushCtx, buffer)
-				cancel()
-				if err != nil {
-					slog.Warn("RecordClicks error", "error", err)
-					continue
-				}
-				buffer = nil
-			}
-		case <-ticker.C:
-			if len(buffer) > 0 {
-				flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-				err := a.recordClicks(flushCtx, buffer)
-				cancel()
-				if err != nil {
-					slog.Warn("RecordClicks error", "error", err)
-					continue
-				}
-				buffer = nil
-			}
-		}
-	}
-}
-
 func (a *ClickHouse) Close() error {
 	var closeErr error
 
 	a.closeOnce.Do(func() {
-		if a.workerCancel != nil {
-			a.workerCancel()
-		}
-		a.workerWG.Wait()
-
-		if a.geo != nil {
-			if err := a.geo.Close(); err != nil {
-				closeErr = err
-				return
-			}
+		a.pipeline.Close()
+		if err := a.conn.Close(); err != nil {
+			closeErr = err
+			return
 		}
 		closeErr = a.db.Close()
 	})
@@ -171,58 +139,13 @@ func (a *ClickHouse) Close() error {
 	return closeErr
 }
 
-func (a *ClickHouse) recordClicks(ctx context.Context, clicks []types.ClickData) error {
-	tx, err := a.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO clicks (user_id, short_code, country, city, user_agent, referer) VALUES (?, ?, ?, ?, ?, ?)")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	for _, data := range clicks {
-		city := "Unknown"
-		country := "Unknown"
-		ip := net.ParseIP(data.IP)
-		if ip != nil {
-			record, err := a.geo.City(ip)
-			if err == nil {
-				if name, ok := record.City.Names["en"]; ok {
-					city = name
-				}
-				if name, ok := record.Country.Names["en"]; ok {
-					country = name
-				}
-			}
-		}
-		_, err = stmt.ExecContext(ctx, data.UserId, data.ShortCode, country, city, data.UserAgent, data.Referer)
-		if err != nil {
-			slog.Error("Failed to exec insert for click", "error", err, "ip", data.IP)
-			continue
-		}
-	}
-	return tx.Commit()
-}
-
 func (a *ClickHouse) PushClick(data types.ClickData) {
-	if a.workerCancel == nil {
-		slog.Warn("Analytics worker is not started, dropping click data", "link", data.ShortCode)
-		return
-	}
-
-	select {
-	case a.clicksBuffer <- data:
-	default:
-		slog.Warn("Analytics buffer full, dropping click data", "link", data.ShortCode)
-	}
+	a.pipeline.PushClick(data)
 }
 
 func (a *ClickHouse) GetAllAnalytic(ctx context.Context, userId int64) ([]types.Analytic, error) {
 	var clicks []types.Analytic
-	query := `SELECT * FROM clicks WHERE user_id = $1`
+	query := `SELECT ` + analyticColumns + ` FROM clicks WHERE user_id = $1`
 
 	err := a.db.SelectContext(ctx, &clicks, query, userId)
 	if err != nil {
@@ -234,7 +157,7 @@ func (a *ClickHouse) GetAllAnalytic(ctx context.Context, userId int64) ([]types.
 
 func (a *ClickHouse) GetAnalyticByCode(ctx context.Context, code string, userId int64) ([]types.Analytic, error) {
 	var clicks []types.Analytic
-	query := `SELECT * FROM clicks WHERE short_code = $1 AND user_id = $2`
+	query := `SELECT ` + analyticColumns + ` FROM clicks WHERE short_code = $1 AND user_id = $2`
 	err := a.db.SelectContext(ctx, &clicks, query, code, userId)
 
 	if err != nil {
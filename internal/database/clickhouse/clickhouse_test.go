@@ -0,0 +1,77 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/types"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+// BenchmarkRecordClicks measures batch insert throughput against a real
+// ClickHouse server. Set CLICKHOUSE_BENCH_ADDR (and optionally
+// CLICKHOUSE_BENCH_USER, CLICKHOUSE_BENCH_PASSWORD, CLICKHOUSE_BENCH_DB) to run it:
+//
+//	go test ./internal/database/clickhouse -run '^$' -bench RecordClicks
+func BenchmarkRecordClicks(b *testing.B) {
+	addr := os.Getenv("CLICKHOUSE_BENCH_ADDR")
+	if addr == "" {
+		b.Skip("CLICKHOUSE_BENCH_ADDR is not set")
+	}
+	cfg := Config{
+		Addr:     addr,
+		User:     os.Getenv("CLICKHOUSE_BENCH_USER"),
+		Password: os.Getenv("CLICKHOUSE_BENCH_PASSWORD"),
+		Database: os.Getenv("CLICKHOUSE_BENCH_DB"),
+	}
+
+	conn, err := clickhouse.Open(cfg.options())
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := &ClickHouse{
+		db:   sqlx.NewDb(clickhouse.OpenDB(cfg.options()), "clickhouse"),
+		conn: conn,
+	}
+	a.pipeline, err = ingest.NewPipeline(ingest.Config{Name: "clickhouse"}, nil, a)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer a.Close()
+
+	if err := a.runMigrations(); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, batchSize := range []int{1000, 10000} {
+		clicks := make([]ingest.Click, batchSize)
+		for i := range clicks {
+			clicks[i] = ingest.Enrich(nil, types.ClickData{
+				UserId:    int64(i % 50),
+				ShortCode: fmt.Sprintf("bench%d", i%500),
+				IP:        "203.0.113.7",
+				UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Benchmark",
+				Referer:   "https://example.com/",
+			})
+		}
+
+		b.Run(fmt.Sprintf("batch=%d", batchSize), func(b *testing.B) {
+			ctx := context.Background()
+			for b.Loop() {
+				if err := a.WriteClicks(ctx, clicks); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "clicks/s")
+		})
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _ = a.db.ExecContext(cleanupCtx, "ALTER TABLE clicks DELETE WHERE short_code LIKE 'bench%'")
+}
@@ -0,0 +1,73 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"linkshortener/internal/types"
+	"time"
+)
+
+// ExportClicks streams raw clicks matching the filter to fn in chronological
+// order, stopping after limit rows. The range is read one month (one
+// partition) at a time and rows are scanned as they arrive, so memory use
+// does not grow with the size of the export.
+func (a *ClickHouse) ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error {
+	cond, args := linkCondition(filter)
+
+	var first sql.NullTime
+	err := a.db.GetContext(ctx, &first,
+		`SELECT minOrNull(clicked_at) FROM clicks WHERE `+cond+` AND clicked_at >= ? AND clicked_at < ?`,
+		append(args, filter.From.UTC(), filter.To.UTC())...)
+	if err != nil || !first.Valid {
+		return err
+	}
+
+	query := `SELECT ` + analyticColumns + ` FROM clicks
+		WHERE ` + cond + ` AND clicked_at >= ? AND clicked_at < ?
+		ORDER BY clicked_at
+		LIMIT ?`
+
+	remaining := limit
+	for month := monthStart(first.Time); month.Before(filter.To) && remaining > 0; month = month.AddDate(0, 1, 0) {
+		from, to := month, month.AddDate(0, 1, 0)
+		if from.Before(filter.From) {
+			from = filter.From
+		}
+		if to.After(filter.To) {
+			to = filter.To
+		}
+
+		n, err := a.exportChunk(ctx, query, append(args, from.UTC(), to.UTC(), remaining), fn)
+		if err != nil {
+			return err
+		}
+		remaining -= n
+	}
+	return nil
+}
+
+func (a *ClickHouse) exportChunk(ctx context.Context, query string, args []any, fn func(types.Analytic) error) (int, error) {
+	rows, err := a.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var click types.Analytic
+		if err := rows.StructScan(&click); err != nil {
+			return n, err
+		}
+		if err := fn(click); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
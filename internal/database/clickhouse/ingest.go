@@ -0,0 +1,81 @@
+package clickhouse
+
+import (
+	"context"
+	"linkshortener/internal/database/ingest"
+	"time"
+)
+
+const insertClicksQuery = "INSERT INTO clicks (user_id, short_code, country, region, city, asn, isp, visitor_hash, " +
+	"user_agent, device, browser, os, " +
+	"referer, referer_host, channel, utm_source, utm_medium, utm_campaign, utm_term, utm_content, " +
+	"clicked_at)"
+
+// WriteClicks inserts a batch of clicks with one columnar insert.
+func (a *ClickHouse) WriteClicks(ctx context.Context, clicks []ingest.Click) error {
+	batch, err := a.conn.PrepareBatch(ctx, insertClicksQuery)
+	if err != nil {
+		return err
+	}
+	defer batch.Abort()
+
+	n := len(clicks)
+	userIds := make([]int64, 0, n)
+	shortCodes := make([]string, 0, n)
+	countries := make([]string, 0, n)
+	regions := make([]string, 0, n)
+	cities := make([]string, 0, n)
+	asns := make([]uint32, 0, n)
+	isps := make([]string, 0, n)
+	visitors := make([]uint64, 0, n)
+	userAgents := make([]string, 0, n)
+	devices := make([]string, 0, n)
+	browsers := make([]string, 0, n)
+	systems := make([]string, 0, n)
+	referers := make([]string, 0, n)
+	refererHosts := make([]string, 0, n)
+	channels := make([]string, 0, n)
+	utmSources := make([]string, 0, n)
+	utmMediums := make([]string, 0, n)
+	utmCampaigns := make([]string, 0, n)
+	utmTerms := make([]string, 0, n)
+	utmContents := make([]string, 0, n)
+	clickedAt := make([]time.Time, 0, n)
+
+	for _, c := range clicks {
+		userIds = append(userIds, c.UserId)
+		shortCodes = append(shortCodes, c.ShortCode)
+		countries = append(countries, c.Country)
+		regions = append(regions, c.Region)
+		cities = append(cities, c.City)
+		asns = append(asns, c.ASN)
+		isps = append(isps, c.ISP)
+		visitors = append(visitors, c.Visitor)
+		userAgents = append(userAgents, c.UserAgent)
+		devices = append(devices, c.Device)
+		browsers = append(browsers, c.Browser)
+		systems = append(systems, c.OS)
+		referers = append(referers, c.Referer)
+		refererHosts = append(refererHosts, c.RefererHost)
+		channels = append(channels, c.Channel)
+		utmSources = append(utmSources, c.UTMSource)
+		utmMediums = append(utmMediums, c.UTMMedium)
+		utmCampaigns = append(utmCampaigns, c.UTMCampaign)
+		utmTerms = append(utmTerms, c.UTMTerm)
+		utmContents = append(utmContents, c.UTMContent)
+		clickedAt = append(clickedAt, c.ClickedAt)
+	}
+
+	columns := []any{
+		userIds, shortCodes, countries, regions, cities, asns, isps, visitors,
+		userAgents, devices, browsers, systems,
+		referers, refererHosts, channels, utmSources, utmMediums, utmCampaigns, utmTerms, utmContents,
+		clickedAt,
+	}
+	for i, column := range columns {
+		if err := batch.Column(i).Append(column); err != nil {
+			return err
+		}
+	}
+	return batch.Send()
+}
@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS browser,
+    DROP COLUMN IF EXISTS device
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS device String AFTER user_agent,
+    ADD COLUMN IF NOT EXISTS browser String AFTER device,
+    ADD COLUMN IF NOT EXISTS os String AFTER browser
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS isp,
+    DROP COLUMN IF EXISTS asn,
+    DROP COLUMN IF EXISTS region
\ No newline at end of file
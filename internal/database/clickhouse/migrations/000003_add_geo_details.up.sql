@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS region String AFTER country,
+    ADD COLUMN IF NOT EXISTS asn UInt32 AFTER city,
+    ADD COLUMN IF NOT EXISTS isp String AFTER asn
\ No newline at end of file
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS rollups_backfill_cutoff;
+DROP VIEW IF EXISTS visitors_daily_mv;
+DROP VIEW IF EXISTS clicks_country_daily_mv;
+DROP VIEW IF EXISTS clicks_daily_mv;
+DROP TABLE IF EXISTS visitors_daily;
+DROP TABLE IF EXISTS clicks_country_daily;
+DROP TABLE IF EXISTS clicks_daily;
+ALTER TABLE clicks DROP COLUMN IF EXISTS visitor_hash;
\ No newline at end of file
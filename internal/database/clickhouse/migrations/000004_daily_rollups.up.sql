@@ -0,0 +1,66 @@
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS visitor_hash UInt64 AFTER isp;
+
+CREATE TABLE IF NOT EXISTS clicks_daily (
+    user_id Int64,
+    short_code String,
+    day Date,
+    clicks UInt64
+)
+ENGINE = SummingMergeTree()
+ORDER BY (user_id, short_code, day);
+
+CREATE TABLE IF NOT EXISTS clicks_country_daily (
+    user_id Int64,
+    short_code String,
+    day Date,
+    country String,
+    clicks UInt64
+)
+ENGINE = SummingMergeTree()
+ORDER BY (user_id, short_code, day, country);
+
+CREATE TABLE IF NOT EXISTS visitors_daily (
+    user_id Int64,
+    short_code String,
+    day Date,
+    visitors AggregateFunction(uniq, UInt64)
+)
+ENGINE = AggregatingMergeTree()
+ORDER BY (user_id, short_code, day);
+
+-- The views are created before the backfill, so that no click inserted
+-- meanwhile is missed. The backfill stops at the moment recorded right
+-- before the views were created, so that it does not count the clicks the
+-- views already did.
+CREATE TABLE IF NOT EXISTS rollups_backfill_cutoff
+ENGINE = Memory AS
+SELECT now64(3, 'UTC') AS cutoff;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_daily_mv TO clicks_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_country_daily_mv TO clicks_country_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, country, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day, country;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS visitors_daily_mv TO visitors_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, uniqState(visitor_hash) AS visitors
+FROM clicks
+GROUP BY user_id, short_code, day;
+
+INSERT INTO clicks_daily
+SELECT user_id, short_code, toDate(clicked_at) AS day, count() AS clicks
+FROM clicks
+WHERE clicked_at < (SELECT cutoff FROM rollups_backfill_cutoff)
+GROUP BY user_id, short_code, day;
+
+INSERT INTO clicks_country_daily
+SELECT user_id, short_code, toDate(clicked_at) AS day, country, count() AS clicks
+FROM clicks
+WHERE clicked_at < (SELECT cutoff FROM rollups_backfill_cutoff)
+GROUP BY user_id, short_code, day, country;
+
+DROP TABLE IF EXISTS rollups_backfill_cutoff;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- See the up migration for how the table is swapped.
+CREATE TABLE IF NOT EXISTS clicks_unpartitioned AS clicks
+ENGINE = MergeTree()
+ORDER BY (user_id, short_code, clicked_at);
+
+RENAME TABLE clicks TO clicks_old;
+
+DROP VIEW IF EXISTS clicks_daily_mv;
+DROP VIEW IF EXISTS clicks_country_daily_mv;
+DROP VIEW IF EXISTS visitors_daily_mv;
+
+INSERT INTO clicks_unpartitioned SELECT * FROM clicks_old;
+
+ALTER TABLE clicks_unpartitioned ADD CONSTRAINT migrating CHECK 0;
+
+RENAME TABLE clicks_unpartitioned TO clicks;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_daily_mv TO clicks_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_country_daily_mv TO clicks_country_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, country, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day, country;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS visitors_daily_mv TO visitors_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, uniqState(visitor_hash) AS visitors
+FROM clicks
+GROUP BY user_id, short_code, day;
+
+ALTER TABLE clicks DROP CONSTRAINT migrating;
+
+DROP TABLE IF EXISTS clicks_old;
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- Clicks are not written while the table is swapped: first there is no
+-- clicks table, then the migrating constraint rejects every insert until the
+-- views are back. The failed batches are spooled by the ingest pipeline and
+-- written again later, so the views see every click. The raw TTL is set by
+-- ApplyRetention, see CLICKHOUSE_RAW_TTL_DAYS.
+CREATE TABLE IF NOT EXISTS clicks_partitioned AS clicks
+ENGINE = MergeTree()
+PARTITION BY toYYYYMM(clicked_at)
+ORDER BY (user_id, short_code, clicked_at);
+
+RENAME TABLE clicks TO clicks_old;
+
+DROP VIEW IF EXISTS clicks_daily_mv;
+DROP VIEW IF EXISTS clicks_country_daily_mv;
+DROP VIEW IF EXISTS visitors_daily_mv;
+
+INSERT INTO clicks_partitioned SELECT * FROM clicks_old;
+
+ALTER TABLE clicks_partitioned ADD CONSTRAINT migrating CHECK 0;
+
+RENAME TABLE clicks_partitioned TO clicks;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_daily_mv TO clicks_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_country_daily_mv TO clicks_country_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, country, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day, country;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS visitors_daily_mv TO visitors_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, uniqState(visitor_hash) AS visitors
+FROM clicks
+GROUP BY user_id, short_code, day;
+
+ALTER TABLE clicks DROP CONSTRAINT migrating;
+
+DROP TABLE IF EXISTS clicks_old;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS utm_content,
+    DROP COLUMN IF EXISTS utm_term,
+    DROP COLUMN IF EXISTS utm_campaign,
+    DROP COLUMN IF EXISTS utm_medium,
+    DROP COLUMN IF EXISTS utm_source,
+    DROP COLUMN IF EXISTS channel,
+    DROP COLUMN IF EXISTS referer_host
\ No newline at end of file
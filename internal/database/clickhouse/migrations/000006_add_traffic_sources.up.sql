@@ -0,0 +1,8 @@
+ALTER TABLE clicks
+    ADD COLUMN IF NOT EXISTS referer_host String AFTER referer,
+    ADD COLUMN IF NOT EXISTS channel LowCardinality(String) AFTER referer_host,
+    ADD COLUMN IF NOT EXISTS utm_source String AFTER channel,
+    ADD COLUMN IF NOT EXISTS utm_medium String AFTER utm_source,
+    ADD COLUMN IF NOT EXISTS utm_campaign String AFTER utm_medium,
+    ADD COLUMN IF NOT EXISTS utm_term String AFTER utm_campaign,
+    ADD COLUMN IF NOT EXISTS utm_content String AFTER utm_term
\ No newline at end of file
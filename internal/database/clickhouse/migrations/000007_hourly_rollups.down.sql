@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS rollups_backfill_cutoff;
+DROP VIEW IF EXISTS clicks_hourly_mv;
+
+DROP TABLE IF EXISTS clicks_hourly;
\ No newline at end of file
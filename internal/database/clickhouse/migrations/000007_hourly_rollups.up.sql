@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS clicks_hourly (
+    user_id Int64,
+    short_code String,
+    hour DateTime('UTC'),
+    clicks UInt64
+)
+ENGINE = SummingMergeTree()
+PARTITION BY toYYYYMM(hour)
+ORDER BY (user_id, short_code, hour);
+
+-- See 000004 for why the view comes before the backfill.
+CREATE TABLE IF NOT EXISTS rollups_backfill_cutoff
+ENGINE = Memory AS
+SELECT now64(3, 'UTC') AS cutoff;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_hourly_mv TO clicks_hourly AS
+SELECT user_id, short_code, toStartOfHour(clicked_at, 'UTC') AS hour, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, hour;
+
+INSERT INTO clicks_hourly
+SELECT user_id, short_code, toStartOfHour(clicked_at, 'UTC') AS hour, count() AS clicks
+FROM clicks
+WHERE clicked_at < (SELECT cutoff FROM rollups_backfill_cutoff)
+GROUP BY user_id, short_code, hour;
+
+DROP TABLE IF EXISTS rollups_backfill_cutoff;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP VIEW IF EXISTS clicks_source_daily_mv;
+DROP VIEW IF EXISTS clicks_city_daily_mv;
+
+DROP TABLE IF EXISTS clicks_source_daily;
+DROP TABLE IF EXISTS clicks_city_daily;
\ No newline at end of file
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS clicks_city_daily (
+    user_id Int64,
+    short_code String,
+    day Date,
+    city String,
+    clicks UInt64
+)
+ENGINE = SummingMergeTree()
+ORDER BY (user_id, short_code, day, city);
+
+CREATE TABLE IF NOT EXISTS clicks_source_daily (
+    user_id Int64,
+    short_code String,
+    day Date,
+    channel LowCardinality(String),
+    source String,
+    clicks UInt64
+)
+ENGINE = SummingMergeTree()
+ORDER BY (user_id, short_code, day, channel, source);
+
+-- See 000004 for why the views come before the backfill.
+CREATE TABLE IF NOT EXISTS rollups_backfill_cutoff
+ENGINE = Memory AS
+SELECT now64(3, 'UTC') AS cutoff;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_city_daily_mv TO clicks_city_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, city, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day, city;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS clicks_source_daily_mv TO clicks_source_daily AS
+SELECT user_id, short_code, toDate(clicked_at) AS day, channel,
+    if(referer_host != '', referer_host, utm_source) AS source, count() AS clicks
+FROM clicks
+GROUP BY user_id, short_code, day, channel, source;
+
+INSERT INTO clicks_city_daily
+SELECT user_id, short_code, toDate(clicked_at) AS day, city, count() AS clicks
+FROM clicks
+WHERE clicked_at < (SELECT cutoff FROM rollups_backfill_cutoff)
+GROUP BY user_id, short_code, day, city;
+
+INSERT INTO clicks_source_daily
+SELECT user_id, short_code, toDate(clicked_at) AS day, channel,
+    if(referer_host != '', referer_host, utm_source) AS source, count() AS clicks
+FROM clicks
+WHERE clicked_at < (SELECT cutoff FROM rollups_backfill_cutoff)
+GROUP BY user_id, short_code, day, channel, source;
+
+DROP TABLE IF EXISTS rollups_backfill_cutoff;
\ No newline at end of file
@@ -0,0 +1,89 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var rollupTables = []string{"clicks_daily", "clicks_country_daily", "clicks_city_daily", "clicks_source_daily", "visitors_daily", "clicks_hourly"}
+
+// rollupTTLColumn returns the expression the TTL of a rollup table is based on.
+func rollupTTLColumn(table string) string {
+	if table == "clicks_hourly" {
+		return "hour"
+	}
+	return "day"
+}
+
+type PartitionStat struct {
+	Table       string `db:"table"`
+	Partition   string `db:"partition"`
+	Parts       uint64 `db:"parts"`
+	Rows        uint64 `db:"rows"`
+	BytesOnDisk uint64 `db:"bytes_on_disk"`
+}
+
+// ApplyRetention brings table TTLs in line with the configuration. A TTL of
+// zero days keeps the data forever. Tables whose TTL already matches are left
+// alone so that restarts do not schedule needless TTL mutations.
+func (a *ClickHouse) ApplyRetention(ctx context.Context, rawTTLDays, rollupTTLDays int) error {
+	if err := a.setTTL(ctx, "clicks", "toDateTime(clicked_at)", rawTTLDays); err != nil {
+		return err
+	}
+	for _, table := range rollupTables {
+		if err := a.setTTL(ctx, table, rollupTTLColumn(table), rollupTTLDays); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ClickHouse) setTTL(ctx context.Context, table, column string, days int) error {
+	var engine string
+	err := a.db.GetContext(ctx, &engine,
+		`SELECT engine_full FROM system.tables WHERE database = currentDatabase() AND name = ?`, table)
+	if err != nil {
+		return err
+	}
+
+	var query string
+	if days > 0 {
+		if strings.Contains(engine, fmt.Sprintf("TTL %s + toIntervalDay(%d)", column, days)) {
+			return nil
+		}
+		query = fmt.Sprintf("ALTER TABLE %s MODIFY TTL %s + INTERVAL %d DAY", table, column, days)
+	} else {
+		if !strings.Contains(engine, " TTL ") {
+			return nil
+		}
+		query = fmt.Sprintf("ALTER TABLE %s REMOVE TTL", table)
+	}
+
+	if _, err := a.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	slog.Info("ClickHouse retention updated", "table", table, "ttl_days", days)
+	return nil
+}
+
+func (a *ClickHouse) PartitionStats(ctx context.Context) ([]PartitionStat, error) {
+	query := `
+		SELECT table, partition, count() AS parts, sum(rows) AS rows, sum(bytes_on_disk) AS bytes_on_disk
+		FROM system.parts
+		WHERE database = currentDatabase() AND active AND has(?, table)
+		GROUP BY table, partition
+		ORDER BY table, partition`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var stats []PartitionStat
+	err := a.db.SelectContext(ctx, &stats, query, append([]string{"clicks"}, rollupTables...))
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,272 @@
+package clickhouse
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"time"
+)
+
+// Rollup tables are kept up to date by materialized views, but the most
+// recent day is still read from the raw clicks table so that late merges of
+// the Summing/AggregatingMergeTree parts never make fresh numbers look stale.
+const rollupLag = 24 * time.Hour
+
+// rollupRange splits a query range [from, to) into the whole rollup buckets
+// [start, split), which are read from a rollup table, and the raw parts
+// [from, start) and [split, to), which are read from the clicks table. Only
+// whole buckets may come from a rollup: a bucket that is cut by from or to
+// would count the clicks outside the range as well.
+type rollupRange struct {
+	from, start, split, to time.Time
+}
+
+// splitRange returns the rollupRange of [from, to) for rollup buckets of the
+// given size, which must divide a day. Buckets are aligned to UTC, like the
+// rollup tables.
+func splitRange(from, to, now time.Time, bucket time.Duration) rollupRange {
+	split := now.UTC().Add(-rollupLag).Truncate(24 * time.Hour)
+	if to.Before(split) {
+		split = to.UTC().Truncate(bucket)
+	}
+	start := from.UTC().Truncate(bucket)
+	if start.Before(from) {
+		start = start.Add(bucket)
+	}
+	if !start.Before(split) {
+		start, split = from, from
+	}
+	return rollupRange{from: from.UTC(), start: start, split: split, to: to.UTC()}
+}
+
+const (
+	dailyRollupRange  = "day >= toDate(?) AND day < toDate(?)"
+	hourlyRollupRange = "hour >= ? AND hour < ?"
+	rawRange          = "((clicked_at >= ? AND clicked_at < ?) OR (clicked_at >= ? AND clicked_at < ?))"
+)
+
+// args builds the arguments for a rollup subquery followed by a raw
+// subquery, both filtered by the same link condition.
+func (r rollupRange) args(cond []any) []any {
+	args := make([]any, 0, 2*len(cond)+6)
+	args = append(args, cond...)
+	args = append(args, r.start, r.split)
+	args = append(args, cond...)
+	args = append(args, r.from, r.start, r.split, r.to)
+	return args
+}
+
+// zonedArgs is args for queries whose rollup and raw subqueries each take the
+// timezone as their first argument.
+func (r rollupRange) zonedArgs(timezone string, cond []any) []any {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	args := make([]any, 0, 2*len(cond)+8)
+	args = append(args, timezone)
+	args = append(args, cond...)
+	args = append(args, r.start, r.split)
+	args = append(args, timezone)
+	args = append(args, cond...)
+	args = append(args, r.from, r.start, r.split, r.to)
+	return args
+}
+
+func linkCondition(f types.AnalyticsFilter) (string, []any) {
+	if f.ShortCode == "" {
+		return "user_id = ?", []any{f.UserId}
+	}
+	return "user_id = ? AND short_code = ?", []any{f.UserId, f.ShortCode}
+}
+
+// GetDailyClicks reads the hourly rollup rather than clicks_daily so that
+// days can be bucketed in the filter's timezone.
+func (a *ClickHouse) GetDailyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	rng := splitRange(filter.From, filter.To, time.Now(), time.Hour)
+	cond, args := linkCondition(filter)
+
+	query := `
+		SELECT day, toInt64(sum(clicks)) AS clicks FROM (
+			SELECT toDate(hour, ?) AS day, sum(clicks) AS clicks FROM clicks_hourly
+			WHERE ` + cond + ` AND ` + hourlyRollupRange + `
+			GROUP BY day
+			UNION ALL
+			SELECT toDate(clicked_at, ?) AS day, count() AS clicks FROM clicks
+			WHERE ` + cond + ` AND ` + rawRange + `
+			GROUP BY day
+		)
+		GROUP BY day
+		ORDER BY day`
+
+	var daily []types.DailyClicks
+	err := a.db.SelectContext(ctx, &daily, query, rng.zonedArgs(filter.Timezone, args)...)
+	if err != nil {
+		return nil, err
+	}
+	return daily, nil
+}
+
+// GetHourlyClicks returns the clicks per weekday and hour of day in the
+// filter's timezone.
+func (a *ClickHouse) GetHourlyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.HourlyClicks, error) {
+	rng := splitRange(filter.From, filter.To, time.Now(), time.Hour)
+	cond, args := linkCondition(filter)
+
+	query := `
+		SELECT toInt64(toDayOfWeek(local)) AS weekday, toInt64(toHour(local)) AS hour, toInt64(sum(clicks)) AS clicks FROM (
+			SELECT toTimeZone(hour, ?) AS local, sum(clicks) AS clicks FROM clicks_hourly
+			WHERE ` + cond + ` AND ` + hourlyRollupRange + `
+			GROUP BY local
+			UNION ALL
+			SELECT toTimeZone(toStartOfHour(toDateTime(clicked_at)), ?) AS local, count() AS clicks FROM clicks
+			WHERE ` + cond + ` AND ` + rawRange + `
+			GROUP BY local
+		)
+		GROUP BY weekday, hour
+		ORDER BY weekday, hour`
+
+	var hourly []types.HourlyClicks
+	err := a.db.SelectContext(ctx, &hourly, query, rng.zonedArgs(filter.Timezone, args)...)
+	if err != nil {
+		return nil, err
+	}
+	return hourly, nil
+}
+
+// GetTotalClicks counts the clicks in the filter's range.
+func (a *ClickHouse) GetTotalClicks(ctx context.Context, filter types.AnalyticsFilter) (int64, error) {
+	rng := splitRange(filter.From, filter.To, time.Now(), 24*time.Hour)
+	cond, args := linkCondition(filter)
+
+	query := `
+		SELECT toInt64(sum(clicks)) FROM (
+			SELECT sum(clicks) AS clicks FROM clicks_daily
+			WHERE ` + cond + ` AND ` + dailyRollupRange + `
+			UNION ALL
+			SELECT count() AS clicks FROM clicks
+			WHERE ` + cond + ` AND ` + rawRange + `
+		)`
+
+	var total int64
+	err := a.db.GetContext(ctx, &total, query, rng.args(args)...)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (a *ClickHouse) GetTopCountries(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "clicks_country_daily", "country", "country", false, filter, limit)
+}
+
+func (a *ClickHouse) GetTopCities(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "clicks_city_daily", "city", "city", false, filter, limit)
+}
+
+func (a *ClickHouse) GetTopChannels(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "clicks_source_daily", "channel", "channel", false, filter, limit)
+}
+
+// GetTopSources counts clicks per referring host, or per utm_source for
+// clicks without a referer. Clicks with neither are left out.
+func (a *ClickHouse) GetTopSources(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "clicks_source_daily", "source", "if(referer_host != '', referer_host, utm_source)", true, filter, limit)
+}
+
+// topStats counts clicks per value of column of a daily rollup, reading raw
+// clicks for the recent part of the range. rawColumn computes the same value
+// from the clicks table. skipEmpty leaves out clicks without a value.
+func (a *ClickHouse) topStats(ctx context.Context, rollup, column, rawColumn string, skipEmpty bool, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	rng := splitRange(filter.From, filter.To, time.Now(), 24*time.Hour)
+	cond, args := linkCondition(filter)
+
+	having := ""
+	if skipEmpty {
+		having = "HAVING key != ''"
+	}
+	query := `
+		SELECT key, toInt64(sum(clicks)) AS clicks FROM (
+			SELECT ` + column + ` AS key, sum(clicks) AS clicks FROM ` + rollup + `
+			WHERE ` + cond + ` AND ` + dailyRollupRange + `
+			GROUP BY key
+			UNION ALL
+			SELECT ` + rawColumn + ` AS key, count() AS clicks FROM clicks
+			WHERE ` + cond + ` AND ` + rawRange + `
+			GROUP BY key
+		)
+		GROUP BY key
+		` + having + `
+		ORDER BY clicks DESC, key
+		LIMIT ?`
+
+	var stats []types.CountStat
+	err := a.db.SelectContext(ctx, &stats, query, append(rng.args(args), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (a *ClickHouse) GetTopLinks(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	rng := splitRange(filter.From, filter.To, time.Now(), time.Hour)
+	cond, args := linkCondition(filter)
+
+	query := `
+		SELECT short_code AS key, toInt64(sum(clicks)) AS clicks FROM (
+			SELECT short_code, sum(clicks) AS clicks FROM clicks_hourly
+			WHERE ` + cond + ` AND ` + hourlyRollupRange + `
+			GROUP BY short_code
+			UNION ALL
+			SELECT short_code, count() AS clicks FROM clicks
+			WHERE ` + cond + ` AND ` + rawRange + `
+			GROUP BY short_code
+		)
+		GROUP BY key
+		ORDER BY clicks DESC
+		LIMIT ?`
+
+	var stats []types.CountStat
+	err := a.db.SelectContext(ctx, &stats, query, append(rng.args(args), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetHotCodes returns the most clicked short codes of all users since the
+// given time, for warming up the link cache.
+func (a *ClickHouse) GetHotCodes(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT short_code FROM clicks_hourly
+		WHERE hour >= toStartOfHour(?)
+		GROUP BY short_code
+		ORDER BY sum(clicks) DESC
+		LIMIT ?`
+
+	var codes []string
+	err := a.db.SelectContext(ctx, &codes, query, since.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (a *ClickHouse) GetUniqueVisitors(ctx context.Context, filter types.AnalyticsFilter) (int64, error) {
+	rng := splitRange(filter.From, filter.To, time.Now(), 24*time.Hour)
+	cond, args := linkCondition(filter)
+
+	query := `
+		SELECT toInt64(uniqMerge(visitors)) FROM (
+			SELECT uniqMergeState(visitors) AS visitors FROM visitors_daily
+			WHERE ` + cond + ` AND ` + dailyRollupRange + `
+			UNION ALL
+			SELECT uniqState(visitor_hash) AS visitors FROM clicks
+			WHERE ` + cond + ` AND ` + rawRange + `
+		)`
+
+	var visitors int64
+	err := a.db.GetContext(ctx, &visitors, query, rng.args(args)...)
+	if err != nil {
+		return 0, err
+	}
+	return visitors, nil
+}
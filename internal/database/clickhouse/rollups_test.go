@@ -0,0 +1,95 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitRange(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 20, 0, 0, time.UTC)
+	// Clicks from 2026-03-09 00:00 UTC on are still read raw, see rollupLag.
+	lagSplit := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	kyiv := time.FixedZone("EET", 2*60*60)
+
+	tests := []struct {
+		name   string
+		from   time.Time
+		to     time.Time
+		bucket time.Duration
+		start  time.Time
+		split  time.Time
+	}{
+		{
+			name:   "aligned range up to now",
+			from:   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			to:     now,
+			bucket: 24 * time.Hour,
+			start:  time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			split:  lagSplit,
+		},
+		{
+			name:   "partial first day is read raw",
+			from:   time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+			to:     now,
+			bucket: 24 * time.Hour,
+			start:  time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			split:  lagSplit,
+		},
+		{
+			name:   "partial first hour is read raw",
+			from:   time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+			to:     now,
+			bucket: time.Hour,
+			start:  time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC),
+			split:  lagSplit,
+		},
+		{
+			name:   "local midnight starts a partial UTC day",
+			from:   time.Date(2026, 3, 1, 0, 0, 0, 0, kyiv),
+			to:     time.Date(2026, 3, 2, 0, 0, 0, 0, kyiv),
+			bucket: 24 * time.Hour,
+			// [02-28 22:00, 03-01 22:00) UTC holds no whole UTC day.
+			start: time.Date(2026, 2, 28, 22, 0, 0, 0, time.UTC),
+			split: time.Date(2026, 2, 28, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "partial last day is read raw",
+			from:   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			to:     time.Date(2026, 3, 5, 6, 0, 0, 0, time.UTC),
+			bucket: 24 * time.Hour,
+			start:  time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			split:  time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "range within one hour",
+			from:   time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+			to:     time.Date(2026, 3, 1, 10, 45, 0, 0, time.UTC),
+			bucket: time.Hour,
+			start:  time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+			split:  time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "recent range after the split",
+			from:   time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC),
+			to:     now,
+			bucket: time.Hour,
+			start:  time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC),
+			split:  time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitRange(tt.from, tt.to, now, tt.bucket)
+			if !got.from.Equal(tt.from) || !got.to.Equal(tt.to) {
+				t.Errorf("raw bounds = [%v, %v), want [%v, %v)", got.from, got.to, tt.from, tt.to)
+			}
+			if !got.start.Equal(tt.start) || !got.split.Equal(tt.split) {
+				t.Errorf("rollup part = [%v, %v), want [%v, %v)", got.start, got.split, tt.start, tt.split)
+			}
+			if got.start.Before(got.from) || got.split.Before(got.start) || got.to.Before(got.split) {
+				t.Errorf("parts overlap: %+v", got)
+			}
+		})
+	}
+}
@@ -2,17 +2,33 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	customerrs "linkshortener/internal/customErrs"
 	"linkshortener/internal/types"
 	"log/slog"
+	"math/rand/v2"
+	"slices"
 	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultLinkCacheTTL = 10 * time.Minute
+	defaultNotFoundTTL  = 30 * time.Second
+	// lookupTimeout bounds a coalesced lookup, which runs detached from the
+	// request that started it.
+	lookupTimeout = 5 * time.Second
 )
 
 //go:generate mockgen -destination=mock_analytics_test.go -package=database . Analytics
 //go:generate mockgen -destination=mock_cache_test.go -package=database . Cache
 //go:generate mockgen -destination=mock_users_repo_test.go -package=database . UsersRepo
 //go:generate mockgen -destination=mock_links_repo_test.go -package=database . LinksRepo
+//go:generate mockgen -destination=mock_alerts_repo_test.go -package=database . AlertsRepo
+//go:generate mockgen -destination=mock_digest_repo_test.go -package=database . DigestRepo
+//go:generate mockgen -destination=mock_invalidations_repo_test.go -package=database . InvalidationsRepo
 //go:generate mockgen -destination=mock_sql_test.go -package=database . SQL
 
 type Analytics interface {
@@ -20,6 +36,18 @@ type Analytics interface {
 	PushClick(data types.ClickData)
 	GetAllAnalytic(ctx context.Context, userId int64) ([]types.Analytic, error)
 	GetAnalyticByCode(ctx context.Context, code string, userId int64) ([]types.Analytic, error)
+	GetDailyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error)
+	GetHourlyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.HourlyClicks, error)
+	GetTotalClicks(ctx context.Context, filter types.AnalyticsFilter) (int64, error)
+	GetTopCountries(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopCities(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopChannels(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopSources(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopLinks(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetHotCodes(ctx context.Context, since time.Time, limit int) ([]string, error)
+	GetUniqueVisitors(ctx context.Context, filter types.AnalyticsFilter) (int64, error)
+	ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error
+	GetLinkRates(ctx context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error)
 	Close() error
 }
 
@@ -28,12 +56,24 @@ type Cache interface {
 	Get(ctx context.Context, shortCode string) (*types.LinkCache, error)
 	Update(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error
 	Delete(ctx context.Context, shortCode string) error
+	InitClickCounters(ctx context.Context, userId int64, shortCode string) error
+	DeleteClickCounters(ctx context.Context, userId int64, shortCodes ...string) error
+	CountClick(ctx context.Context, data types.ClickData) error
+	GetClickCounters(ctx context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error)
 	Close() error
 }
 
 type UsersRepo interface {
-	CreateUser(ctx context.Context, telegramID int64) error
+	CreateUser(ctx context.Context, telegramID int64, timezone string) error
 	GetUserIDByTelegramID(ctx context.Context, telegramID int64) (int64, error)
+	GetUserIDByAPITokenHash(ctx context.Context, tokenHash string) (int64, error)
+	GetTelegramIDByUserID(ctx context.Context, userId int64) (int64, error)
+	SetAPITokenHash(ctx context.Context, userId int64, tokenHash string) error
+	GetUserSettings(ctx context.Context, userId int64) (*types.UserSettings, error)
+	SetCityOptOut(ctx context.Context, userId int64, optOut bool) error
+	// SetTimezone also moves the user's digest subscriptions to the run
+	// computed by next for the new timezone, in the same transaction.
+	SetTimezone(ctx context.Context, userId int64, timezone string, next func(types.DigestSubscription) time.Time) error
 }
 
 type LinksRepo interface {
@@ -43,66 +83,126 @@ type LinksRepo interface {
 	GetAllLinksByUser(ctx context.Context, userId int64) ([]types.LinkData, error)
 	UpdateLink(ctx context.Context, userId int64, shortCode, newLink string) error
 	DeleteLinkByCode(ctx context.Context, userId int64, shortCode string) error
-	DeleteLinkById(ctx context.Context, userId, linkId int64) error
-	DeleteAllLinksByUser(ctx context.Context, userId int64) error
+	DeleteLinkById(ctx context.Context, userId, linkId int64) (string, error)
+	DeleteAllLinksByUser(ctx context.Context, userId int64) ([]string, error)
+	SetLinkCacheTTL(ctx context.Context, userId int64, shortCode string, ttl time.Duration) error
+}
+
+type AlertsRepo interface {
+	GetLinkAlert(ctx context.Context, userId int64, shortCode string) (*types.LinkAlert, error)
+	ListLinkAlerts(ctx context.Context) ([]types.LinkAlert, error)
+	SaveLinkAlert(ctx context.Context, alert types.LinkAlert) error
+	ClaimAlert(ctx context.Context, userId int64, shortCode string, cutoff time.Time) (bool, error)
+}
+
+type DigestRepo interface {
+	GetDigestSubscriptions(ctx context.Context, userId int64) ([]types.DigestSubscription, error)
+	SaveDigestSubscription(ctx context.Context, sub types.DigestSubscription) error
+	DeleteDigestSubscription(ctx context.Context, userId int64, period string) error
+	ClaimDueDigests(ctx context.Context, now time.Time, next func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error)
+}
+
+// InvalidationsRepo is the outbox of cache invalidations. Every change that
+// makes a cached link stale records its code in the same transaction, so
+// the invalidation outlives a crash between the commit and the cache delete.
+type InvalidationsRepo interface {
+	// PendingInvalidations returns up to limit of the oldest invalidations
+	// that have not been acknowledged.
+	PendingInvalidations(ctx context.Context, limit int) ([]types.Invalidation, error)
+	AckInvalidations(ctx context.Context, ids []int64) error
 }
 
 type SQL interface {
 	UsersRepo
 	LinksRepo
+	AlertsRepo
+	DigestRepo
+	InvalidationsRepo
 	Close() error
 }
 
+type CacheConfig struct {
+	// TTL applies to links without their own cache TTL.
+	TTL time.Duration
+	// NotFoundTTL keeps lookups of unknown codes, mostly from scanners, away
+	// from PostgreSQL for a while. The entry is dropped as soon as the code
+	// is created.
+	NotFoundTTL time.Duration
+}
+
 type Database struct {
 	analytics Analytics
 	cache     Cache
 	sql       SQL
+	cacheCfg  CacheConfig
+	lookups   singleflight.Group
+
+	invalidations invalidations
 }
 
-func CreateDatabase(ctx context.Context, analytics Analytics, sql SQL, cache Cache) *Database {
+func CreateDatabase(ctx context.Context, analytics Analytics, sql SQL, cache Cache, cacheCfg CacheConfig) *Database {
+	if cacheCfg.TTL <= 0 {
+		cacheCfg.TTL = defaultLinkCacheTTL
+	}
+	if cacheCfg.NotFoundTTL <= 0 {
+		cacheCfg.NotFoundTTL = defaultNotFoundTTL
+	}
 	analytics.Start(ctx)
-	return &Database{
+	d := &Database{
 		analytics: analytics,
 		sql:       sql,
 		cache:     cache,
+		cacheCfg:  cacheCfg,
 	}
+	d.invalidations.wake = make(chan struct{}, 1)
+	go d.retryInvalidations(ctx)
+	return d
 }
 
-func (d *Database) CreateUser(ctx context.Context, telegramID int64) error {
-	return d.sql.CreateUser(ctx, telegramID)
+func (d *Database) CreateUser(ctx context.Context, telegramID int64, timezone string) error {
+	return d.sql.CreateUser(ctx, telegramID, timezone)
 }
 
 func (d *Database) CreateLink(ctx context.Context, userID int64, originalLink string) (int64, error) {
 	return d.sql.CreateLink(ctx, userID, originalLink)
 }
 
+// SetShortCode also drops the negative cache entry the code may have from
+// the lookups made while it was free.
 func (d *Database) SetShortCode(ctx context.Context, id int64, shortCode string) error {
-	return d.sql.SetShortCode(ctx, id, shortCode)
+	if err := d.sql.SetShortCode(ctx, id, shortCode); err != nil {
+		return err
+	}
+	d.invalidate(ctx, shortCode)
+	return nil
 }
 
 func (d *Database) UpdateLink(ctx context.Context, userId int64, shortCode, newLink string) error {
 	if err := d.sql.UpdateLink(ctx, userId, shortCode, newLink); err != nil {
 		return err
 	}
-	return d.cache.Update(ctx, shortCode, &types.LinkCache{OriginalLink: newLink, UserID: userId}, 10*time.Minute)
+	d.invalidate(ctx, shortCode)
+	return nil
 }
 
 func (d *Database) DeleteLinkByCode(ctx context.Context, userId int64, shortCode string) error {
 	if err := d.sql.DeleteLinkByCode(ctx, userId, shortCode); err != nil {
 		return err
 	}
-	if err := d.cache.Delete(ctx, shortCode); err != nil {
-		return err
-	}
+	d.invalidate(ctx, shortCode)
+	d.deleteClickCounters(ctx, userId, shortCode)
 	return nil
 }
 
+// GetLinkCacheByCode resolves a short code through the cache. On a miss,
+// concurrent lookups of the same code share a single database query, and
+// codes that do not exist are cached as such for a short while.
 func (d *Database) GetLinkCacheByCode(ctx context.Context, shortCode string) (*types.LinkCache, error) {
-	var err error
-	var linkCache *types.LinkCache
-	linkCache, err = d.cache.Get(ctx, shortCode)
-
+	linkCache, err := d.cache.Get(ctx, shortCode)
 	if err == nil {
+		if linkCache.NotFound {
+			return nil, customerrs.NotFound(sql.ErrNoRows)
+		}
 		return linkCache, nil
 	}
 
@@ -110,48 +210,178 @@ func (d *Database) GetLinkCacheByCode(ctx context.Context, shortCode string) (*t
 		slog.Warn("Cache error", "error", err)
 	}
 
-	linkCache, err = d.sql.GetLink(ctx, shortCode)
+	result := d.lookups.DoChan(shortCode, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+		defer cancel()
+		return d.loadLink(ctx, shortCode)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		linkCache, _ := res.Val.(*types.LinkCache)
+		return linkCache, res.Err
+	}
+}
 
+// loadLink reads a link from the database and caches it. A mutation may
+// commit and invalidate the code between the read and the Set, in which case
+// the entry just cached is stale and is deleted again. Failing to cache the
+// link is only logged: the link was read, so the redirect can still be
+// served.
+func (d *Database) loadLink(ctx context.Context, shortCode string) (*types.LinkCache, error) {
+	generation := d.invalidations.generation(shortCode)
+	before := generation.Load()
+	defer func() {
+		if generation.Load() != before {
+			d.invalidate(ctx, shortCode)
+		}
+	}()
+
+	linkCache, err := d.sql.GetLink(ctx, shortCode)
+	if errors.Is(err, customerrs.ErrNoFound) {
+		if err := d.cache.Set(ctx, shortCode, &types.LinkCache{NotFound: true}, jitter(d.cacheCfg.NotFoundTTL)); err != nil {
+			slog.Warn("Failed to cache missing code", "error", err)
+		}
+		return nil, err
+	}
 	if err != nil {
 		slog.Error("Database error", "error", err)
 		return nil, err
 	}
 
-	if err = d.cache.Set(ctx, shortCode, linkCache, 10*time.Minute); err != nil {
-		slog.Warn("Failed to warm up cache", "error", err)
-		return linkCache, err
+	if err = d.cache.Set(ctx, shortCode, linkCache, jitter(d.cacheTTL(linkCache))); err != nil {
+		slog.Warn("Failed to cache link", "short_code", shortCode, "error", err)
 	}
-
 	return linkCache, nil
 }
 
+func (d *Database) cacheTTL(link *types.LinkCache) time.Duration {
+	if link.CacheTTLSeconds > 0 {
+		return time.Duration(link.CacheTTLSeconds) * time.Second
+	}
+	return d.cacheCfg.TTL
+}
+
+// WarmUp loads the codes clicked most since the given time into the cache,
+// so that a fresh deploy does not send all hot traffic to PostgreSQL at
+// once. It returns the number of links loaded.
+func (d *Database) WarmUp(ctx context.Context, since time.Time, limit int) (int, error) {
+	codes, err := d.analytics.GetHotCodes(ctx, since, limit)
+	if err != nil {
+		return 0, err
+	}
+	warmed := 0
+	for _, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return warmed, err
+		}
+		if _, err := d.loadLink(ctx, code); err == nil {
+			warmed++
+		}
+	}
+	return warmed, nil
+}
+
+// jitter spreads a TTL by ±10% so that entries cached together do not all
+// expire at the same moment.
+func jitter(ttl time.Duration) time.Duration {
+	spread := int64(ttl / 5)
+	if spread <= 0 {
+		return ttl
+	}
+	return ttl - ttl/10 + time.Duration(rand.Int64N(spread))
+}
+
 func (d *Database) GetUserIDByTelegramID(ctx context.Context, telegramID int64) (int64, error) {
 	return d.sql.GetUserIDByTelegramID(ctx, telegramID)
 }
 
+func (d *Database) GetUserIDByAPITokenHash(ctx context.Context, tokenHash string) (int64, error) {
+	return d.sql.GetUserIDByAPITokenHash(ctx, tokenHash)
+}
+
+func (d *Database) GetTelegramIDByUserID(ctx context.Context, userId int64) (int64, error) {
+	return d.sql.GetTelegramIDByUserID(ctx, userId)
+}
+
+func (d *Database) SetAPITokenHash(ctx context.Context, userId int64, tokenHash string) error {
+	return d.sql.SetAPITokenHash(ctx, userId, tokenHash)
+}
+
+func (d *Database) GetUserSettings(ctx context.Context, userId int64) (*types.UserSettings, error) {
+	return d.sql.GetUserSettings(ctx, userId)
+}
+
+// SetCityOptOut also drops the user's links from the cache, because cached
+// entries carry the opt-out flag to the redirect handler.
+func (d *Database) SetCityOptOut(ctx context.Context, userId int64, optOut bool) error {
+	if err := d.sql.SetCityOptOut(ctx, userId, optOut); err != nil {
+		return err
+	}
+	links, err := d.sql.GetAllLinksByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	codes := make([]string, 0, len(links))
+	for _, link := range links {
+		codes = append(codes, link.ShortCode)
+	}
+	d.invalidate(ctx, codes...)
+	return nil
+}
+
+func (d *Database) SetTimezone(ctx context.Context, userId int64, timezone string, next func(types.DigestSubscription) time.Time) error {
+	return d.sql.SetTimezone(ctx, userId, timezone, next)
+}
+
 func (d *Database) GetAllLinksByUser(ctx context.Context, userId int64) ([]types.LinkData, error) {
 	return d.sql.GetAllLinksByUser(ctx, userId)
 }
 
+// SetLinkCacheTTL overrides the cache TTL of one link; zero restores the
+// global TTL. The cached entry is dropped so the new TTL applies right away.
+func (d *Database) SetLinkCacheTTL(ctx context.Context, userId int64, shortCode string, ttl time.Duration) error {
+	if err := d.sql.SetLinkCacheTTL(ctx, userId, shortCode, ttl); err != nil {
+		return err
+	}
+	d.invalidate(ctx, shortCode)
+	return nil
+}
+
 func (d *Database) DeleteLinkById(ctx context.Context, userId, linkId int64) error {
-	return d.sql.DeleteLinkById(ctx, userId, linkId)
+	shortCode, err := d.sql.DeleteLinkById(ctx, userId, linkId)
+	if err != nil {
+		return err
+	}
+	d.invalidate(ctx, shortCode)
+	d.deleteClickCounters(ctx, userId, shortCode)
+	return nil
 }
 
 func (d *Database) DeleteAllLinksByUser(ctx context.Context, userId int64) error {
-	linksData, err := d.sql.GetAllLinksByUser(ctx, userId)
+	codes, err := d.sql.DeleteAllLinksByUser(ctx, userId)
 	if err != nil {
 		return err
 	}
-	err = d.sql.DeleteAllLinksByUser(ctx, userId)
-	if err != nil {
-		return nil
+	d.invalidate(ctx, codes...)
+	d.deleteClickCounters(ctx, userId, codes...)
+	return nil
+}
+
+// deleteClickCounters drops the counters of deleted links, so that a code
+// published again starts from zero. The links are already gone, so a
+// failure is only logged; the counters are unreachable without the link.
+func (d *Database) deleteClickCounters(ctx context.Context, userId int64, shortCodes ...string) {
+	shortCodes = slices.DeleteFunc(slices.Clone(shortCodes), func(code string) bool { return code == "" })
+	if len(shortCodes) == 0 {
+		return
 	}
-	for _, data := range linksData {
-		if err = d.cache.Delete(ctx, data.ShortCode); err != nil {
-			return err
-		}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+	defer cancel()
+	if err := d.cache.DeleteClickCounters(ctx, userId, shortCodes...); err != nil {
+		slog.Warn("Failed to delete click counters", "user_id", userId, "error", err)
 	}
-	return nil
 }
 
 func (d *Database) Close() error {
@@ -171,6 +401,20 @@ func (d *Database) PushClick(data types.ClickData) {
 	d.analytics.PushClick(data)
 }
 
+// InitClickCounters starts the real-time counters of a newly published
+// link, see Cache.
+func (d *Database) InitClickCounters(ctx context.Context, userId int64, shortCode string) error {
+	return d.cache.InitClickCounters(ctx, userId, shortCode)
+}
+
+func (d *Database) CountClick(ctx context.Context, data types.ClickData) error {
+	return d.cache.CountClick(ctx, data)
+}
+
+func (d *Database) GetClickCounters(ctx context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error) {
+	return d.cache.GetClickCounters(ctx, userId, shortCodes, day)
+}
+
 func (d *Database) GetAllAnalytic(ctx context.Context, userId int64) ([]types.Analytic, error) {
 	return d.analytics.GetAllAnalytic(ctx, userId)
 }
@@ -178,3 +422,83 @@ func (d *Database) GetAllAnalytic(ctx context.Context, userId int64) ([]types.An
 func (d *Database) GetAnalyticByCode(ctx context.Context, code string, userId int64) ([]types.Analytic, error) {
 	return d.analytics.GetAnalyticByCode(ctx, code, userId)
 }
+
+func (d *Database) GetDailyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	return d.analytics.GetDailyClicks(ctx, filter)
+}
+
+func (d *Database) GetHourlyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.HourlyClicks, error) {
+	return d.analytics.GetHourlyClicks(ctx, filter)
+}
+
+func (d *Database) GetTotalClicks(ctx context.Context, filter types.AnalyticsFilter) (int64, error) {
+	return d.analytics.GetTotalClicks(ctx, filter)
+}
+
+func (d *Database) GetTopCountries(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return d.analytics.GetTopCountries(ctx, filter, limit)
+}
+
+func (d *Database) GetTopCities(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return d.analytics.GetTopCities(ctx, filter, limit)
+}
+
+func (d *Database) GetTopChannels(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return d.analytics.GetTopChannels(ctx, filter, limit)
+}
+
+func (d *Database) GetTopSources(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return d.analytics.GetTopSources(ctx, filter, limit)
+}
+
+func (d *Database) GetTopLinks(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return d.analytics.GetTopLinks(ctx, filter, limit)
+}
+
+func (d *Database) GetHotCodes(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	return d.analytics.GetHotCodes(ctx, since, limit)
+}
+
+func (d *Database) GetUniqueVisitors(ctx context.Context, filter types.AnalyticsFilter) (int64, error) {
+	return d.analytics.GetUniqueVisitors(ctx, filter)
+}
+
+func (d *Database) ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error {
+	return d.analytics.ExportClicks(ctx, filter, limit, fn)
+}
+
+func (d *Database) GetLinkRates(ctx context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error) {
+	return d.analytics.GetLinkRates(ctx, baselineFrom, windowFrom, to)
+}
+
+func (d *Database) GetLinkAlert(ctx context.Context, userId int64, shortCode string) (*types.LinkAlert, error) {
+	return d.sql.GetLinkAlert(ctx, userId, shortCode)
+}
+
+func (d *Database) ListLinkAlerts(ctx context.Context) ([]types.LinkAlert, error) {
+	return d.sql.ListLinkAlerts(ctx)
+}
+
+func (d *Database) SaveLinkAlert(ctx context.Context, alert types.LinkAlert) error {
+	return d.sql.SaveLinkAlert(ctx, alert)
+}
+
+func (d *Database) ClaimAlert(ctx context.Context, userId int64, shortCode string, cutoff time.Time) (bool, error) {
+	return d.sql.ClaimAlert(ctx, userId, shortCode, cutoff)
+}
+
+func (d *Database) GetDigestSubscriptions(ctx context.Context, userId int64) ([]types.DigestSubscription, error) {
+	return d.sql.GetDigestSubscriptions(ctx, userId)
+}
+
+func (d *Database) SaveDigestSubscription(ctx context.Context, sub types.DigestSubscription) error {
+	return d.sql.SaveDigestSubscription(ctx, sub)
+}
+
+func (d *Database) DeleteDigestSubscription(ctx context.Context, userId int64, period string) error {
+	return d.sql.DeleteDigestSubscription(ctx, userId, period)
+}
+
+func (d *Database) ClaimDueDigests(ctx context.Context, now time.Time, next func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error) {
+	return d.sql.ClaimDueDigests(ctx, now, next)
+}
@@ -0,0 +1,339 @@
+package database
+
+import (
+	"context"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func newTestDatabase(t *testing.T) (*Database, *MockCache, *MockSQL) {
+	ctrl := gomock.NewController(t)
+	cache := NewMockCache(ctrl)
+	sqlDB := NewMockSQL(ctrl)
+	return &Database{cache: cache, sql: sqlDB, cacheCfg: CacheConfig{TTL: defaultLinkCacheTTL, NotFoundTTL: defaultNotFoundTTL}}, cache, sqlDB
+}
+
+func TestGetLinkCacheByCodeCachesMissingCodes(t *testing.T) {
+	ctx := context.Background()
+	d, cache, sqlDB := newTestDatabase(t)
+
+	gomock.InOrder(
+		cache.EXPECT().Get(gomock.Any(), "nope").Return(nil, customerrs.ErrNoFound),
+		sqlDB.EXPECT().GetLink(gomock.Any(), "nope").Return(nil, customerrs.ErrNoFound),
+		cache.EXPECT().
+			Set(gomock.Any(), "nope", &types.LinkCache{NotFound: true}, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, _ *types.LinkCache, ttl time.Duration) error {
+				if ttl <= 0 || ttl > defaultNotFoundTTL*11/10 {
+					t.Errorf("negative entry TTL = %v", ttl)
+				}
+				return nil
+			}),
+		cache.EXPECT().Get(gomock.Any(), "nope").Return(&types.LinkCache{NotFound: true}, nil),
+	)
+
+	for range 2 {
+		link, err := d.GetLinkCacheByCode(ctx, "nope")
+		if link != nil || !errors.Is(err, customerrs.ErrNoFound) {
+			t.Fatalf("GetLinkCacheByCode() = %v, %v, want customerrs.ErrNoFound", link, err)
+		}
+	}
+}
+
+func TestGetLinkCacheByCodeCoalescesMisses(t *testing.T) {
+	const callers = 10
+	d, cache, sqlDB := newTestDatabase(t)
+
+	var misses atomic.Int32
+	cache.EXPECT().Get(gomock.Any(), "abc").DoAndReturn(func(context.Context, string) (*types.LinkCache, error) {
+		misses.Add(1)
+		return nil, customerrs.ErrNoFound
+	}).Times(callers)
+	sqlDB.EXPECT().GetLink(gomock.Any(), "abc").DoAndReturn(func(context.Context, string) (*types.LinkCache, error) {
+		for misses.Load() < callers {
+			time.Sleep(time.Millisecond)
+		}
+		// Give the last callers time to join the in-flight lookup.
+		time.Sleep(20 * time.Millisecond)
+		return &types.LinkCache{OriginalLink: "https://example.com"}, nil
+	}).Times(1)
+	cache.EXPECT().Set(gomock.Any(), "abc", gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Go(func() {
+			link, err := d.GetLinkCacheByCode(context.Background(), "abc")
+			if err != nil || link.OriginalLink != "https://example.com" {
+				t.Errorf("GetLinkCacheByCode() = %v, %v", link, err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestMutationsInvalidateCache(t *testing.T) {
+	ctx := context.Background()
+	errSQL := errors.New("sql failed")
+
+	tests := []struct {
+		name    string
+		expect  func(cache *MockCache, sqlDB *MockSQL)
+		mutate  func(d *Database) error
+		wantErr error
+	}{
+		{
+			name: "create link",
+			expect: func(_ *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().CreateLink(gomock.Any(), int64(1), "https://example.com").Return(int64(42), nil)
+			},
+			mutate: func(d *Database) error {
+				_, err := d.CreateLink(ctx, 1, "https://example.com")
+				return err
+			},
+		},
+		{
+			name: "set short code",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				gomock.InOrder(
+					sqlDB.EXPECT().SetShortCode(gomock.Any(), int64(42), "abc").Return(nil),
+					cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+				)
+			},
+			mutate: func(d *Database) error { return d.SetShortCode(ctx, 42, "abc") },
+		},
+		{
+			name: "update link",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				gomock.InOrder(
+					sqlDB.EXPECT().UpdateLink(gomock.Any(), int64(1), "abc", "https://new.example.com").Return(nil),
+					cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+				)
+			},
+			mutate: func(d *Database) error { return d.UpdateLink(ctx, 1, "abc", "https://new.example.com") },
+		},
+		{
+			name: "failed update leaves cache alone",
+			expect: func(_ *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().UpdateLink(gomock.Any(), int64(1), "abc", "https://new.example.com").Return(errSQL)
+			},
+			mutate:  func(d *Database) error { return d.UpdateLink(ctx, 1, "abc", "https://new.example.com") },
+			wantErr: errSQL,
+		},
+		{
+			name: "delete link by code",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				gomock.InOrder(
+					sqlDB.EXPECT().DeleteLinkByCode(gomock.Any(), int64(1), "abc").Return(nil),
+					cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+					cache.EXPECT().DeleteClickCounters(gomock.Any(), int64(1), "abc").Return(nil),
+				)
+			},
+			mutate: func(d *Database) error { return d.DeleteLinkByCode(ctx, 1, "abc") },
+		},
+		{
+			name: "delete link by id",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				gomock.InOrder(
+					sqlDB.EXPECT().DeleteLinkById(gomock.Any(), int64(1), int64(42)).Return("abc", nil),
+					cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+					cache.EXPECT().DeleteClickCounters(gomock.Any(), int64(1), "abc").Return(nil),
+				)
+			},
+			mutate: func(d *Database) error { return d.DeleteLinkById(ctx, 1, 42) },
+		},
+		{
+			name: "delete unpublished link by id",
+			expect: func(_ *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().DeleteLinkById(gomock.Any(), int64(1), int64(42)).Return("", nil)
+			},
+			mutate: func(d *Database) error { return d.DeleteLinkById(ctx, 1, 42) },
+		},
+		{
+			name: "delete all links of a user",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().DeleteAllLinksByUser(gomock.Any(), int64(1)).Return([]string{"abc", "xyz"}, nil)
+				cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil)
+				cache.EXPECT().Delete(gomock.Any(), "xyz").Return(nil)
+				cache.EXPECT().DeleteClickCounters(gomock.Any(), int64(1), "abc", "xyz").Return(nil)
+			},
+			mutate: func(d *Database) error { return d.DeleteAllLinksByUser(ctx, 1) },
+		},
+		{
+			name: "failed bulk delete is reported",
+			expect: func(_ *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().DeleteAllLinksByUser(gomock.Any(), int64(1)).Return(nil, errSQL)
+			},
+			mutate:  func(d *Database) error { return d.DeleteAllLinksByUser(ctx, 1) },
+			wantErr: errSQL,
+		},
+		{
+			name: "city opt-out",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().SetCityOptOut(gomock.Any(), int64(1), true).Return(nil)
+				sqlDB.EXPECT().GetAllLinksByUser(gomock.Any(), int64(1)).Return([]types.LinkData{{ShortCode: "abc"}, {ShortCode: "xyz"}}, nil)
+				cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil)
+				cache.EXPECT().Delete(gomock.Any(), "xyz").Return(nil)
+			},
+			mutate: func(d *Database) error { return d.SetCityOptOut(ctx, 1, true) },
+		},
+		{
+			name: "transient cache error is retried",
+			expect: func(cache *MockCache, sqlDB *MockSQL) {
+				sqlDB.EXPECT().DeleteLinkByCode(gomock.Any(), int64(1), "abc").Return(nil)
+				gomock.InOrder(
+					cache.EXPECT().Delete(gomock.Any(), "abc").Return(errors.New("connection reset")),
+					cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+				)
+				cache.EXPECT().DeleteClickCounters(gomock.Any(), int64(1), "abc").Return(nil)
+			},
+			mutate: func(d *Database) error { return d.DeleteLinkByCode(ctx, 1, "abc") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cache, sqlDB := newTestDatabase(t)
+			tt.expect(cache, sqlDB)
+			if err := tt.mutate(d); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvalidateRetriesInBackground(t *testing.T) {
+	d, cache, sqlDB := newTestDatabase(t)
+	errCache := errors.New("redis down")
+	outbox := []types.Invalidation{{Id: 7, ShortCode: "abc"}}
+
+	sqlDB.EXPECT().UpdateLink(gomock.Any(), int64(1), "abc", "https://new.example.com").Return(nil)
+	sqlDB.EXPECT().PendingInvalidations(gomock.Any(), invalidationBatch).Return(outbox, nil).Times(2)
+	gomock.InOrder(
+		cache.EXPECT().Delete(gomock.Any(), "abc").Return(errCache).Times(invalidateAttempts+1),
+		cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+		sqlDB.EXPECT().AckInvalidations(gomock.Any(), []int64{7}).Return(nil),
+	)
+
+	// The update is committed, so a cache failure is not reported.
+	if err := d.UpdateLink(context.Background(), 1, "abc", "https://new.example.com"); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+	if pending := d.drainInvalidations(context.Background()); pending != 1 {
+		t.Errorf("pending after a failed retry = %d, want 1", pending)
+	}
+	if pending := d.drainInvalidations(context.Background()); pending != 0 {
+		t.Errorf("pending after a successful retry = %d, want 0", pending)
+	}
+}
+
+// TestDrainInvalidationsAfterRestart covers a process that died between the
+// commit and the cache delete: the next one finds the code in the outbox.
+func TestDrainInvalidationsAfterRestart(t *testing.T) {
+	d, cache, sqlDB := newTestDatabase(t)
+
+	batch := make([]types.Invalidation, invalidationBatch)
+	var ids []int64
+	for i := range batch {
+		batch[i] = types.Invalidation{Id: int64(i + 1), ShortCode: "abc"}
+		ids = append(ids, int64(i+1))
+	}
+	gomock.InOrder(
+		sqlDB.EXPECT().PendingInvalidations(gomock.Any(), invalidationBatch).Return(batch, nil),
+		cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+		sqlDB.EXPECT().AckInvalidations(gomock.Any(), ids).Return(nil),
+		// A full batch means there may be more.
+		sqlDB.EXPECT().PendingInvalidations(gomock.Any(), invalidationBatch).Return([]types.Invalidation{{Id: 101, ShortCode: "xyz"}}, nil),
+		cache.EXPECT().Delete(gomock.Any(), "xyz").Return(nil),
+		sqlDB.EXPECT().AckInvalidations(gomock.Any(), []int64{101}).Return(nil),
+	)
+
+	if pending := d.drainInvalidations(context.Background()); pending != 0 {
+		t.Errorf("pending = %d, want 0", pending)
+	}
+}
+
+func TestLoadLinkDropsEntryInvalidatedDuringRead(t *testing.T) {
+	d, cache, sqlDB := newTestDatabase(t)
+	link := &types.LinkCache{OriginalLink: "https://old.example.com"}
+
+	gomock.InOrder(
+		cache.EXPECT().Get(gomock.Any(), "abc").Return(nil, customerrs.ErrNoFound),
+		sqlDB.EXPECT().GetLink(gomock.Any(), "abc").DoAndReturn(func(ctx context.Context, _ string) (*types.LinkCache, error) {
+			// An update commits after the link was read.
+			d.invalidate(ctx, "abc")
+			return link, nil
+		}),
+		cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+		cache.EXPECT().Set(gomock.Any(), "abc", link, gomock.Any()).Return(nil),
+		cache.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+	)
+
+	if _, err := d.GetLinkCacheByCode(context.Background(), "abc"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetLinkCacheByCodeServesLinkWhenCachingFails(t *testing.T) {
+	d, cache, sqlDB := newTestDatabase(t)
+	link := &types.LinkCache{OriginalLink: "https://example.com"}
+
+	cache.EXPECT().Get(gomock.Any(), "abc").Return(nil, errors.New("redis down"))
+	sqlDB.EXPECT().GetLink(gomock.Any(), "abc").Return(link, nil)
+	cache.EXPECT().Set(gomock.Any(), "abc", link, gomock.Any()).Return(errors.New("redis down"))
+
+	got, err := d.GetLinkCacheByCode(context.Background(), "abc")
+	if err != nil || got != link {
+		t.Errorf("GetLinkCacheByCode() = %+v, %v; want the link from SQL", got, err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for range 1000 {
+		got := jitter(defaultLinkCacheTTL)
+		if got < defaultLinkCacheTTL*9/10 || got >= defaultLinkCacheTTL*11/10 {
+			t.Fatalf("jitter(%v) = %v, want within ±10%%", defaultLinkCacheTTL, got)
+		}
+	}
+}
+
+func TestGetLinkCacheByCodeUsesPerLinkTTL(t *testing.T) {
+	d, cache, sqlDB := newTestDatabase(t)
+	link := &types.LinkCache{OriginalLink: "https://example.com", CacheTTLSeconds: 86400}
+
+	cache.EXPECT().Get(gomock.Any(), "abc").Return(nil, customerrs.ErrNoFound)
+	sqlDB.EXPECT().GetLink(gomock.Any(), "abc").Return(link, nil)
+	cache.EXPECT().Set(gomock.Any(), "abc", link, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ *types.LinkCache, ttl time.Duration) error {
+			if ttl < 21*time.Hour || ttl > 27*time.Hour {
+				t.Errorf("TTL = %v, want about 24h", ttl)
+			}
+			return nil
+		})
+
+	if _, err := d.GetLinkCacheByCode(context.Background(), "abc"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	d, cache, sqlDB := newTestDatabase(t)
+	analytics := NewMockAnalytics(gomock.NewController(t))
+	d.analytics = analytics
+	since := time.Now().Add(-24 * time.Hour)
+
+	analytics.EXPECT().GetHotCodes(gomock.Any(), since, 3).Return([]string{"abc", "gone", "xyz"}, nil)
+	sqlDB.EXPECT().GetLink(gomock.Any(), "abc").Return(&types.LinkCache{OriginalLink: "https://a.example.com"}, nil)
+	sqlDB.EXPECT().GetLink(gomock.Any(), "gone").Return(nil, customerrs.ErrNoFound)
+	sqlDB.EXPECT().GetLink(gomock.Any(), "xyz").Return(&types.LinkCache{OriginalLink: "https://x.example.com"}, nil)
+	cache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(3)
+
+	warmed, err := d.WarmUp(context.Background(), since, 3)
+	if err != nil || warmed != 2 {
+		t.Errorf("WarmUp() = %d, %v, want 2", warmed, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package ingest turns raw clicks into enriched rows and hands them to an
+// analytics store in batches. Batches the store rejects are kept in an
+// on-disk spool and replayed once the store is back.
+package ingest
+
+import (
+	"hash/fnv"
+	"linkshortener/internal/geoip"
+	"linkshortener/internal/referrer"
+	"linkshortener/internal/types"
+	"linkshortener/internal/useragent"
+	"math/rand/v2"
+	"time"
+)
+
+// Click is a fully enriched click. It is also the record format of the
+// spool, so replaying never depends on GeoIP being available.
+type Click struct {
+	UserId      int64     `json:"user_id"`
+	ShortCode   string    `json:"short_code"`
+	Country     string    `json:"country"`
+	Region      string    `json:"region"`
+	City        string    `json:"city"`
+	ASN         uint32    `json:"asn"`
+	ISP         string    `json:"isp"`
+	Visitor     uint64    `json:"visitor_hash"`
+	UserAgent   string    `json:"user_agent"`
+	Device      string    `json:"device"`
+	Browser     string    `json:"browser"`
+	OS          string    `json:"os"`
+	Referer     string    `json:"referer"`
+	RefererHost string    `json:"referer_host"`
+	Channel     string    `json:"channel"`
+	UTMSource   string    `json:"utm_source"`
+	UTMMedium   string    `json:"utm_medium"`
+	UTMCampaign string    `json:"utm_campaign"`
+	UTMTerm     string    `json:"utm_term"`
+	UTMContent  string    `json:"utm_content"`
+	ClickedAt   time.Time `json:"clicked_at"`
+}
+
+// Enrich resolves the location, device and traffic source of a click. geo
+// may be nil, in which case the location is unknown.
+func Enrich(geo *geoip.Resolver, data types.ClickData) Click {
+	loc := geo.Lookup(data.IP)
+	if data.CityOptOut {
+		loc.City = geoip.Unknown
+		loc.Region = geoip.Unknown
+	}
+	ua := useragent.Parse(data.UserAgent)
+	refererHost := referrer.Normalize(data.Referer)
+	clickedAt := data.ClickedAt
+	if clickedAt.IsZero() {
+		clickedAt = time.Now()
+	}
+	return Click{
+		UserId:      data.UserId,
+		ShortCode:   data.ShortCode,
+		Country:     loc.Country,
+		Region:      loc.Region,
+		City:        loc.City,
+		ASN:         loc.ASN,
+		ISP:         loc.ISP,
+		Visitor:     VisitorHash(data.IP, data.UserAgent),
+		UserAgent:   data.UserAgent,
+		Device:      ua.Device,
+		Browser:     ua.Browser,
+		OS:          ua.OS,
+		Referer:     data.Referer,
+		RefererHost: refererHost,
+		Channel:     referrer.Classify(refererHost, data.UTMSource, data.UTMMedium),
+		UTMSource:   data.UTMSource,
+		UTMMedium:   data.UTMMedium,
+		UTMCampaign: data.UTMCampaign,
+		UTMTerm:     data.UTMTerm,
+		UTMContent:  data.UTMContent,
+		ClickedAt:   clickedAt.UTC(),
+	}
+}
+
+// VisitorHash identifies a visitor for unique counts without storing the IP.
+// Without an IP (DNT, GPC or IP mode none) the user agent alone would merge
+// everyone on the same browser into one visitor, so such a click is hashed
+// with a random salt and counts as a visitor of its own.
+func VisitorHash(ip, userAgent string) uint64 {
+	if ip == "" {
+		return rand.Uint64()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(ip))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+	return h.Sum64()
+}
+
+// Analytic returns the click as it is read back from the store.
+func (c Click) Analytic() types.Analytic {
+	return types.Analytic{
+		UserId:      c.UserId,
+		ShortCode:   c.ShortCode,
+		Country:     c.Country,
+		Region:      c.Region,
+		City:        c.City,
+		ASN:         c.ASN,
+		ISP:         c.ISP,
+		UserAgent:   c.UserAgent,
+		Device:      c.Device,
+		Browser:     c.Browser,
+		OS:          c.OS,
+		Referer:     c.Referer,
+		RefererHost: c.RefererHost,
+		Channel:     c.Channel,
+		UTMSource:   c.UTMSource,
+		UTMMedium:   c.UTMMedium,
+		UTMCampaign: c.UTMCampaign,
+		UTMTerm:     c.UTMTerm,
+		UTMContent:  c.UTMContent,
+		ClickedAt:   c.ClickedAt,
+	}
+}
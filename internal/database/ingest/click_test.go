@@ -0,0 +1,16 @@
+package ingest
+
+import "testing"
+
+func TestVisitorHash(t *testing.T) {
+	const ua = "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0"
+	if VisitorHash("192.0.2.1", ua) != VisitorHash("192.0.2.1", ua) {
+		t.Error("VisitorHash differs for the same IP and user agent")
+	}
+	if VisitorHash("192.0.2.1", ua) == VisitorHash("192.0.2.2", ua) {
+		t.Error("VisitorHash is the same for different IPs")
+	}
+	if VisitorHash("", ua) == VisitorHash("", ua) {
+		t.Error("VisitorHash merges clicks without an IP into one visitor")
+	}
+}
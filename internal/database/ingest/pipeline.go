@@ -0,0 +1,297 @@
+package ingest
+
+import (
+	"context"
+	"linkshortener/internal/geoip"
+	"linkshortener/internal/types"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 1000
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 10000
+
+	spoolMinBackoff = time.Second
+	spoolMaxBackoff = 2 * time.Minute
+)
+
+// Writer stores a batch of clicks. A batch is either stored as a whole or
+// not at all; failed batches are spooled and written again later.
+type Writer interface {
+	WriteClicks(ctx context.Context, clicks []Click) error
+}
+
+type Config struct {
+	// Name prefixes the expvar counters of the spool, e.g. "clickhouse".
+	Name string
+	// SpoolDir disables the spool when empty; failed batches are dropped.
+	SpoolDir      string
+	SpoolMaxBytes int64
+	BatchSize     int
+	FlushInterval time.Duration
+	BufferSize    int
+	Workers       int
+}
+
+// Pipeline enriches pushed clicks on a pool of workers and writes them in
+// batches of BatchSize, or every FlushInterval, whichever comes first.
+type Pipeline struct {
+	w             Writer
+	geo           *geoip.Resolver
+	batchSize     int
+	flushInterval time.Duration
+	workers       int
+	clicksBuffer  chan types.ClickData
+	enriched      chan Click
+	spool         *spool
+	metrics       spoolMetrics
+	pushMu        sync.RWMutex
+	draining      bool
+	pipelineWG    sync.WaitGroup
+	writerDone    chan struct{}
+	workerCancel  context.CancelFunc
+	workerWG      sync.WaitGroup
+	startOnce     sync.Once
+	drainOnce     sync.Once
+	closeOnce     sync.Once
+}
+
+func NewPipeline(cfg Config, geo *geoip.Resolver, w Writer) (*Pipeline, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+
+	p := &Pipeline{
+		w:             w,
+		geo:           geo,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		workers:       cfg.Workers,
+		clicksBuffer:  make(chan types.ClickData, cfg.BufferSize),
+		enriched:      make(chan Click, cfg.BatchSize),
+		metrics:       newSpoolMetrics(cfg.Name),
+	}
+
+	if cfg.SpoolDir != "" {
+		var err error
+		p.spool, err = openSpool(cfg.SpoolDir, cfg.SpoolMaxBytes, p.metrics.bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Pipeline) Start(ctx context.Context) {
+	p.startOnce.Do(func() {
+		workerCtx, cancel := context.WithCancel(ctx)
+		p.workerCancel = cancel
+
+		p.pipelineWG.Add(p.workers)
+		for range p.workers {
+			go p.enricher()
+		}
+		p.writerDone = make(chan struct{})
+		go p.writer()
+
+		go func() {
+			<-workerCtx.Done()
+			p.drain()
+		}()
+
+		if p.spool != nil {
+			p.workerWG.Add(1)
+			go p.replayer(workerCtx)
+		}
+	})
+}
+
+// drain stops accepting new clicks and waits until everything already
+// queued has been enriched and handed to the writer (or the spool).
+func (p *Pipeline) drain() {
+	p.drainOnce.Do(func() {
+		p.pushMu.Lock()
+		p.draining = true
+		close(p.clicksBuffer)
+		p.pushMu.Unlock()
+
+		if p.writerDone == nil {
+			return
+		}
+		p.pipelineWG.Wait()
+		close(p.enriched)
+		<-p.writerDone
+	})
+}
+
+// Close flushes queued clicks and stops the replayer. The Writer must stay
+// usable until Close returns.
+func (p *Pipeline) Close() {
+	p.closeOnce.Do(func() {
+		p.drain()
+		if p.workerCancel != nil {
+			p.workerCancel()
+		}
+		p.workerWG.Wait()
+
+		if p.spool != nil {
+			if err := p.spool.close(); err != nil {
+				slog.Warn("Failed to close click spool", "error", err)
+			}
+		}
+	})
+}
+
+func (p *Pipeline) PushClick(data types.ClickData) {
+	p.pushMu.RLock()
+	defer p.pushMu.RUnlock()
+
+	if p.workerCancel == nil || p.draining {
+		slog.Warn("Analytics worker is not running, dropping click data", "link", data.ShortCode)
+		return
+	}
+
+	select {
+	case p.clicksBuffer <- data:
+	default:
+		slog.Warn("Analytics buffer full, spooling click data", "link", data.ShortCode)
+		p.spill([]Click{Enrich(p.geo, data)})
+	}
+}
+
+func (p *Pipeline) enricher() {
+	defer p.pipelineWG.Done()
+	for data := range p.clicksBuffer {
+		p.enriched <- Enrich(p.geo, data)
+	}
+}
+
+func (p *Pipeline) writer() {
+	defer close(p.writerDone)
+
+	buffer := make([]Click, 0, p.batchSize)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(buffer) == 0 {
+			return
+		}
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := p.w.WriteClicks(flushCtx, buffer)
+		cancel()
+		if err != nil {
+			slog.Warn("RecordClicks error", "error", err, "count", len(buffer))
+			p.spill(buffer)
+		}
+		buffer = buffer[:0]
+	}
+
+	for {
+		select {
+		case row, ok := <-p.enriched:
+			if !ok {
+				flush()
+				return
+			}
+			buffer = append(buffer, row)
+			if len(buffer) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (p *Pipeline) spill(clicks []Click) {
+	if p.spool == nil {
+		p.metrics.dropped.Add(int64(len(clicks)))
+		slog.Warn("Click spool is disabled, dropping click data", "count", len(clicks))
+		return
+	}
+	if err := p.spool.write(clicks); err != nil {
+		p.metrics.dropped.Add(int64(len(clicks)))
+		slog.Error("Failed to spool click data, dropping", "error", err, "count", len(clicks))
+		return
+	}
+	p.metrics.spooled.Add(int64(len(clicks)))
+}
+
+func (p *Pipeline) replayer(ctx context.Context) {
+	defer p.workerWG.Done()
+
+	backoff := spoolMinBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		err := p.replaySpool(ctx)
+		backoff = nextBackoff(backoff, err)
+		if err != nil {
+			slog.Warn("Click spool replay failed", "error", err, "retry_in", backoff)
+		}
+		timer.Reset(backoff)
+	}
+}
+
+// nextBackoff doubles the replay delay after a failure, up to
+// spoolMaxBackoff, and resets it after a successful replay.
+func nextBackoff(backoff time.Duration, err error) time.Duration {
+	if err != nil {
+		return min(backoff*2, spoolMaxBackoff)
+	}
+	return spoolMinBackoff
+}
+
+func (p *Pipeline) replaySpool(ctx context.Context) error {
+	for ctx.Err() == nil {
+		path, err := p.spool.next()
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return nil
+		}
+
+		clicks, err := readSegment(path)
+		if err != nil {
+			return err
+		}
+		if len(clicks) > 0 {
+			flushCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			err = p.w.WriteClicks(flushCtx, clicks)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := p.spool.remove(path); err != nil {
+			return err
+		}
+		p.metrics.replayed.Add(int64(len(clicks)))
+		slog.Info("Replayed spooled clicks", "segment", path, "count", len(clicks))
+	}
+	return nil
+}
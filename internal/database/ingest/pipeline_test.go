@@ -0,0 +1,91 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"linkshortener/internal/types"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeWriter struct {
+	mu      sync.Mutex
+	fail    bool
+	batches [][]Click
+}
+
+func (w *fakeWriter) WriteClicks(_ context.Context, clicks []Click) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fail {
+		return errors.New("store is down")
+	}
+	w.batches = append(w.batches, append([]Click(nil), clicks...))
+	return nil
+}
+
+func (w *fakeWriter) written() (clicks, batches int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range w.batches {
+		clicks += len(b)
+	}
+	return clicks, len(w.batches)
+}
+
+func TestPipelineBatchesAndFlushesOnClose(t *testing.T) {
+	w := &fakeWriter{}
+	p, err := NewPipeline(Config{Name: "test_batches", BatchSize: 2, FlushInterval: time.Hour, Workers: 1}, nil, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start(context.Background())
+	for range 5 {
+		p.PushClick(types.ClickData{UserId: 1, ShortCode: "abc"})
+	}
+	p.Close()
+
+	if clicks, batches := w.written(); clicks != 5 || batches != 3 {
+		t.Errorf("written %d clicks in %d batches, want 5 in 3", clicks, batches)
+	}
+	// Clicks pushed after Close are dropped rather than blocking.
+	p.PushClick(types.ClickData{UserId: 1, ShortCode: "abc"})
+}
+
+func TestPipelineReplaysSpooledClicks(t *testing.T) {
+	w := &fakeWriter{fail: true}
+	p, err := NewPipeline(Config{Name: "test_replay", SpoolDir: t.TempDir(), SpoolMaxBytes: 1 << 20, BatchSize: 1, Workers: 1}, nil, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+	defer p.Close()
+
+	p.PushClick(types.ClickData{UserId: 1, ShortCode: "abc", IP: "203.0.113.7"})
+	deadline := time.Now().Add(5 * time.Second)
+	for p.metrics.spooled.Value() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the failed batch was not spooled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w.mu.Lock()
+	w.fail = false
+	w.mu.Unlock()
+	for {
+		if clicks, _ := w.written(); clicks == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("spooled click was not replayed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := w.batches[0][0].Visitor; got != VisitorHash("203.0.113.7", "") {
+		t.Errorf("replayed click lost its visitor hash: %d", got)
+	}
+}
@@ -0,0 +1,229 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentExt      = ".seg"
+	segmentMaxBytes = 4 << 20
+)
+
+var errSpoolFull = errors.New("spool is full")
+
+type spoolMetrics struct {
+	spooled  *expvar.Int
+	replayed *expvar.Int
+	dropped  *expvar.Int
+	bytes    *expvar.Int
+}
+
+// newSpoolMetrics publishes the spool counters as <name>_spool_*. Pipelines
+// with the same name share their counters.
+func newSpoolMetrics(name string) spoolMetrics {
+	return spoolMetrics{
+		spooled:  counter(name + "_spool_spooled"),
+		replayed: counter(name + "_spool_replayed"),
+		dropped:  counter(name + "_spool_dropped"),
+		bytes:    counter(name + "_spool_bytes"),
+	}
+}
+
+func counter(name string) *expvar.Int {
+	if v, ok := expvar.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	return expvar.NewInt(name)
+}
+
+// spool is a bounded write-ahead log of clicks that could not be written to
+// the store. Clicks are appended as JSON lines to numbered segment files;
+// a segment is sealed before it is handed out for replay.
+type spool struct {
+	dir         string
+	size        *expvar.Int
+	maxBytes    int64
+	mu          sync.Mutex
+	current     *os.File
+	currentSize int64
+	totalSize   int64
+	nextSeq     uint64
+}
+
+func openSpool(dir string, maxBytes int64, size *expvar.Int) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &spool{dir: dir, size: size, maxBytes: maxBytes}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range segments {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		s.totalSize += info.Size()
+		seq, _ := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	s.size.Set(s.totalSize)
+
+	if len(segments) > 0 {
+		slog.Info("Found spooled clicks from previous run", "segments", len(segments), "bytes", s.totalSize)
+	}
+
+	return s, nil
+}
+
+func (s *spool) segments() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), segmentExt) {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (s *spool) write(clicks []Click) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, c := range clicks {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	size := int64(buf.Len())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.totalSize+size > s.maxBytes {
+		return errSpoolFull
+	}
+
+	if s.current == nil || s.currentSize+size > segmentMaxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.current.Write(buf.Bytes())
+	s.currentSize += int64(n)
+	s.totalSize += int64(n)
+	s.size.Set(s.totalSize)
+	if err != nil {
+		return err
+	}
+	return s.current.Sync()
+}
+
+func (s *spool) rotate() error {
+	if err := s.seal(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%020d%s", s.nextSeq, segmentExt)
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.nextSeq++
+	s.current = f
+	s.currentSize = 0
+	return nil
+}
+
+func (s *spool) seal() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	s.currentSize = 0
+	return err
+}
+
+// next returns the oldest segment ready for replay, sealing the active
+// segment if it is the only one left. It returns "" when the spool is empty.
+func (s *spool) next() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil || len(segments) == 0 {
+		return "", err
+	}
+	path := filepath.Join(s.dir, segments[0])
+	if s.current != nil && s.current.Name() == path {
+		if err := s.seal(); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+func (s *spool) remove(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.totalSize -= info.Size()
+	s.size.Set(s.totalSize)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *spool) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seal()
+}
+
+func readSegment(path string) ([]Click, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var clicks []Click
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var c Click
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			slog.Warn("Skipping corrupted spool record", "segment", path, "error", err)
+			continue
+		}
+		clicks = append(clicks, c)
+	}
+	return clicks, scanner.Err()
+}
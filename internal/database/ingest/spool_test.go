@@ -0,0 +1,166 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"linkshortener/internal/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpoolResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	size := new(expvar.Int)
+
+	s, err := openSpool(dir, 1<<20, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.write([]Click{{ShortCode: "a"}, {ShortCode: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	written := size.Value()
+
+	// A new process picks up the size of the existing segment and appends to
+	// a new one instead of overwriting it.
+	size = new(expvar.Int)
+	s, err = openSpool(dir, 1<<20, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size.Value() != written {
+		t.Errorf("size after reopening = %d, want %d", size.Value(), written)
+	}
+	if err := s.write([]Click{{ShortCode: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for {
+		path, err := s.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path == "" {
+			break
+		}
+		clicks, err := readSegment(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range clicks {
+			got = append(got, c.ShortCode)
+		}
+		if err := s.remove(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(got, "") != "abc" {
+		t.Errorf("replayed %v, want the clicks of both runs in order", got)
+	}
+	if size.Value() != 0 {
+		t.Errorf("size after replay = %d, want 0", size.Value())
+	}
+}
+
+func TestSpoolRejectsWritesOverLimit(t *testing.T) {
+	s, err := openSpool(t.TempDir(), 10, new(expvar.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if err := s.write([]Click{{ShortCode: "too-long-for-ten-bytes"}}); !errors.Is(err, errSpoolFull) {
+		t.Errorf("write over the limit = %v, want errSpoolFull", err)
+	}
+}
+
+func TestReadSegmentSkipsCorruptedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "00000000000000000000"+segmentExt)
+	data := `{"short_code":"a"}` + "\n" + `{"short_co` + "\n" + `{"short_code":"b"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clicks, err := readSegment(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clicks) != 2 || clicks[0].ShortCode != "a" || clicks[1].ShortCode != "b" {
+		t.Errorf("readSegment = %+v, want clicks a and b", clicks)
+	}
+}
+
+func TestPipelineReplaysSpoolOfPreviousRun(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{Name: "test_restart", SpoolDir: dir, SpoolMaxBytes: 1 << 20, BatchSize: 1, FlushInterval: time.Hour, Workers: 1}
+
+	down := &fakeWriter{fail: true}
+	p, err := NewPipeline(cfg, nil, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start(context.Background())
+	p.PushClick(types.ClickData{UserId: 1, ShortCode: "abc"})
+	p.PushClick(types.ClickData{UserId: 1, ShortCode: "abc"})
+	p.Close()
+
+	up := &fakeWriter{}
+	p, err = NewPipeline(cfg, nil, up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+	defer p.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if clicks, _ := up.written(); clicks == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("clicks spooled by the previous run were not replayed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for {
+		segments, err := p.spool.segments()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(segments) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replayed segments were not removed: %v", segments)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	failed := errors.New("store is down")
+	tests := []struct {
+		backoff time.Duration
+		err     error
+		want    time.Duration
+	}{
+		{spoolMinBackoff, failed, 2 * spoolMinBackoff},
+		{4 * time.Second, failed, 8 * time.Second},
+		{spoolMaxBackoff / 2, failed, spoolMaxBackoff},
+		{spoolMaxBackoff, failed, spoolMaxBackoff},
+		{spoolMaxBackoff, nil, spoolMinBackoff},
+	}
+	for _, tt := range tests {
+		if got := nextBackoff(tt.backoff, tt.err); got != tt.want {
+			t.Errorf("nextBackoff(%v, %v) = %v, want %v", tt.backoff, tt.err, got, tt.want)
+		}
+	}
+}
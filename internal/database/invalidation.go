@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"expvar"
+	"hash/fnv"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	invalidateAttempts = 3
+	invalidateBackoff  = 50 * time.Millisecond
+	// The invalidation outbox is drained after every mutation and at least
+	// every invalidateRetryMax. While the cache keeps failing, draining backs
+	// off between these bounds.
+	invalidateRetryMin = time.Second
+	invalidateRetryMax = time.Minute
+	// invalidationBatch is the number of outbox entries read at once.
+	invalidationBatch = 100
+	// generationStripes is the number of invalidation counters codes are
+	// spread over, see invalidations.generation.
+	generationStripes = 256
+)
+
+var invalidationsPending = expvar.NewInt("cache_invalidations_pending")
+
+// invalidations tracks cache invalidations. wake starts a drain of the
+// outbox, see InvalidationsRepo. generations counts invalidations per stripe
+// of codes, so that a lookup can tell whether the code was invalidated while
+// it was reading the database.
+type invalidations struct {
+	wake        chan struct{}
+	generations [generationStripes]atomic.Uint64
+}
+
+func (inv *invalidations) generation(shortCode string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(shortCode))
+	return &inv.generations[h.Sum32()%generationStripes]
+}
+
+func (inv *invalidations) drainSoon() {
+	select {
+	case inv.wake <- struct{}{}:
+	default:
+	}
+}
+
+// invalidate is the single path by which link mutations reach the cache. It
+// runs after the change is committed and drops the given codes, retrying
+// briefly so that a Redis hiccup does not leave a stale redirect behind.
+// The SQL repository recorded the same codes in its invalidation outbox, and
+// the background drain deletes them again until the cache accepts it, also
+// after a restart: the change itself succeeded, so a cache failure is not
+// reported as an error. Empty codes belong to links that were never
+// published and are skipped.
+func (d *Database) invalidate(ctx context.Context, shortCodes ...string) {
+	// The change is already committed, so a cancelled caller must not leave
+	// the cache behind.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+	defer cancel()
+
+	for _, shortCode := range shortCodes {
+		if shortCode == "" {
+			continue
+		}
+		d.invalidations.generation(shortCode).Add(1)
+		if err := d.deleteCached(ctx, shortCode); err != nil {
+			slog.Warn("Failed to invalidate cached link, retrying in background", "short_code", shortCode, "error", err)
+		}
+	}
+	d.invalidations.drainSoon()
+}
+
+func (d *Database) deleteCached(ctx context.Context, shortCode string) error {
+	var err error
+	for attempt := 1; attempt <= invalidateAttempts; attempt++ {
+		if err = d.cache.Delete(ctx, shortCode); err == nil {
+			return nil
+		}
+		if attempt < invalidateAttempts {
+			time.Sleep(invalidateBackoff * time.Duration(attempt))
+		}
+	}
+	return err
+}
+
+// retryInvalidations drains the invalidation outbox until ctx is done: once
+// at start, to finish the invalidations of a previous process, after every
+// mutation and every invalidateRetryMax for those of other instances. It
+// backs off while the cache or the database keep failing.
+func (d *Database) retryInvalidations(ctx context.Context) {
+	for {
+		backoff := invalidateRetryMin
+		for d.drainInvalidations(ctx) > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, invalidateRetryMax)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.invalidations.wake:
+		case <-time.After(invalidateRetryMax):
+		}
+	}
+}
+
+// drainInvalidations deletes the codes in the outbox batch by batch and
+// acknowledges the entries of the codes deleted. It stops at the first batch
+// with a failure and returns the number of entries of that batch still
+// pending, or 1 if the outbox could not be read.
+func (d *Database) drainInvalidations(ctx context.Context) (failed int) {
+	defer func() { invalidationsPending.Set(int64(failed)) }()
+
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+		pending, err := d.sql.PendingInvalidations(readCtx, invalidationBatch)
+		cancel()
+		if err != nil {
+			slog.Warn("Failed to read cache invalidations", "error", err)
+			return 1
+		}
+
+		deleted := make(map[string]error)
+		var ack []int64
+		for _, inv := range pending {
+			err, done := deleted[inv.ShortCode]
+			if !done {
+				deleteCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+				err = d.cache.Delete(deleteCtx, inv.ShortCode)
+				cancel()
+				deleted[inv.ShortCode] = err
+				if err != nil {
+					slog.Warn("Retry of cache invalidation failed", "short_code", inv.ShortCode, "error", err)
+				}
+			}
+			if err != nil {
+				failed++
+				continue
+			}
+			ack = append(ack, inv.Id)
+		}
+
+		if len(ack) > 0 {
+			ackCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+			err := d.sql.AckInvalidations(ackCtx, ack)
+			cancel()
+			if err != nil {
+				// The codes were deleted and are deleted again next time.
+				slog.Warn("Failed to acknowledge cache invalidations", "error", err)
+				return failed + len(ack)
+			}
+		}
+		if failed > 0 || len(pending) < invalidationBatch {
+			return failed
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"time"
+)
+
+func (s *SQL) GetLinkAlert(_ context.Context, userId int64, shortCode string) (*types.LinkAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert := types.DefaultLinkAlert(userId, shortCode)
+	if l := s.linkByCode(userId, shortCode); l != nil {
+		if state, ok := s.alerts[l.Id]; ok && state.settings != nil {
+			alert = *state.settings
+		}
+	}
+	return &alert, nil
+}
+
+func (s *SQL) ListLinkAlerts(context.Context) ([]types.LinkAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var alerts []types.LinkAlert
+	for id, state := range s.alerts {
+		l := s.links[id]
+		alert := types.DefaultLinkAlert(l.UserId, l.ShortCode)
+		if state.settings != nil {
+			alert = *state.settings
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func (s *SQL) SaveLinkAlert(_ context.Context, alert types.LinkAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.linkByCode(alert.UserId, alert.ShortCode)
+	if l == nil {
+		return nil
+	}
+	state, ok := s.alerts[l.Id]
+	if !ok {
+		state = &alertState{}
+		s.alerts[l.Id] = state
+	}
+	state.settings = &alert
+	return nil
+}
+
+func (s *SQL) ClaimAlert(_ context.Context, userId int64, shortCode string, cutoff time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.linkByCode(userId, shortCode)
+	if l == nil {
+		return false, nil
+	}
+	state, ok := s.alerts[l.Id]
+	if !ok {
+		state = &alertState{}
+		s.alerts[l.Id] = state
+	}
+	if !state.lastAlertAt.IsZero() && !state.lastAlertAt.Before(cutoff) {
+		return false, nil
+	}
+	state.lastAlertAt = time.Now()
+	return true, nil
+}
@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/geoip"
+	"linkshortener/internal/types"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+type click struct {
+	types.Analytic
+	visitor uint64
+}
+
+// Analytics keeps every click in a slice and aggregates on read. Clicks are
+// enriched and stored as soon as they are pushed, so tests can query them
+// right away.
+type Analytics struct {
+	geo    *geoip.Resolver
+	mu     sync.RWMutex
+	clicks []click
+}
+
+// NewAnalytics returns an empty store. geo may be nil, in which case every
+// location is unknown.
+func NewAnalytics(geo *geoip.Resolver) *Analytics {
+	return &Analytics{geo: geo}
+}
+
+func (a *Analytics) Start(context.Context) {}
+
+func (a *Analytics) Close() error {
+	return nil
+}
+
+func (a *Analytics) PushClick(data types.ClickData) {
+	c := ingest.Enrich(a.geo, data)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clicks = append(a.clicks, click{Analytic: c.Analytic(), visitor: c.Visitor})
+}
+
+// each calls fn for every click matching the filter in the order they were
+// pushed.
+func (a *Analytics) each(filter types.AnalyticsFilter, fn func(c click)) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, c := range a.clicks {
+		if c.UserId != filter.UserId || (filter.ShortCode != "" && c.ShortCode != filter.ShortCode) {
+			continue
+		}
+		if c.ClickedAt.Before(filter.From) || !c.ClickedAt.Before(filter.To) {
+			continue
+		}
+		fn(c)
+	}
+}
+
+func allTime(userId int64, shortCode string) types.AnalyticsFilter {
+	return types.AnalyticsFilter{UserId: userId, ShortCode: shortCode, From: time.Unix(0, 0), To: time.Now().Add(time.Hour)}
+}
+
+func (a *Analytics) GetAllAnalytic(_ context.Context, userId int64) ([]types.Analytic, error) {
+	var clicks []types.Analytic
+	a.each(allTime(userId, ""), func(c click) { clicks = append(clicks, c.Analytic) })
+	return clicks, nil
+}
+
+func (a *Analytics) GetAnalyticByCode(_ context.Context, code string, userId int64) ([]types.Analytic, error) {
+	var clicks []types.Analytic
+	a.each(allTime(userId, code), func(c click) { clicks = append(clicks, c.Analytic) })
+	return clicks, nil
+}
+
+func location(timezone string) *time.Location {
+	if loc, err := time.LoadLocation(timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// GetDailyClicks returns days as UTC midnights, like ClickHouse's Date.
+func (a *Analytics) GetDailyClicks(_ context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	loc := location(filter.Timezone)
+	perDay := make(map[time.Time]int64)
+	a.each(filter, func(c click) {
+		local := c.ClickedAt.In(loc)
+		perDay[time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)]++
+	})
+
+	var daily []types.DailyClicks
+	for _, day := range slices.SortedFunc(maps.Keys(perDay), time.Time.Compare) {
+		daily = append(daily, types.DailyClicks{Day: day, Clicks: perDay[day]})
+	}
+	return daily, nil
+}
+
+func (a *Analytics) GetHourlyClicks(_ context.Context, filter types.AnalyticsFilter) ([]types.HourlyClicks, error) {
+	loc := location(filter.Timezone)
+	var cells [7][24]int64
+	a.each(filter, func(c click) {
+		local := c.ClickedAt.In(loc)
+		cells[(int(local.Weekday())+6)%7][local.Hour()]++
+	})
+
+	var hourly []types.HourlyClicks
+	for day := range cells {
+		for hour, clicks := range cells[day] {
+			if clicks > 0 {
+				hourly = append(hourly, types.HourlyClicks{Weekday: day + 1, Hour: hour, Clicks: clicks})
+			}
+		}
+	}
+	return hourly, nil
+}
+
+func (a *Analytics) GetTotalClicks(_ context.Context, filter types.AnalyticsFilter) (int64, error) {
+	var total int64
+	a.each(filter, func(click) { total++ })
+	return total, nil
+}
+
+func (a *Analytics) GetTopCountries(_ context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	counts := make(map[string]int64)
+	a.each(filter, func(c click) { counts[c.Country]++ })
+	return topStats(counts, limit), nil
+}
+
+func (a *Analytics) GetTopCities(_ context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	counts := make(map[string]int64)
+	a.each(filter, func(c click) { counts[c.City]++ })
+	return topStats(counts, limit), nil
+}
+
+func (a *Analytics) GetTopChannels(_ context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	counts := make(map[string]int64)
+	a.each(filter, func(c click) { counts[c.Channel]++ })
+	return topStats(counts, limit), nil
+}
+
+func (a *Analytics) GetTopSources(_ context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	counts := make(map[string]int64)
+	a.each(filter, func(c click) {
+		source := c.RefererHost
+		if source == "" {
+			source = c.UTMSource
+		}
+		if source != "" {
+			counts[source]++
+		}
+	})
+	return topStats(counts, limit), nil
+}
+
+func (a *Analytics) GetTopLinks(_ context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	counts := make(map[string]int64)
+	a.each(filter, func(c click) { counts[c.ShortCode]++ })
+	return topStats(counts, limit), nil
+}
+
+// topStats orders by clicks, most first, and breaks ties by key so results
+// are stable.
+func topStats(counts map[string]int64, limit int) []types.CountStat {
+	stats := make([]types.CountStat, 0, len(counts))
+	for key, clicks := range counts {
+		stats = append(stats, types.CountStat{Key: key, Clicks: clicks})
+	}
+	slices.SortFunc(stats, func(a, b types.CountStat) int {
+		return cmp.Or(cmp.Compare(b.Clicks, a.Clicks), cmp.Compare(a.Key, b.Key))
+	})
+	if limit >= 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
+
+func (a *Analytics) GetHotCodes(_ context.Context, since time.Time, limit int) ([]string, error) {
+	counts := make(map[string]int64)
+	a.mu.RLock()
+	for _, c := range a.clicks {
+		if !c.ClickedAt.Before(since) {
+			counts[c.ShortCode]++
+		}
+	}
+	a.mu.RUnlock()
+
+	var codes []string
+	for _, s := range topStats(counts, limit) {
+		codes = append(codes, s.Key)
+	}
+	return codes, nil
+}
+
+func (a *Analytics) GetUniqueVisitors(_ context.Context, filter types.AnalyticsFilter) (int64, error) {
+	visitors := make(map[uint64]struct{})
+	a.each(filter, func(c click) { visitors[c.visitor] = struct{}{} })
+	return int64(len(visitors)), nil
+}
+
+func (a *Analytics) ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error {
+	var clicks []types.Analytic
+	a.each(filter, func(c click) { clicks = append(clicks, c.Analytic) })
+	slices.SortStableFunc(clicks, func(a, b types.Analytic) int { return a.ClickedAt.Compare(b.ClickedAt) })
+
+	for i, c := range clicks {
+		if i >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type linkKey struct {
+	userId    int64
+	shortCode string
+}
+
+func (a *Analytics) GetLinkRates(_ context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error) {
+	rates := make(map[linkKey]*types.LinkRate)
+	a.mu.RLock()
+	for _, c := range a.clicks {
+		if c.ClickedAt.Before(baselineFrom) || !c.ClickedAt.Before(to) {
+			continue
+		}
+		key := linkKey{c.UserId, c.ShortCode}
+		rate, ok := rates[key]
+		if !ok {
+			rate = &types.LinkRate{UserId: c.UserId, ShortCode: c.ShortCode}
+			rates[key] = rate
+		}
+		if c.ClickedAt.Before(windowFrom) {
+			rate.Baseline++
+		} else {
+			rate.Recent++
+		}
+	}
+	a.mu.RUnlock()
+
+	result := make([]types.LinkRate, 0, len(rates))
+	for _, rate := range rates {
+		result = append(result, *rate)
+	}
+	return result, nil
+}
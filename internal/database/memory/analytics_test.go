@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsBucketsInTimezone(t *testing.T) {
+	a := NewAnalytics(nil)
+	// 22:30 UTC on Sunday is 01:30 on Monday in Kyiv (UTC+3 in summer).
+	a.PushClick(types.ClickData{UserId: 1, ShortCode: "a", ClickedAt: time.Date(2026, 6, 7, 22, 30, 0, 0, time.UTC)})
+	a.PushClick(types.ClickData{UserId: 1, ShortCode: "b", ClickedAt: time.Date(2026, 6, 8, 10, 0, 0, 0, time.UTC)})
+	a.PushClick(types.ClickData{UserId: 2, ShortCode: "a", ClickedAt: time.Date(2026, 6, 8, 10, 0, 0, 0, time.UTC)})
+
+	ctx := context.Background()
+	filter := types.AnalyticsFilter{
+		UserId:   1,
+		From:     time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+		Timezone: "Europe/Kyiv",
+	}
+
+	daily, err := a.GetDailyClicks(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDaily := []types.DailyClicks{{Day: time.Date(2026, 6, 8, 0, 0, 0, 0, time.UTC), Clicks: 2}}
+	if !reflect.DeepEqual(daily, wantDaily) {
+		t.Errorf("daily = %+v, want %+v", daily, wantDaily)
+	}
+
+	hourly, err := a.GetHourlyClicks(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHourly := []types.HourlyClicks{{Weekday: 1, Hour: 1, Clicks: 1}, {Weekday: 1, Hour: 13, Clicks: 1}}
+	if !reflect.DeepEqual(hourly, wantHourly) {
+		t.Errorf("hourly = %+v, want %+v", hourly, wantHourly)
+	}
+
+	filter.To = time.Date(2026, 6, 8, 10, 0, 0, 0, time.UTC)
+	links, err := a.GetTopLinks(ctx, filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.CountStat{{Key: "a", Clicks: 1}}; !reflect.DeepEqual(links, want) {
+		t.Errorf("top links = %+v, want %+v", links, want)
+	}
+}
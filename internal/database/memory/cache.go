@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/types"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	link      types.LinkCache
+	expiresAt time.Time
+}
+
+type counters struct {
+	initialised bool
+	total       int64
+	perBucket   map[time.Time]int64
+	visitors    map[uint64]struct{}
+}
+
+// counterBucket matches the resolution of the Redis per-day counters.
+const counterBucket = 15 * time.Minute
+
+func newCounters() *counters {
+	return &counters{perBucket: make(map[time.Time]int64), visitors: make(map[uint64]struct{})}
+}
+
+// Cache is a map with expiry plus the click counters that Redis keeps.
+// Visitors are counted exactly instead of with HyperLogLog.
+type Cache struct {
+	mu       sync.Mutex
+	links    map[string]cacheEntry
+	counters map[linkKey]*counters
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		links:    make(map[string]cacheEntry),
+		counters: make(map[linkKey]*counters),
+	}
+}
+
+func (c *Cache) Close() error {
+	return nil
+}
+
+func (c *Cache) Set(_ context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := cacheEntry{link: *cache}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+	c.links[shortCode] = entry
+	return nil
+}
+
+func (c *Cache) Get(_ context.Context, shortCode string) (*types.LinkCache, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.links[shortCode]
+	if !ok {
+		return nil, customerrs.ErrNoFound
+	}
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		delete(c.links, shortCode)
+		return nil, customerrs.ErrNoFound
+	}
+	link := entry.link
+	return &link, nil
+}
+
+func (c *Cache) Update(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error {
+	return c.Set(ctx, shortCode, cache, expiration)
+}
+
+func (c *Cache) Delete(_ context.Context, shortCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.links, shortCode)
+	return nil
+}
+
+func (c *Cache) InitClickCounters(_ context.Context, userId int64, shortCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt := newCounters()
+	cnt.initialised = true
+	c.counters[linkKey{userId, shortCode}] = cnt
+	return nil
+}
+
+func (c *Cache) DeleteClickCounters(_ context.Context, userId int64, shortCodes ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, code := range shortCodes {
+		delete(c.counters, linkKey{userId, code})
+	}
+	return nil
+}
+
+func (c *Cache) CountClick(_ context.Context, data types.ClickData) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := linkKey{data.UserId, data.ShortCode}
+	cnt, ok := c.counters[key]
+	if !ok {
+		cnt = newCounters()
+		c.counters[key] = cnt
+	}
+	cnt.total++
+	cnt.perBucket[data.ClickedAt.UTC().Truncate(counterBucket)]++
+	cnt.visitors[ingest.VisitorHash(data.IP, data.UserAgent)] = struct{}{}
+	return nil
+}
+
+func (c *Cache) GetClickCounters(_ context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	result := make(map[string]types.ClickCounters, len(shortCodes))
+	for _, code := range shortCodes {
+		cnt, ok := c.counters[linkKey{userId, code}]
+		if !ok || !cnt.initialised {
+			continue
+		}
+		var today int64
+		for at, n := range cnt.perBucket {
+			if !at.Before(start) && at.Before(end) {
+				today += n
+			}
+		}
+		result[code] = types.ClickCounters{
+			Total:    cnt.total,
+			Today:    today,
+			Visitors: int64(len(cnt.visitors)),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,15 @@
+package memory
+
+import (
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/storagetest"
+	"testing"
+)
+
+func TestCacheConformance(t *testing.T) {
+	storagetest.Cache(t, func(*testing.T) database.Cache { return NewCache() })
+}
+
+func TestAnalyticsConformance(t *testing.T) {
+	storagetest.Analytics(t, func(*testing.T) database.Analytics { return NewAnalytics(nil) })
+}
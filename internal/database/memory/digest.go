@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"linkshortener/internal/types"
+	"slices"
+	"time"
+)
+
+func (s *SQL) GetDigestSubscriptions(_ context.Context, userId int64) ([]types.DigestSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var subs []types.DigestSubscription
+	for key, sub := range s.digests {
+		if key.userId == userId {
+			subs = append(subs, s.withTimezone(sub))
+		}
+	}
+	slices.SortFunc(subs, func(a, b types.DigestSubscription) int { return cmp.Compare(a.Period, b.Period) })
+	return subs, nil
+}
+
+func (s *SQL) SaveDigestSubscription(_ context.Context, sub types.DigestSubscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[sub.UserId]; ok {
+		s.digests[digestKey{sub.UserId, sub.Period}] = sub
+	}
+	return nil
+}
+
+func (s *SQL) DeleteDigestSubscription(_ context.Context, userId int64, period string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.digests, digestKey{userId, period})
+	return nil
+}
+
+func (s *SQL) SetTimezone(_ context.Context, userId int64, timezone string, next func(types.DigestSubscription) time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userId]
+	if !ok {
+		return nil
+	}
+	u.timezone = timezone
+	for key, sub := range s.digests {
+		if key.userId == userId {
+			sub = s.withTimezone(sub)
+			sub.NextRunAt = next(sub)
+			s.digests[key] = sub
+		}
+	}
+	return nil
+}
+
+func (s *SQL) ClaimDueDigests(_ context.Context, now time.Time, next func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []types.DigestSubscription
+	for key, sub := range s.digests {
+		if sub.NextRunAt.After(now) {
+			continue
+		}
+		sub = s.withTimezone(sub)
+		due = append(due, sub)
+		sub.NextRunAt = next(sub)
+		s.digests[key] = sub
+	}
+	slices.SortFunc(due, func(a, b types.DigestSubscription) int { return a.NextRunAt.Compare(b.NextRunAt) })
+	return due, nil
+}
+
+// withTimezone fills in the timezone of the subscriber, which the
+// PostgreSQL repository joins from the users table.
+func (s *SQL) withTimezone(sub types.DigestSubscription) types.DigestSubscription {
+	if u, ok := s.users[sub.UserId]; ok {
+		sub.Timezone = u.timezone
+	}
+	return sub
+}
@@ -0,0 +1,333 @@
+// Package memory implements the storage interfaces of package database in
+// process memory, for local development without PostgreSQL, Redis or
+// ClickHouse and for fast end-to-end tests. Nothing survives a restart.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"slices"
+	"sync"
+	"time"
+)
+
+// errNoRows is what the SQL repositories return for missing rows.
+var errNoRows = customerrs.NotFound(sql.ErrNoRows)
+
+type user struct {
+	id           int64
+	telegramID   int64
+	timezone     string
+	cityOptOut   bool
+	apiTokenHash string
+}
+
+type link struct {
+	types.LinkData
+	cacheTTL time.Duration
+}
+
+type alertState struct {
+	settings    *types.LinkAlert
+	lastAlertAt time.Time
+}
+
+type digestKey struct {
+	userId int64
+	period string
+}
+
+// SQL mirrors the PostgreSQL repository, including its errors: lookups of
+// missing rows return errNoRows.
+type SQL struct {
+	mu         sync.Mutex
+	lastUserId int64
+	lastLinkId int64
+	users      map[int64]*user
+	links      map[int64]*link
+	alerts     map[int64]*alertState
+	digests    map[digestKey]types.DigestSubscription
+
+	lastInvalidationId int64
+	invalidations      []types.Invalidation
+}
+
+func NewSQL() *SQL {
+	return &SQL{
+		users:   make(map[int64]*user),
+		links:   make(map[int64]*link),
+		alerts:  make(map[int64]*alertState),
+		digests: make(map[digestKey]types.DigestSubscription),
+	}
+}
+
+func (s *SQL) Close() error {
+	return nil
+}
+
+func (s *SQL) userByTelegramID(telegramID int64) *user {
+	for _, u := range s.users {
+		if u.telegramID == telegramID {
+			return u
+		}
+	}
+	return nil
+}
+
+// invalidate records cache invalidations like the triggers of the SQL
+// repositories do.
+func (s *SQL) invalidate(shortCodes ...string) {
+	for _, code := range shortCodes {
+		if code == "" {
+			continue
+		}
+		s.lastInvalidationId++
+		s.invalidations = append(s.invalidations, types.Invalidation{Id: s.lastInvalidationId, ShortCode: code})
+	}
+}
+
+// linkByCode returns the link with the given code; userId 0 matches any
+// owner.
+func (s *SQL) linkByCode(userId int64, shortCode string) *link {
+	for _, l := range s.links {
+		if l.ShortCode == shortCode && (userId == 0 || l.UserId == userId) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (s *SQL) CreateUser(_ context.Context, telegramID int64, timezone string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.userByTelegramID(telegramID) != nil {
+		return nil
+	}
+	s.lastUserId++
+	s.users[s.lastUserId] = &user{id: s.lastUserId, telegramID: telegramID, timezone: timezone}
+	return nil
+}
+
+func (s *SQL) GetUserIDByTelegramID(_ context.Context, telegramID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u := s.userByTelegramID(telegramID); u != nil {
+		return u.id, nil
+	}
+	return 0, errNoRows
+}
+
+func (s *SQL) GetUserIDByAPITokenHash(_ context.Context, tokenHash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if tokenHash != "" && u.apiTokenHash == tokenHash {
+			return u.id, nil
+		}
+	}
+	return 0, errNoRows
+}
+
+func (s *SQL) GetTelegramIDByUserID(_ context.Context, userId int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[userId]; ok {
+		return u.telegramID, nil
+	}
+	return 0, errNoRows
+}
+
+func (s *SQL) SetAPITokenHash(_ context.Context, userId int64, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[userId]; ok {
+		u.apiTokenHash = tokenHash
+	}
+	return nil
+}
+
+func (s *SQL) GetUserSettings(_ context.Context, userId int64) (*types.UserSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userId]
+	if !ok {
+		return nil, errNoRows
+	}
+	return &types.UserSettings{CityOptOut: u.cityOptOut, Timezone: u.timezone}, nil
+}
+
+func (s *SQL) SetCityOptOut(_ context.Context, userId int64, optOut bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userId]
+	if !ok || u.cityOptOut == optOut {
+		return nil
+	}
+	u.cityOptOut = optOut
+	for _, l := range s.links {
+		if l.UserId == userId {
+			s.invalidate(l.ShortCode)
+		}
+	}
+	return nil
+}
+
+func (s *SQL) CreateLink(_ context.Context, userID int64, originalLink string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return 0, customerrs.ErrNoFound
+	}
+	s.lastLinkId++
+	now := time.Now().UTC()
+	s.links[s.lastLinkId] = &link{LinkData: types.LinkData{
+		Id:           s.lastLinkId,
+		UserId:       userID,
+		OriginalLink: originalLink,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}}
+	return s.lastLinkId, nil
+}
+
+func (s *SQL) SetShortCode(_ context.Context, id int64, shortCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if other := s.linkByCode(0, shortCode); other != nil && other.Id != id {
+		return customerrs.ErrCodeIsBusy
+	}
+	if l, ok := s.links[id]; ok && l.ShortCode != shortCode {
+		s.invalidate(l.ShortCode, shortCode)
+		l.ShortCode = shortCode
+	}
+	return nil
+}
+
+func (s *SQL) GetLink(_ context.Context, shortCode string) (*types.LinkCache, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.linkByCode(0, shortCode)
+	if l == nil || shortCode == "" {
+		return nil, errNoRows
+	}
+	return &types.LinkCache{
+		OriginalLink:    l.OriginalLink,
+		UserID:          l.UserId,
+		CityOptOut:      s.users[l.UserId].cityOptOut,
+		CacheTTLSeconds: int64(l.cacheTTL / time.Second),
+	}, nil
+}
+
+// GetAllLinksByUser returns the links in creation order.
+func (s *SQL) GetAllLinksByUser(_ context.Context, userId int64) ([]types.LinkData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []types.LinkData
+	for _, l := range s.links {
+		if l.UserId == userId {
+			links = append(links, l.LinkData)
+		}
+	}
+	slices.SortFunc(links, func(a, b types.LinkData) int { return cmp.Compare(a.Id, b.Id) })
+	return links, nil
+}
+
+func (s *SQL) UpdateLink(_ context.Context, userId int64, shortCode, newLink string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l := s.linkByCode(userId, shortCode); l != nil {
+		l.OriginalLink = newLink
+		l.UpdatedAt = time.Now().UTC()
+		s.invalidate(l.ShortCode)
+	}
+	return nil
+}
+
+func (s *SQL) SetLinkCacheTTL(_ context.Context, userId int64, shortCode string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.linkByCode(userId, shortCode)
+	if l == nil {
+		return errNoRows
+	}
+	l.cacheTTL = ttl.Truncate(time.Second)
+	s.invalidate(l.ShortCode)
+	return nil
+}
+
+func (s *SQL) deleteLink(id int64) {
+	if l, ok := s.links[id]; ok {
+		s.invalidate(l.ShortCode)
+	}
+	delete(s.links, id)
+	delete(s.alerts, id)
+}
+
+func (s *SQL) DeleteLinkByCode(_ context.Context, userId int64, shortCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l := s.linkByCode(userId, shortCode); l != nil {
+		s.deleteLink(l.Id)
+	}
+	return nil
+}
+
+func (s *SQL) DeleteLinkById(_ context.Context, userId, linkId int64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[linkId]
+	if !ok || l.UserId != userId {
+		return "", nil
+	}
+	s.deleteLink(linkId)
+	return l.ShortCode, nil
+}
+
+func (s *SQL) DeleteAllLinksByUser(_ context.Context, userId int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var codes []string
+	for id, l := range s.links {
+		if l.UserId == userId {
+			codes = append(codes, l.ShortCode)
+			s.deleteLink(id)
+		}
+	}
+	return codes, nil
+}
+
+func (s *SQL) PendingInvalidations(_ context.Context, limit int) ([]types.Invalidation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.invalidations[:min(limit, len(s.invalidations))]), nil
+}
+
+func (s *SQL) AckInvalidations(_ context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invalidations = slices.DeleteFunc(s.invalidations, func(inv types.Invalidation) bool {
+		return slices.Contains(ids, inv.Id)
+	})
+	return nil
+}
@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/storagetest"
+	"testing"
+)
+
+func TestSQLConformance(t *testing.T) {
+	storagetest.SQL(t, func(*testing.T) database.SQL { return NewSQL() })
+}
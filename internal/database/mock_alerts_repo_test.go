@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: linkshortener/internal/database (interfaces: AlertsRepo)
+
+// Package database is a generated GoMock package.
+package database
+
+import (
+	context "context"
+	types "linkshortener/internal/types"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAlertsRepo is a mock of AlertsRepo interface.
+type MockAlertsRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertsRepoMockRecorder
+}
+
+// MockAlertsRepoMockRecorder is the mock recorder for MockAlertsRepo.
+type MockAlertsRepoMockRecorder struct {
+	mock *MockAlertsRepo
+}
+
+// NewMockAlertsRepo creates a new mock instance.
+func NewMockAlertsRepo(ctrl *gomock.Controller) *MockAlertsRepo {
+	mock := &MockAlertsRepo{ctrl: ctrl}
+	mock.recorder = &MockAlertsRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertsRepo) EXPECT() *MockAlertsRepoMockRecorder {
+	return m.recorder
+}
+
+// ClaimAlert mocks base method.
+func (m *MockAlertsRepo) ClaimAlert(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimAlert", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimAlert indicates an expected call of ClaimAlert.
+func (mr *MockAlertsRepoMockRecorder) ClaimAlert(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimAlert", reflect.TypeOf((*MockAlertsRepo)(nil).ClaimAlert), arg0, arg1, arg2, arg3)
+}
+
+// GetLinkAlert mocks base method.
+func (m *MockAlertsRepo) GetLinkAlert(arg0 context.Context, arg1 int64, arg2 string) (*types.LinkAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkAlert", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.LinkAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkAlert indicates an expected call of GetLinkAlert.
+func (mr *MockAlertsRepoMockRecorder) GetLinkAlert(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkAlert", reflect.TypeOf((*MockAlertsRepo)(nil).GetLinkAlert), arg0, arg1, arg2)
+}
+
+// ListLinkAlerts mocks base method.
+func (m *MockAlertsRepo) ListLinkAlerts(arg0 context.Context) ([]types.LinkAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLinkAlerts", arg0)
+	ret0, _ := ret[0].([]types.LinkAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLinkAlerts indicates an expected call of ListLinkAlerts.
+func (mr *MockAlertsRepoMockRecorder) ListLinkAlerts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLinkAlerts", reflect.TypeOf((*MockAlertsRepo)(nil).ListLinkAlerts), arg0)
+}
+
+// SaveLinkAlert mocks base method.
+func (m *MockAlertsRepo) SaveLinkAlert(arg0 context.Context, arg1 types.LinkAlert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLinkAlert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLinkAlert indicates an expected call of SaveLinkAlert.
+func (mr *MockAlertsRepoMockRecorder) SaveLinkAlert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLinkAlert", reflect.TypeOf((*MockAlertsRepo)(nil).SaveLinkAlert), arg0, arg1)
+}
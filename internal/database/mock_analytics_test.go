@@ -8,6 +8,7 @@ import (
 	context "context"
 	types "linkshortener/internal/types"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -49,6 +50,20 @@ func (mr *MockAnalyticsMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockAnalytics)(nil).Close))
 }
 
+// ExportClicks mocks base method.
+func (m *MockAnalytics) ExportClicks(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int, arg3 func(types.Analytic) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportClicks", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportClicks indicates an expected call of ExportClicks.
+func (mr *MockAnalyticsMockRecorder) ExportClicks(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportClicks", reflect.TypeOf((*MockAnalytics)(nil).ExportClicks), arg0, arg1, arg2, arg3)
+}
+
 // GetAllAnalytic mocks base method.
 func (m *MockAnalytics) GetAllAnalytic(arg0 context.Context, arg1 int64) ([]types.Analytic, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +94,171 @@ func (mr *MockAnalyticsMockRecorder) GetAnalyticByCode(arg0, arg1, arg2 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnalyticByCode", reflect.TypeOf((*MockAnalytics)(nil).GetAnalyticByCode), arg0, arg1, arg2)
 }
 
+// GetDailyClicks mocks base method.
+func (m *MockAnalytics) GetDailyClicks(arg0 context.Context, arg1 types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyClicks", arg0, arg1)
+	ret0, _ := ret[0].([]types.DailyClicks)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyClicks indicates an expected call of GetDailyClicks.
+func (mr *MockAnalyticsMockRecorder) GetDailyClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyClicks", reflect.TypeOf((*MockAnalytics)(nil).GetDailyClicks), arg0, arg1)
+}
+
+// GetHotCodes mocks base method.
+func (m *MockAnalytics) GetHotCodes(arg0 context.Context, arg1 time.Time, arg2 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHotCodes", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHotCodes indicates an expected call of GetHotCodes.
+func (mr *MockAnalyticsMockRecorder) GetHotCodes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHotCodes", reflect.TypeOf((*MockAnalytics)(nil).GetHotCodes), arg0, arg1, arg2)
+}
+
+// GetHourlyClicks mocks base method.
+func (m *MockAnalytics) GetHourlyClicks(arg0 context.Context, arg1 types.AnalyticsFilter) ([]types.HourlyClicks, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHourlyClicks", arg0, arg1)
+	ret0, _ := ret[0].([]types.HourlyClicks)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHourlyClicks indicates an expected call of GetHourlyClicks.
+func (mr *MockAnalyticsMockRecorder) GetHourlyClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHourlyClicks", reflect.TypeOf((*MockAnalytics)(nil).GetHourlyClicks), arg0, arg1)
+}
+
+// GetLinkRates mocks base method.
+func (m *MockAnalytics) GetLinkRates(arg0 context.Context, arg1, arg2, arg3 time.Time) ([]types.LinkRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkRates", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]types.LinkRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkRates indicates an expected call of GetLinkRates.
+func (mr *MockAnalyticsMockRecorder) GetLinkRates(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkRates", reflect.TypeOf((*MockAnalytics)(nil).GetLinkRates), arg0, arg1, arg2, arg3)
+}
+
+// GetTopChannels mocks base method.
+func (m *MockAnalytics) GetTopChannels(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopChannels", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopChannels indicates an expected call of GetTopChannels.
+func (mr *MockAnalyticsMockRecorder) GetTopChannels(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopChannels", reflect.TypeOf((*MockAnalytics)(nil).GetTopChannels), arg0, arg1, arg2)
+}
+
+// GetTopCities mocks base method.
+func (m *MockAnalytics) GetTopCities(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopCities", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopCities indicates an expected call of GetTopCities.
+func (mr *MockAnalyticsMockRecorder) GetTopCities(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopCities", reflect.TypeOf((*MockAnalytics)(nil).GetTopCities), arg0, arg1, arg2)
+}
+
+// GetTopCountries mocks base method.
+func (m *MockAnalytics) GetTopCountries(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopCountries", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopCountries indicates an expected call of GetTopCountries.
+func (mr *MockAnalyticsMockRecorder) GetTopCountries(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopCountries", reflect.TypeOf((*MockAnalytics)(nil).GetTopCountries), arg0, arg1, arg2)
+}
+
+// GetTopLinks mocks base method.
+func (m *MockAnalytics) GetTopLinks(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopLinks", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopLinks indicates an expected call of GetTopLinks.
+func (mr *MockAnalyticsMockRecorder) GetTopLinks(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopLinks", reflect.TypeOf((*MockAnalytics)(nil).GetTopLinks), arg0, arg1, arg2)
+}
+
+// GetTopSources mocks base method.
+func (m *MockAnalytics) GetTopSources(arg0 context.Context, arg1 types.AnalyticsFilter, arg2 int) ([]types.CountStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopSources", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.CountStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopSources indicates an expected call of GetTopSources.
+func (mr *MockAnalyticsMockRecorder) GetTopSources(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopSources", reflect.TypeOf((*MockAnalytics)(nil).GetTopSources), arg0, arg1, arg2)
+}
+
+// GetTotalClicks mocks base method.
+func (m *MockAnalytics) GetTotalClicks(arg0 context.Context, arg1 types.AnalyticsFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalClicks", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalClicks indicates an expected call of GetTotalClicks.
+func (mr *MockAnalyticsMockRecorder) GetTotalClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalClicks", reflect.TypeOf((*MockAnalytics)(nil).GetTotalClicks), arg0, arg1)
+}
+
+// GetUniqueVisitors mocks base method.
+func (m *MockAnalytics) GetUniqueVisitors(arg0 context.Context, arg1 types.AnalyticsFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUniqueVisitors", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUniqueVisitors indicates an expected call of GetUniqueVisitors.
+func (mr *MockAnalyticsMockRecorder) GetUniqueVisitors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUniqueVisitors", reflect.TypeOf((*MockAnalytics)(nil).GetUniqueVisitors), arg0, arg1)
+}
+
 // PushClick mocks base method.
 func (m *MockAnalytics) PushClick(arg0 types.ClickData) {
 	m.ctrl.T.Helper()
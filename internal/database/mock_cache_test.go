@@ -50,6 +50,20 @@ func (mr *MockCacheMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCache)(nil).Close))
 }
 
+// CountClick mocks base method.
+func (m *MockCache) CountClick(arg0 context.Context, arg1 types.ClickData) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClick", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CountClick indicates an expected call of CountClick.
+func (mr *MockCacheMockRecorder) CountClick(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClick", reflect.TypeOf((*MockCache)(nil).CountClick), arg0, arg1)
+}
+
 // Delete mocks base method.
 func (m *MockCache) Delete(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -64,6 +78,25 @@ func (mr *MockCacheMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCache)(nil).Delete), arg0, arg1)
 }
 
+// DeleteClickCounters mocks base method.
+func (m *MockCache) DeleteClickCounters(arg0 context.Context, arg1 int64, arg2 ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteClickCounters", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClickCounters indicates an expected call of DeleteClickCounters.
+func (mr *MockCacheMockRecorder) DeleteClickCounters(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClickCounters", reflect.TypeOf((*MockCache)(nil).DeleteClickCounters), varargs...)
+}
+
 // Get mocks base method.
 func (m *MockCache) Get(arg0 context.Context, arg1 string) (*types.LinkCache, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +112,35 @@ func (mr *MockCacheMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), arg0, arg1)
 }
 
+// GetClickCounters mocks base method.
+func (m *MockCache) GetClickCounters(arg0 context.Context, arg1 int64, arg2 []string, arg3 time.Time) (map[string]types.ClickCounters, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClickCounters", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(map[string]types.ClickCounters)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClickCounters indicates an expected call of GetClickCounters.
+func (mr *MockCacheMockRecorder) GetClickCounters(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClickCounters", reflect.TypeOf((*MockCache)(nil).GetClickCounters), arg0, arg1, arg2, arg3)
+}
+
+// InitClickCounters mocks base method.
+func (m *MockCache) InitClickCounters(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitClickCounters", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitClickCounters indicates an expected call of InitClickCounters.
+func (mr *MockCacheMockRecorder) InitClickCounters(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitClickCounters", reflect.TypeOf((*MockCache)(nil).InitClickCounters), arg0, arg1, arg2)
+}
+
 // Set mocks base method.
 func (m *MockCache) Set(arg0 context.Context, arg1 string, arg2 *types.LinkCache, arg3 time.Duration) error {
 	m.ctrl.T.Helper()
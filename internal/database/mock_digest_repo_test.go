@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: linkshortener/internal/database (interfaces: DigestRepo)
+
+// Package database is a generated GoMock package.
+package database
+
+import (
+	context "context"
+	types "linkshortener/internal/types"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDigestRepo is a mock of DigestRepo interface.
+type MockDigestRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockDigestRepoMockRecorder
+}
+
+// MockDigestRepoMockRecorder is the mock recorder for MockDigestRepo.
+type MockDigestRepoMockRecorder struct {
+	mock *MockDigestRepo
+}
+
+// NewMockDigestRepo creates a new mock instance.
+func NewMockDigestRepo(ctrl *gomock.Controller) *MockDigestRepo {
+	mock := &MockDigestRepo{ctrl: ctrl}
+	mock.recorder = &MockDigestRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDigestRepo) EXPECT() *MockDigestRepoMockRecorder {
+	return m.recorder
+}
+
+// ClaimDueDigests mocks base method.
+func (m *MockDigestRepo) ClaimDueDigests(arg0 context.Context, arg1 time.Time, arg2 func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueDigests", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.DigestSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueDigests indicates an expected call of ClaimDueDigests.
+func (mr *MockDigestRepoMockRecorder) ClaimDueDigests(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueDigests", reflect.TypeOf((*MockDigestRepo)(nil).ClaimDueDigests), arg0, arg1, arg2)
+}
+
+// DeleteDigestSubscription mocks base method.
+func (m *MockDigestRepo) DeleteDigestSubscription(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDigestSubscription", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDigestSubscription indicates an expected call of DeleteDigestSubscription.
+func (mr *MockDigestRepoMockRecorder) DeleteDigestSubscription(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDigestSubscription", reflect.TypeOf((*MockDigestRepo)(nil).DeleteDigestSubscription), arg0, arg1, arg2)
+}
+
+// GetDigestSubscriptions mocks base method.
+func (m *MockDigestRepo) GetDigestSubscriptions(arg0 context.Context, arg1 int64) ([]types.DigestSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDigestSubscriptions", arg0, arg1)
+	ret0, _ := ret[0].([]types.DigestSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDigestSubscriptions indicates an expected call of GetDigestSubscriptions.
+func (mr *MockDigestRepoMockRecorder) GetDigestSubscriptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDigestSubscriptions", reflect.TypeOf((*MockDigestRepo)(nil).GetDigestSubscriptions), arg0, arg1)
+}
+
+// SaveDigestSubscription mocks base method.
+func (m *MockDigestRepo) SaveDigestSubscription(arg0 context.Context, arg1 types.DigestSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDigestSubscription", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDigestSubscription indicates an expected call of SaveDigestSubscription.
+func (mr *MockDigestRepoMockRecorder) SaveDigestSubscription(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDigestSubscription", reflect.TypeOf((*MockDigestRepo)(nil).SaveDigestSubscription), arg0, arg1)
+}
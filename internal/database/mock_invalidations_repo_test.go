@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: linkshortener/internal/database (interfaces: InvalidationsRepo)
+
+// Package database is a generated GoMock package.
+package database
+
+import (
+	context "context"
+	types "linkshortener/internal/types"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockInvalidationsRepo is a mock of InvalidationsRepo interface.
+type MockInvalidationsRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvalidationsRepoMockRecorder
+}
+
+// MockInvalidationsRepoMockRecorder is the mock recorder for MockInvalidationsRepo.
+type MockInvalidationsRepoMockRecorder struct {
+	mock *MockInvalidationsRepo
+}
+
+// NewMockInvalidationsRepo creates a new mock instance.
+func NewMockInvalidationsRepo(ctrl *gomock.Controller) *MockInvalidationsRepo {
+	mock := &MockInvalidationsRepo{ctrl: ctrl}
+	mock.recorder = &MockInvalidationsRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvalidationsRepo) EXPECT() *MockInvalidationsRepoMockRecorder {
+	return m.recorder
+}
+
+// AckInvalidations mocks base method.
+func (m *MockInvalidationsRepo) AckInvalidations(arg0 context.Context, arg1 []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckInvalidations", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AckInvalidations indicates an expected call of AckInvalidations.
+func (mr *MockInvalidationsRepoMockRecorder) AckInvalidations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckInvalidations", reflect.TypeOf((*MockInvalidationsRepo)(nil).AckInvalidations), arg0, arg1)
+}
+
+// PendingInvalidations mocks base method.
+func (m *MockInvalidationsRepo) PendingInvalidations(arg0 context.Context, arg1 int) ([]types.Invalidation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingInvalidations", arg0, arg1)
+	ret0, _ := ret[0].([]types.Invalidation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingInvalidations indicates an expected call of PendingInvalidations.
+func (mr *MockInvalidationsRepoMockRecorder) PendingInvalidations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingInvalidations", reflect.TypeOf((*MockInvalidationsRepo)(nil).PendingInvalidations), arg0, arg1)
+}
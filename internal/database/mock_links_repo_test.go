@@ -8,6 +8,7 @@ import (
 	context "context"
 	types "linkshortener/internal/types"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -51,11 +52,12 @@ func (mr *MockLinksRepoMockRecorder) CreateLink(arg0, arg1, arg2 interface{}) *g
 }
 
 // DeleteAllLinksByUser mocks base method.
-func (m *MockLinksRepo) DeleteAllLinksByUser(arg0 context.Context, arg1 int64) error {
+func (m *MockLinksRepo) DeleteAllLinksByUser(arg0 context.Context, arg1 int64) ([]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteAllLinksByUser", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteAllLinksByUser indicates an expected call of DeleteAllLinksByUser.
@@ -79,11 +81,12 @@ func (mr *MockLinksRepoMockRecorder) DeleteLinkByCode(arg0, arg1, arg2 interface
 }
 
 // DeleteLinkById mocks base method.
-func (m *MockLinksRepo) DeleteLinkById(arg0 context.Context, arg1, arg2 int64) error {
+func (m *MockLinksRepo) DeleteLinkById(arg0 context.Context, arg1, arg2 int64) (string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteLinkById", arg0, arg1, arg2)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteLinkById indicates an expected call of DeleteLinkById.
@@ -122,6 +125,20 @@ func (mr *MockLinksRepoMockRecorder) GetLink(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockLinksRepo)(nil).GetLink), arg0, arg1)
 }
 
+// SetLinkCacheTTL mocks base method.
+func (m *MockLinksRepo) SetLinkCacheTTL(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkCacheTTL", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkCacheTTL indicates an expected call of SetLinkCacheTTL.
+func (mr *MockLinksRepoMockRecorder) SetLinkCacheTTL(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkCacheTTL", reflect.TypeOf((*MockLinksRepo)(nil).SetLinkCacheTTL), arg0, arg1, arg2, arg3)
+}
+
 // SetShortCode mocks base method.
 func (m *MockLinksRepo) SetShortCode(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
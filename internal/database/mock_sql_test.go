@@ -8,6 +8,7 @@ import (
 	context "context"
 	types "linkshortener/internal/types"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,50 @@ func (m *MockSQL) EXPECT() *MockSQLMockRecorder {
 	return m.recorder
 }
 
+// AckInvalidations mocks base method.
+func (m *MockSQL) AckInvalidations(arg0 context.Context, arg1 []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckInvalidations", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AckInvalidations indicates an expected call of AckInvalidations.
+func (mr *MockSQLMockRecorder) AckInvalidations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckInvalidations", reflect.TypeOf((*MockSQL)(nil).AckInvalidations), arg0, arg1)
+}
+
+// ClaimAlert mocks base method.
+func (m *MockSQL) ClaimAlert(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimAlert", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimAlert indicates an expected call of ClaimAlert.
+func (mr *MockSQLMockRecorder) ClaimAlert(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimAlert", reflect.TypeOf((*MockSQL)(nil).ClaimAlert), arg0, arg1, arg2, arg3)
+}
+
+// ClaimDueDigests mocks base method.
+func (m *MockSQL) ClaimDueDigests(arg0 context.Context, arg1 time.Time, arg2 func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueDigests", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]types.DigestSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueDigests indicates an expected call of ClaimDueDigests.
+func (mr *MockSQLMockRecorder) ClaimDueDigests(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueDigests", reflect.TypeOf((*MockSQL)(nil).ClaimDueDigests), arg0, arg1, arg2)
+}
+
 // Close mocks base method.
 func (m *MockSQL) Close() error {
 	m.ctrl.T.Helper()
@@ -65,25 +110,26 @@ func (mr *MockSQLMockRecorder) CreateLink(arg0, arg1, arg2 interface{}) *gomock.
 }
 
 // CreateUser mocks base method.
-func (m *MockSQL) CreateUser(arg0 context.Context, arg1 int64) error {
+func (m *MockSQL) CreateUser(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockSQLMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockSQLMockRecorder) CreateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockSQL)(nil).CreateUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockSQL)(nil).CreateUser), arg0, arg1, arg2)
 }
 
 // DeleteAllLinksByUser mocks base method.
-func (m *MockSQL) DeleteAllLinksByUser(arg0 context.Context, arg1 int64) error {
+func (m *MockSQL) DeleteAllLinksByUser(arg0 context.Context, arg1 int64) ([]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteAllLinksByUser", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteAllLinksByUser indicates an expected call of DeleteAllLinksByUser.
@@ -92,6 +138,20 @@ func (mr *MockSQLMockRecorder) DeleteAllLinksByUser(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllLinksByUser", reflect.TypeOf((*MockSQL)(nil).DeleteAllLinksByUser), arg0, arg1)
 }
 
+// DeleteDigestSubscription mocks base method.
+func (m *MockSQL) DeleteDigestSubscription(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDigestSubscription", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDigestSubscription indicates an expected call of DeleteDigestSubscription.
+func (mr *MockSQLMockRecorder) DeleteDigestSubscription(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDigestSubscription", reflect.TypeOf((*MockSQL)(nil).DeleteDigestSubscription), arg0, arg1, arg2)
+}
+
 // DeleteLinkByCode mocks base method.
 func (m *MockSQL) DeleteLinkByCode(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -107,11 +167,12 @@ func (mr *MockSQLMockRecorder) DeleteLinkByCode(arg0, arg1, arg2 interface{}) *g
 }
 
 // DeleteLinkById mocks base method.
-func (m *MockSQL) DeleteLinkById(arg0 context.Context, arg1, arg2 int64) error {
+func (m *MockSQL) DeleteLinkById(arg0 context.Context, arg1, arg2 int64) (string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteLinkById", arg0, arg1, arg2)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteLinkById indicates an expected call of DeleteLinkById.
@@ -135,6 +196,21 @@ func (mr *MockSQLMockRecorder) GetAllLinksByUser(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllLinksByUser", reflect.TypeOf((*MockSQL)(nil).GetAllLinksByUser), arg0, arg1)
 }
 
+// GetDigestSubscriptions mocks base method.
+func (m *MockSQL) GetDigestSubscriptions(arg0 context.Context, arg1 int64) ([]types.DigestSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDigestSubscriptions", arg0, arg1)
+	ret0, _ := ret[0].([]types.DigestSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDigestSubscriptions indicates an expected call of GetDigestSubscriptions.
+func (mr *MockSQLMockRecorder) GetDigestSubscriptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDigestSubscriptions", reflect.TypeOf((*MockSQL)(nil).GetDigestSubscriptions), arg0, arg1)
+}
+
 // GetLink mocks base method.
 func (m *MockSQL) GetLink(arg0 context.Context, arg1 string) (*types.LinkCache, error) {
 	m.ctrl.T.Helper()
@@ -150,6 +226,51 @@ func (mr *MockSQLMockRecorder) GetLink(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockSQL)(nil).GetLink), arg0, arg1)
 }
 
+// GetLinkAlert mocks base method.
+func (m *MockSQL) GetLinkAlert(arg0 context.Context, arg1 int64, arg2 string) (*types.LinkAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkAlert", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.LinkAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkAlert indicates an expected call of GetLinkAlert.
+func (mr *MockSQLMockRecorder) GetLinkAlert(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkAlert", reflect.TypeOf((*MockSQL)(nil).GetLinkAlert), arg0, arg1, arg2)
+}
+
+// GetTelegramIDByUserID mocks base method.
+func (m *MockSQL) GetTelegramIDByUserID(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelegramIDByUserID", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelegramIDByUserID indicates an expected call of GetTelegramIDByUserID.
+func (mr *MockSQLMockRecorder) GetTelegramIDByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelegramIDByUserID", reflect.TypeOf((*MockSQL)(nil).GetTelegramIDByUserID), arg0, arg1)
+}
+
+// GetUserIDByAPITokenHash mocks base method.
+func (m *MockSQL) GetUserIDByAPITokenHash(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserIDByAPITokenHash", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserIDByAPITokenHash indicates an expected call of GetUserIDByAPITokenHash.
+func (mr *MockSQLMockRecorder) GetUserIDByAPITokenHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDByAPITokenHash", reflect.TypeOf((*MockSQL)(nil).GetUserIDByAPITokenHash), arg0, arg1)
+}
+
 // GetUserIDByTelegramID mocks base method.
 func (m *MockSQL) GetUserIDByTelegramID(arg0 context.Context, arg1 int64) (int64, error) {
 	m.ctrl.T.Helper()
@@ -165,6 +286,121 @@ func (mr *MockSQLMockRecorder) GetUserIDByTelegramID(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDByTelegramID", reflect.TypeOf((*MockSQL)(nil).GetUserIDByTelegramID), arg0, arg1)
 }
 
+// GetUserSettings mocks base method.
+func (m *MockSQL) GetUserSettings(arg0 context.Context, arg1 int64) (*types.UserSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", arg0, arg1)
+	ret0, _ := ret[0].(*types.UserSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockSQLMockRecorder) GetUserSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockSQL)(nil).GetUserSettings), arg0, arg1)
+}
+
+// ListLinkAlerts mocks base method.
+func (m *MockSQL) ListLinkAlerts(arg0 context.Context) ([]types.LinkAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLinkAlerts", arg0)
+	ret0, _ := ret[0].([]types.LinkAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLinkAlerts indicates an expected call of ListLinkAlerts.
+func (mr *MockSQLMockRecorder) ListLinkAlerts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLinkAlerts", reflect.TypeOf((*MockSQL)(nil).ListLinkAlerts), arg0)
+}
+
+// PendingInvalidations mocks base method.
+func (m *MockSQL) PendingInvalidations(arg0 context.Context, arg1 int) ([]types.Invalidation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingInvalidations", arg0, arg1)
+	ret0, _ := ret[0].([]types.Invalidation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingInvalidations indicates an expected call of PendingInvalidations.
+func (mr *MockSQLMockRecorder) PendingInvalidations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingInvalidations", reflect.TypeOf((*MockSQL)(nil).PendingInvalidations), arg0, arg1)
+}
+
+// SaveDigestSubscription mocks base method.
+func (m *MockSQL) SaveDigestSubscription(arg0 context.Context, arg1 types.DigestSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDigestSubscription", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDigestSubscription indicates an expected call of SaveDigestSubscription.
+func (mr *MockSQLMockRecorder) SaveDigestSubscription(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDigestSubscription", reflect.TypeOf((*MockSQL)(nil).SaveDigestSubscription), arg0, arg1)
+}
+
+// SaveLinkAlert mocks base method.
+func (m *MockSQL) SaveLinkAlert(arg0 context.Context, arg1 types.LinkAlert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLinkAlert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLinkAlert indicates an expected call of SaveLinkAlert.
+func (mr *MockSQLMockRecorder) SaveLinkAlert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLinkAlert", reflect.TypeOf((*MockSQL)(nil).SaveLinkAlert), arg0, arg1)
+}
+
+// SetAPITokenHash mocks base method.
+func (m *MockSQL) SetAPITokenHash(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAPITokenHash", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAPITokenHash indicates an expected call of SetAPITokenHash.
+func (mr *MockSQLMockRecorder) SetAPITokenHash(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAPITokenHash", reflect.TypeOf((*MockSQL)(nil).SetAPITokenHash), arg0, arg1, arg2)
+}
+
+// SetCityOptOut mocks base method.
+func (m *MockSQL) SetCityOptOut(arg0 context.Context, arg1 int64, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCityOptOut", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCityOptOut indicates an expected call of SetCityOptOut.
+func (mr *MockSQLMockRecorder) SetCityOptOut(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCityOptOut", reflect.TypeOf((*MockSQL)(nil).SetCityOptOut), arg0, arg1, arg2)
+}
+
+// SetLinkCacheTTL mocks base method.
+func (m *MockSQL) SetLinkCacheTTL(arg0 context.Context, arg1 int64, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkCacheTTL", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkCacheTTL indicates an expected call of SetLinkCacheTTL.
+func (mr *MockSQLMockRecorder) SetLinkCacheTTL(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkCacheTTL", reflect.TypeOf((*MockSQL)(nil).SetLinkCacheTTL), arg0, arg1, arg2, arg3)
+}
+
 // SetShortCode mocks base method.
 func (m *MockSQL) SetShortCode(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -179,6 +415,20 @@ func (mr *MockSQLMockRecorder) SetShortCode(arg0, arg1, arg2 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetShortCode", reflect.TypeOf((*MockSQL)(nil).SetShortCode), arg0, arg1, arg2)
 }
 
+// SetTimezone mocks base method.
+func (m *MockSQL) SetTimezone(arg0 context.Context, arg1 int64, arg2 string, arg3 func(types.DigestSubscription) time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTimezone", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTimezone indicates an expected call of SetTimezone.
+func (mr *MockSQLMockRecorder) SetTimezone(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTimezone", reflect.TypeOf((*MockSQL)(nil).SetTimezone), arg0, arg1, arg2, arg3)
+}
+
 // UpdateLink mocks base method.
 func (m *MockSQL) UpdateLink(arg0 context.Context, arg1 int64, arg2, arg3 string) error {
 	m.ctrl.T.Helper()
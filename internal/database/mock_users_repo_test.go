@@ -6,7 +6,9 @@ package database
 
 import (
 	context "context"
+	types "linkshortener/internal/types"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,17 +37,47 @@ func (m *MockUsersRepo) EXPECT() *MockUsersRepoMockRecorder {
 }
 
 // CreateUser mocks base method.
-func (m *MockUsersRepo) CreateUser(arg0 context.Context, arg1 int64) error {
+func (m *MockUsersRepo) CreateUser(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockUsersRepoMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUsersRepoMockRecorder) CreateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUsersRepo)(nil).CreateUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUsersRepo)(nil).CreateUser), arg0, arg1, arg2)
+}
+
+// GetTelegramIDByUserID mocks base method.
+func (m *MockUsersRepo) GetTelegramIDByUserID(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelegramIDByUserID", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelegramIDByUserID indicates an expected call of GetTelegramIDByUserID.
+func (mr *MockUsersRepoMockRecorder) GetTelegramIDByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelegramIDByUserID", reflect.TypeOf((*MockUsersRepo)(nil).GetTelegramIDByUserID), arg0, arg1)
+}
+
+// GetUserIDByAPITokenHash mocks base method.
+func (m *MockUsersRepo) GetUserIDByAPITokenHash(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserIDByAPITokenHash", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserIDByAPITokenHash indicates an expected call of GetUserIDByAPITokenHash.
+func (mr *MockUsersRepoMockRecorder) GetUserIDByAPITokenHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDByAPITokenHash", reflect.TypeOf((*MockUsersRepo)(nil).GetUserIDByAPITokenHash), arg0, arg1)
 }
 
 // GetUserIDByTelegramID mocks base method.
@@ -62,3 +94,60 @@ func (mr *MockUsersRepoMockRecorder) GetUserIDByTelegramID(arg0, arg1 interface{
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDByTelegramID", reflect.TypeOf((*MockUsersRepo)(nil).GetUserIDByTelegramID), arg0, arg1)
 }
+
+// GetUserSettings mocks base method.
+func (m *MockUsersRepo) GetUserSettings(arg0 context.Context, arg1 int64) (*types.UserSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", arg0, arg1)
+	ret0, _ := ret[0].(*types.UserSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockUsersRepoMockRecorder) GetUserSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockUsersRepo)(nil).GetUserSettings), arg0, arg1)
+}
+
+// SetAPITokenHash mocks base method.
+func (m *MockUsersRepo) SetAPITokenHash(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAPITokenHash", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAPITokenHash indicates an expected call of SetAPITokenHash.
+func (mr *MockUsersRepoMockRecorder) SetAPITokenHash(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAPITokenHash", reflect.TypeOf((*MockUsersRepo)(nil).SetAPITokenHash), arg0, arg1, arg2)
+}
+
+// SetCityOptOut mocks base method.
+func (m *MockUsersRepo) SetCityOptOut(arg0 context.Context, arg1 int64, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCityOptOut", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCityOptOut indicates an expected call of SetCityOptOut.
+func (mr *MockUsersRepoMockRecorder) SetCityOptOut(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCityOptOut", reflect.TypeOf((*MockUsersRepo)(nil).SetCityOptOut), arg0, arg1, arg2)
+}
+
+// SetTimezone mocks base method.
+func (m *MockUsersRepo) SetTimezone(arg0 context.Context, arg1 int64, arg2 string, arg3 func(types.DigestSubscription) time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTimezone", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTimezone indicates an expected call of SetTimezone.
+func (mr *MockUsersRepoMockRecorder) SetTimezone(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTimezone", reflect.TypeOf((*MockUsersRepo)(nil).SetTimezone), arg0, arg1, arg2, arg3)
+}
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    user_id BIGINT NOT NULL,
+    short_code TEXT NOT NULL,
+    country TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    asn BIGINT NOT NULL DEFAULT 0,
+    isp TEXT NOT NULL DEFAULT '',
+    visitor_hash BIGINT NOT NULL DEFAULT 0,
+    user_agent TEXT NOT NULL DEFAULT '',
+    device TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    referer TEXT NOT NULL DEFAULT '',
+    referer_host TEXT NOT NULL DEFAULT '',
+    channel TEXT NOT NULL DEFAULT '',
+    utm_source TEXT NOT NULL DEFAULT '',
+    utm_medium TEXT NOT NULL DEFAULT '',
+    utm_campaign TEXT NOT NULL DEFAULT '',
+    utm_term TEXT NOT NULL DEFAULT '',
+    utm_content TEXT NOT NULL DEFAULT '',
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL
+) PARTITION BY RANGE (clicked_at);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_user_code_time ON clicks (user_id, short_code, clicked_at);
+CREATE INDEX IF NOT EXISTS idx_clicks_time ON clicks (clicked_at);
\ No newline at end of file
@@ -0,0 +1,108 @@
+package pganalytics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const partitionPrefix = "clicks_"
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func partitionName(month time.Time) string {
+	return partitionPrefix + month.Format("2006_01")
+}
+
+// parsePartitionName returns the month of a partition created by
+// ensurePartition.
+func parsePartitionName(name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, partitionPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	month, err := time.Parse("2006_01", suffix)
+	return month, err == nil
+}
+
+// ensurePartition creates the partition holding t unless this process has
+// already seen it.
+func (a *Analytics) ensurePartition(ctx context.Context, t time.Time) error {
+	month := monthStart(t)
+
+	a.partitionsMu.Lock()
+	defer a.partitionsMu.Unlock()
+	if a.partitions[month] {
+		return nil
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF clicks FOR VALUES FROM (%s) TO (%s)`,
+		pq.QuoteIdentifier(partitionName(month)),
+		pq.QuoteLiteral(month.Format(time.RFC3339)),
+		pq.QuoteLiteral(month.AddDate(0, 1, 0).Format(time.RFC3339)))
+	if _, err := a.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	a.partitions[month] = true
+	return nil
+}
+
+// expiredPartitions returns the partitions among names whose month ended
+// before cutoff.
+func expiredPartitions(names []string, cutoff time.Time) []string {
+	var expired []string
+	for _, name := range names {
+		month, ok := parsePartitionName(name)
+		if ok && !month.AddDate(0, 1, 0).After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	return expired
+}
+
+func (a *Analytics) dropExpiredPartitions(ctx context.Context) error {
+	var names []string
+	err := a.db.SelectContext(ctx, &names, `
+		SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'clicks'::regclass`)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -a.retentionDays)
+	for _, name := range expiredPartitions(names, cutoff) {
+		if _, err := a.db.ExecContext(ctx, `DROP TABLE IF EXISTS `+pq.QuoteIdentifier(name)); err != nil {
+			return err
+		}
+		month, _ := parsePartitionName(name)
+		a.partitionsMu.Lock()
+		delete(a.partitions, month)
+		a.partitionsMu.Unlock()
+		slog.Info("Dropped expired clicks partition", "partition", name)
+	}
+	return nil
+}
+
+func (a *Analytics) retainer(ctx context.Context) {
+	defer a.workerWG.Done()
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		if err := a.dropExpiredPartitions(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to drop expired clicks partitions", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pganalytics
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPartitionNames(t *testing.T) {
+	month := monthStart(time.Date(2026, 3, 31, 23, 30, 0, 0, time.FixedZone("EEST", 3*60*60)))
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !month.Equal(want) {
+		t.Fatalf("monthStart = %v, want %v", month, want)
+	}
+	name := partitionName(month)
+	if name != "clicks_2026_03" {
+		t.Fatalf("partitionName = %q", name)
+	}
+	if parsed, ok := parsePartitionName(name); !ok || !parsed.Equal(month) {
+		t.Errorf("parsePartitionName(%q) = %v, %v", name, parsed, ok)
+	}
+	if _, ok := parsePartitionName("clicks_default"); ok {
+		t.Error("parsePartitionName accepted a foreign table")
+	}
+}
+
+func TestExpiredPartitions(t *testing.T) {
+	names := []string{"clicks_2026_01", "clicks_2026_02", "clicks_2026_03", "clicks_default"}
+	// A partition expires only when its whole month is past the cutoff.
+	cutoff := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	if got, want := expiredPartitions(names, cutoff), []string{"clicks_2026_01", "clicks_2026_02"}; !slices.Equal(got, want) {
+		t.Errorf("expiredPartitions = %v, want %v", got, want)
+	}
+	if got := expiredPartitions(names, cutoff.Add(-time.Second)); !slices.Equal(got, []string{"clicks_2026_01"}) {
+		t.Errorf("expiredPartitions just before a month ends = %v", got)
+	}
+}
@@ -0,0 +1,194 @@
+// Package pganalytics stores clicks in PostgreSQL, for installs too small to
+// justify ClickHouse. Clicks go to a table partitioned by month and are
+// aggregated at query time.
+package pganalytics
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/geoip"
+	"linkshortener/internal/types"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+const (
+	// migrationsTable differs from the one of the links repository, so both
+	// can live in the same database.
+	migrationsTable = "analytics_schema_migrations"
+
+	retentionInterval = 24 * time.Hour
+
+	analyticColumns = "user_id, short_code, country, region, city, asn, isp, user_agent, device, browser, os, " +
+		"referer, referer_host, channel, utm_source, utm_medium, utm_campaign, utm_term, utm_content, clicked_at"
+)
+
+var insertColumns = []string{
+	"user_id", "short_code", "country", "region", "city", "asn", "isp", "visitor_hash",
+	"user_agent", "device", "browser", "os",
+	"referer", "referer_host", "channel", "utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+	"clicked_at",
+}
+
+type Config struct {
+	URL string
+	// RetentionDays drops monthly partitions whose clicks are all older
+	// than this many days. Zero keeps clicks forever.
+	RetentionDays int
+	// Ingest configures batching and the spool; its Name defaults to
+	// "pganalytics".
+	Ingest ingest.Config
+}
+
+type Analytics struct {
+	db            *sqlx.DB
+	pipeline      *ingest.Pipeline
+	retentionDays int
+	partitionsMu  sync.Mutex
+	partitions    map[time.Time]bool
+	workerCancel  context.CancelFunc
+	workerWG      sync.WaitGroup
+	startOnce     sync.Once
+	closeOnce     sync.Once
+}
+
+func Connect(ctx context.Context, cfg Config, geo *geoip.Resolver) (*Analytics, error) {
+	if cfg.Ingest.Name == "" {
+		cfg.Ingest.Name = "pganalytics"
+	}
+
+	db, err := sqlx.ConnectContext(ctx, "postgres", cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Analytics{
+		db:            db,
+		retentionDays: cfg.RetentionDays,
+		partitions:    make(map[time.Time]bool),
+	}
+
+	if err := a.runMigrations(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	a.pipeline, err = ingest.NewPipeline(cfg.Ingest, geo, a)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *Analytics) runMigrations() error {
+	d, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	driver, err := postgres.WithInstance(a.db.DB, &postgres.Config{MigrationsTable: migrationsTable})
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance(
+		"iofs", d,
+		"postgres", driver)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	slog.Info("Analytics migrations applied successfully")
+	return nil
+}
+
+func (a *Analytics) Start(ctx context.Context) {
+	a.startOnce.Do(func() {
+		a.pipeline.Start(ctx)
+
+		if a.retentionDays > 0 {
+			workerCtx, cancel := context.WithCancel(ctx)
+			a.workerCancel = cancel
+			a.workerWG.Add(1)
+			go a.retainer(workerCtx)
+		}
+	})
+}
+
+func (a *Analytics) Close() error {
+	var closeErr error
+
+	a.closeOnce.Do(func() {
+		a.pipeline.Close()
+		if a.workerCancel != nil {
+			a.workerCancel()
+		}
+		a.workerWG.Wait()
+		closeErr = a.db.Close()
+	})
+
+	return closeErr
+}
+
+func (a *Analytics) PushClick(data types.ClickData) {
+	a.pipeline.PushClick(data)
+}
+
+// WriteClicks copies a batch of clicks into the table in one transaction,
+// creating the monthly partitions it needs first.
+func (a *Analytics) WriteClicks(ctx context.Context, clicks []ingest.Click) error {
+	for _, c := range clicks {
+		if err := a.ensurePartition(ctx, c.ClickedAt); err != nil {
+			return err
+		}
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("clicks", insertColumns...))
+	if err != nil {
+		return err
+	}
+	for _, c := range clicks {
+		_, err := stmt.ExecContext(ctx,
+			c.UserId, c.ShortCode, c.Country, c.Region, c.City, int64(c.ASN), c.ISP, int64(c.Visitor),
+			c.UserAgent, c.Device, c.Browser, c.OS,
+			c.Referer, c.RefererHost, c.Channel, c.UTMSource, c.UTMMedium, c.UTMCampaign, c.UTMTerm, c.UTMContent,
+			c.ClickedAt.UTC())
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
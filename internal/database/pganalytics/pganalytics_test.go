@@ -0,0 +1,37 @@
+package pganalytics
+
+import (
+	"context"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/database/storagetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestConformance needs a PostgreSQL database in TEST_POSTGRES_URL, like the
+// tests of package postgresql.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := Connect(ctx, Config{
+		URL: url,
+		Ingest: ingest.Config{
+			BatchSize:     100,
+			FlushInterval: 50 * time.Millisecond,
+			BufferSize:    1000,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	a.Start(ctx)
+
+	storagetest.Analytics(t, func(*testing.T) database.Analytics { return a })
+}
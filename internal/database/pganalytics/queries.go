@@ -0,0 +1,224 @@
+package pganalytics
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"strconv"
+	"time"
+)
+
+// args collects query arguments and hands out their placeholders.
+type args []any
+
+func (a *args) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// where selects the clicks of the filter's user (or link) in its range.
+func where(filter types.AnalyticsFilter, a *args) string {
+	cond := "user_id = " + a.add(filter.UserId)
+	if filter.ShortCode != "" {
+		cond += " AND short_code = " + a.add(filter.ShortCode)
+	}
+	return cond + " AND clicked_at >= " + a.add(filter.From.UTC()) + " AND clicked_at < " + a.add(filter.To.UTC())
+}
+
+func timezone(filter types.AnalyticsFilter) string {
+	if filter.Timezone == "" {
+		return "UTC"
+	}
+	return filter.Timezone
+}
+
+func (a *Analytics) GetAllAnalytic(ctx context.Context, userId int64) ([]types.Analytic, error) {
+	var clicks []types.Analytic
+	query := `SELECT ` + analyticColumns + ` FROM clicks WHERE user_id = $1`
+	err := a.db.SelectContext(ctx, &clicks, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return clicks, nil
+}
+
+func (a *Analytics) GetAnalyticByCode(ctx context.Context, code string, userId int64) ([]types.Analytic, error) {
+	var clicks []types.Analytic
+	query := `SELECT ` + analyticColumns + ` FROM clicks WHERE short_code = $1 AND user_id = $2`
+	err := a.db.SelectContext(ctx, &clicks, query, code, userId)
+	if err != nil {
+		return nil, err
+	}
+	return clicks, nil
+}
+
+// GetDailyClicks buckets days in the filter's timezone.
+func (a *Analytics) GetDailyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	var qa args
+	tz := qa.add(timezone(filter))
+	query := `
+		SELECT (clicked_at AT TIME ZONE ` + tz + `)::date AS day, count(*) AS clicks
+		FROM clicks
+		WHERE ` + where(filter, &qa) + `
+		GROUP BY day
+		ORDER BY day`
+
+	var daily []types.DailyClicks
+	if err := a.db.SelectContext(ctx, &daily, query, qa...); err != nil {
+		return nil, err
+	}
+	return daily, nil
+}
+
+// GetHourlyClicks returns the clicks per weekday and hour of day in the
+// filter's timezone.
+func (a *Analytics) GetHourlyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.HourlyClicks, error) {
+	var qa args
+	tz := qa.add(timezone(filter))
+	query := `
+		SELECT EXTRACT(ISODOW FROM clicked_at AT TIME ZONE ` + tz + `)::int AS weekday,
+			EXTRACT(HOUR FROM clicked_at AT TIME ZONE ` + tz + `)::int AS hour,
+			count(*) AS clicks
+		FROM clicks
+		WHERE ` + where(filter, &qa) + `
+		GROUP BY weekday, hour
+		ORDER BY weekday, hour`
+
+	var hourly []types.HourlyClicks
+	if err := a.db.SelectContext(ctx, &hourly, query, qa...); err != nil {
+		return nil, err
+	}
+	return hourly, nil
+}
+
+// GetTotalClicks counts the clicks in the filter's range.
+func (a *Analytics) GetTotalClicks(ctx context.Context, filter types.AnalyticsFilter) (int64, error) {
+	var qa args
+	query := `SELECT count(*) FROM clicks WHERE ` + where(filter, &qa)
+
+	var total int64
+	if err := a.db.GetContext(ctx, &total, query, qa...); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (a *Analytics) GetTopCountries(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "country", false, filter, limit)
+}
+
+func (a *Analytics) GetTopCities(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "city", false, filter, limit)
+}
+
+func (a *Analytics) GetTopChannels(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "channel", false, filter, limit)
+}
+
+// GetTopSources counts clicks per referring host, or per utm_source for
+// clicks without a referer. Clicks with neither are left out.
+func (a *Analytics) GetTopSources(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "COALESCE(NULLIF(referer_host, ''), utm_source)", true, filter, limit)
+}
+
+func (a *Analytics) GetTopLinks(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	return a.topStats(ctx, "short_code", false, filter, limit)
+}
+
+// topStats counts clicks per value of column, most clicked first.
+// skipEmpty leaves out clicks without a value.
+func (a *Analytics) topStats(ctx context.Context, column string, skipEmpty bool, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error) {
+	var qa args
+	having := ""
+	if skipEmpty {
+		having = "HAVING " + column + " <> ''"
+	}
+	query := `
+		SELECT ` + column + ` AS key, count(*) AS clicks
+		FROM clicks
+		WHERE ` + where(filter, &qa) + `
+		GROUP BY key
+		` + having + `
+		ORDER BY clicks DESC, key
+		LIMIT ` + qa.add(limit)
+
+	var stats []types.CountStat
+	if err := a.db.SelectContext(ctx, &stats, query, qa...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetHotCodes returns the most clicked short codes of all users since the
+// given time, for warming up the link cache.
+func (a *Analytics) GetHotCodes(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT short_code FROM clicks
+		WHERE clicked_at >= $1
+		GROUP BY short_code
+		ORDER BY count(*) DESC
+		LIMIT $2`
+
+	var codes []string
+	if err := a.db.SelectContext(ctx, &codes, query, since.UTC(), limit); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (a *Analytics) GetUniqueVisitors(ctx context.Context, filter types.AnalyticsFilter) (int64, error) {
+	var qa args
+	query := `SELECT count(DISTINCT visitor_hash) FROM clicks WHERE ` + where(filter, &qa)
+
+	var visitors int64
+	if err := a.db.GetContext(ctx, &visitors, query, qa...); err != nil {
+		return 0, err
+	}
+	return visitors, nil
+}
+
+// ExportClicks streams raw clicks matching the filter to fn in chronological
+// order, stopping after limit rows. Rows are scanned as they arrive, so
+// memory use does not grow with the size of the export.
+func (a *Analytics) ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error {
+	var qa args
+	query := `SELECT ` + analyticColumns + ` FROM clicks
+		WHERE ` + where(filter, &qa) + `
+		ORDER BY clicked_at
+		LIMIT ` + qa.add(limit)
+
+	rows, err := a.db.QueryxContext(ctx, query, qa...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var click types.Analytic
+		if err := rows.StructScan(&click); err != nil {
+			return err
+		}
+		if err := fn(click); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetLinkRates returns, for every link clicked in [baselineFrom, to), its
+// clicks in [windowFrom, to) and in the baseline [baselineFrom, windowFrom).
+func (a *Analytics) GetLinkRates(ctx context.Context, baselineFrom, windowFrom, to time.Time) ([]types.LinkRate, error) {
+	query := `
+		SELECT user_id, short_code,
+			count(*) FILTER (WHERE clicked_at >= $1) AS recent,
+			count(*) FILTER (WHERE clicked_at < $1) AS baseline
+		FROM clicks
+		WHERE clicked_at >= $2 AND clicked_at < $3
+		GROUP BY user_id, short_code`
+
+	var rates []types.LinkRate
+	err := a.db.SelectContext(ctx, &rates, query, windowFrom.UTC(), baselineFrom.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
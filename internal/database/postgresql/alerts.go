@@ -0,0 +1,74 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"time"
+)
+
+const linkAlertColumns = `l.user_id, l.short_code, a.enabled, a.spike_factor, a.min_clicks, a.drop_alerts, a.muted_until`
+
+func (db *PostgreSQL) GetLinkAlert(ctx context.Context, userId int64, shortCode string) (*types.LinkAlert, error) {
+	query := `
+		SELECT ` + linkAlertColumns + `
+		FROM link_alerts a JOIN links l ON l.id = a.link_id
+		WHERE l.user_id = $1 AND l.short_code = $2`
+	var alert types.LinkAlert
+	err := db.db.GetContext(ctx, &alert, query, userId, shortCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		alert = types.DefaultLinkAlert(userId, shortCode)
+		return &alert, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (db *PostgreSQL) ListLinkAlerts(ctx context.Context) ([]types.LinkAlert, error) {
+	query := `SELECT ` + linkAlertColumns + ` FROM link_alerts a JOIN links l ON l.id = a.link_id`
+	var alerts []types.LinkAlert
+	err := db.db.SelectContext(ctx, &alerts, query)
+	return alerts, err
+}
+
+func (db *PostgreSQL) SaveLinkAlert(ctx context.Context, alert types.LinkAlert) error {
+	query := `
+		INSERT INTO link_alerts (link_id, enabled, spike_factor, min_clicks, drop_alerts, muted_until)
+		SELECT id, $3, $4, $5, $6, $7 FROM links WHERE user_id = $1 AND short_code = $2
+		ON CONFLICT (link_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			spike_factor = EXCLUDED.spike_factor,
+			min_clicks = EXCLUDED.min_clicks,
+			drop_alerts = EXCLUDED.drop_alerts,
+			muted_until = EXCLUDED.muted_until`
+	_, err := db.db.ExecContext(ctx, query, alert.UserId, alert.ShortCode,
+		alert.Enabled, alert.SpikeFactor, alert.MinClicks, alert.DropAlerts, alert.MutedUntil)
+	return err
+}
+
+// ClaimAlert records that an alert for the link is being sent unless one was
+// already sent after cutoff. Only one caller (across all instances) wins.
+func (db *PostgreSQL) ClaimAlert(ctx context.Context, userId int64, shortCode string, cutoff time.Time) (bool, error) {
+	query := `
+		INSERT INTO link_alerts (link_id, last_alert_at)
+		SELECT id, CURRENT_TIMESTAMP FROM links WHERE user_id = $1 AND short_code = $2
+		ON CONFLICT (link_id) DO UPDATE SET last_alert_at = EXCLUDED.last_alert_at
+		WHERE link_alerts.last_alert_at IS NULL OR link_alerts.last_alert_at < $3
+		RETURNING link_id`
+	var linkId int64
+	err := db.db.GetContext(ctx, &linkId, query, userId, shortCode, cutoff)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (db *PostgreSQL) GetTelegramIDByUserID(ctx context.Context, userId int64) (int64, error) {
+	var telegramID int64
+	err := db.db.GetContext(ctx, &telegramID, "SELECT telegram_id FROM users WHERE id = $1", userId)
+	return telegramID, customerrs.NotFound(err)
+}
@@ -0,0 +1,120 @@
+package postgresql
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"time"
+)
+
+// digestLockKey identifies the advisory lock held while due digests are
+// claimed, so that only one instance picks them up at a time.
+const digestLockKey = 7_302_981_201
+
+const digestColumns = `d.user_id, d.period, d.send_hour, d.weekday, d.next_run_at, u.timezone`
+
+func (db *PostgreSQL) GetDigestSubscriptions(ctx context.Context, userId int64) ([]types.DigestSubscription, error) {
+	query := `
+		SELECT ` + digestColumns + `
+		FROM digest_subscriptions d JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1
+		ORDER BY d.period`
+	var subs []types.DigestSubscription
+	err := db.db.SelectContext(ctx, &subs, query, userId)
+	return subs, err
+}
+
+func (db *PostgreSQL) SaveDigestSubscription(ctx context.Context, sub types.DigestSubscription) error {
+	query := `
+		INSERT INTO digest_subscriptions (user_id, period, send_hour, weekday, next_run_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, period) DO UPDATE SET
+			send_hour = EXCLUDED.send_hour,
+			weekday = EXCLUDED.weekday,
+			next_run_at = EXCLUDED.next_run_at`
+	_, err := db.db.ExecContext(ctx, query, sub.UserId, sub.Period, sub.Hour, sub.Weekday, sub.NextRunAt)
+	return err
+}
+
+func (db *PostgreSQL) DeleteDigestSubscription(ctx context.Context, userId int64, period string) error {
+	query := `DELETE FROM digest_subscriptions WHERE user_id = $1 AND period = $2`
+	_, err := db.db.ExecContext(ctx, query, userId, period)
+	return err
+}
+
+// SetTimezone changes the user's timezone and moves their digest
+// subscriptions to the run computed by next in the new timezone, so that
+// they are not sent at the hour of the old one.
+func (db *PostgreSQL) SetTimezone(ctx context.Context, userId int64, timezone string, next func(types.DigestSubscription) time.Time) error {
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET timezone = $1 WHERE id = $2`, timezone, userId); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT ` + digestColumns + `
+		FROM digest_subscriptions d JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1
+		FOR UPDATE OF d`
+	var subs []types.DigestSubscription
+	if err := tx.SelectContext(ctx, &subs, query, userId); err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE digest_subscriptions SET next_run_at = $1 WHERE user_id = $2 AND period = $3`,
+			next(sub), sub.UserId, sub.Period)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimDueDigests returns the subscriptions due at now and moves each of them
+// to the run computed by next in the same transaction. The transaction holds
+// an advisory lock, so a digest is handed out once even when several
+// instances poll at the same time; if another instance holds the lock,
+// nothing is returned.
+func (db *PostgreSQL) ClaimDueDigests(ctx context.Context, now time.Time, next func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error) {
+	tx, err := db.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, `SELECT pg_try_advisory_xact_lock($1)`, digestLockKey); err != nil || !locked {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + digestColumns + `
+		FROM digest_subscriptions d JOIN users u ON u.id = d.user_id
+		WHERE d.next_run_at <= $1
+		ORDER BY d.next_run_at
+		FOR UPDATE OF d SKIP LOCKED`
+	var due []types.DigestSubscription
+	if err := tx.SelectContext(ctx, &due, query, now); err != nil {
+		return nil, err
+	}
+
+	for _, sub := range due {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE digest_subscriptions SET next_run_at = $1 WHERE user_id = $2 AND period = $3`,
+			next(sub), sub.UserId, sub.Period)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
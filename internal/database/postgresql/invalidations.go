@@ -0,0 +1,23 @@
+package postgresql
+
+import (
+	"context"
+	"linkshortener/internal/types"
+
+	"github.com/lib/pq"
+)
+
+// PendingInvalidations returns the oldest invalidations recorded by the
+// triggers of migration 000009.
+func (db *PostgreSQL) PendingInvalidations(ctx context.Context, limit int) ([]types.Invalidation, error) {
+	query := `SELECT id, short_code FROM cache_invalidations ORDER BY id LIMIT $1`
+	var pending []types.Invalidation
+	err := db.db.SelectContext(ctx, &pending, query, limit)
+	return pending, err
+}
+
+func (db *PostgreSQL) AckInvalidations(ctx context.Context, ids []int64) error {
+	query := `DELETE FROM cache_invalidations WHERE id = ANY($1)`
+	_, err := db.db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
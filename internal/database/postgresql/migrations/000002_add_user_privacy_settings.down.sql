@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS geo_city_opt_out;
\ No newline at end of file
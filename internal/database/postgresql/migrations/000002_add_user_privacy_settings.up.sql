@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS geo_city_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS api_token_hash;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS api_token_hash TEXT UNIQUE;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS link_alerts;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS link_alerts (
+    link_id BIGINT PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    spike_factor DOUBLE PRECISION NOT NULL DEFAULT 3,
+    min_clicks BIGINT NOT NULL DEFAULT 20,
+    drop_alerts BOOLEAN NOT NULL DEFAULT TRUE,
+    muted_until TIMESTAMP WITH TIME ZONE,
+    last_alert_at TIMESTAMP WITH TIME ZONE
+);
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period TEXT NOT NULL CHECK (period IN ('daily', 'weekly')),
+    send_hour SMALLINT NOT NULL DEFAULT 9 CHECK (send_hour BETWEEN 0 AND 23),
+    weekday SMALLINT NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 1 AND 7),
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_subscriptions_next_run_at ON digest_subscriptions(next_run_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE links DROP COLUMN IF EXISTS cache_ttl_seconds;
\ No newline at end of file
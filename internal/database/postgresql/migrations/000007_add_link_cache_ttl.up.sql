@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS cache_ttl_seconds INTEGER CHECK (cache_ttl_seconds > 0);
\ No newline at end of file
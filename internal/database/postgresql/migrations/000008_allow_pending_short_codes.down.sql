@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_links_short_code;
+ALTER TABLE links ADD CONSTRAINT links_short_code_key UNIQUE (short_code);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_short_code_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_links_short_code ON links(short_code) WHERE short_code <> '';
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS users_cache_invalidation ON users;
+DROP TRIGGER IF EXISTS links_cache_invalidation ON links;
+DROP FUNCTION IF EXISTS enqueue_user_invalidation();
+DROP FUNCTION IF EXISTS enqueue_link_invalidation();
+DROP TABLE IF EXISTS cache_invalidations;
\ No newline at end of file
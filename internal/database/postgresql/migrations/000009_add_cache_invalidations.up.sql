@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS cache_invalidations (
+    id BIGSERIAL PRIMARY KEY,
+    short_code TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every change to a published link records its code in the same
+-- transaction, so the cache entry is dropped even if the process dies right
+-- after the commit. The application drains the table.
+CREATE OR REPLACE FUNCTION enqueue_link_invalidation() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.short_code <> '' THEN
+            INSERT INTO cache_invalidations (short_code) VALUES (OLD.short_code);
+        END IF;
+    END IF;
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.short_code <> '' THEN
+            INSERT INTO cache_invalidations (short_code) VALUES (NEW.short_code);
+        END IF;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF NEW.short_code <> '' AND NEW.short_code <> OLD.short_code THEN
+            INSERT INTO cache_invalidations (short_code) VALUES (NEW.short_code);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS links_cache_invalidation ON links;
+CREATE TRIGGER links_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON links
+    FOR EACH ROW EXECUTE FUNCTION enqueue_link_invalidation();
+
+-- Cached links carry the owner's city opt-out.
+CREATE OR REPLACE FUNCTION enqueue_user_invalidation() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO cache_invalidations (short_code)
+    SELECT short_code FROM links WHERE user_id = NEW.id AND short_code <> '';
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_cache_invalidation ON users;
+CREATE TRIGGER users_cache_invalidation
+    AFTER UPDATE OF geo_city_opt_out ON users
+    FOR EACH ROW WHEN (OLD.geo_city_opt_out IS DISTINCT FROM NEW.geo_city_opt_out)
+    EXECUTE FUNCTION enqueue_user_invalidation();
\ No newline at end of file
@@ -2,12 +2,16 @@ package postgresql
 
 import (
 	"context"
+	"database/sql"
 	"embed"
 	"errors"
+	customerrs "linkshortener/internal/customErrs"
 	"linkshortener/internal/types"
 	"log/slog"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -17,6 +21,8 @@ import (
 //go:embed migrations/*.sql
 var migrationsPostgreSQLFS embed.FS
 
+const uniqueViolation = "23505"
+
 type PostgreSQL struct {
 	db *sqlx.DB
 }
@@ -37,7 +43,7 @@ func Connect(ctx context.Context, url string) (*PostgreSQL, error) {
 }
 
 func (db *PostgreSQL) runMigrations() error {
-	d, err := iofs.New(migrationsPostgreSQLFS, "migrations/postgresql")
+	d, err := iofs.New(migrationsPostgreSQLFS, "migrations")
 	if err != nil {
 		return err
 	}
@@ -66,30 +72,45 @@ func (db *PostgreSQL) Close() error {
 	return db.db.Close()
 }
 
-func (db *PostgreSQL) CreateUser(ctx context.Context, telegramID int64) error {
+// CreateUser registers a user with the given timezone. The timezone of an
+// existing user is left untouched.
+func (db *PostgreSQL) CreateUser(ctx context.Context, telegramID int64, timezone string) error {
 	query := `
-		INSERT INTO users (telegram_id) VALUES ($1)
+		INSERT INTO users (telegram_id, timezone) VALUES ($1, $2)
 		ON CONFLICT (telegram_id) DO UPDATE SET telegram_id = EXCLUDED.telegram_id;`
-	_, err := db.db.ExecContext(ctx, query, telegramID)
+	_, err := db.db.ExecContext(ctx, query, telegramID, timezone)
 	return err
 }
 
 func (db *PostgreSQL) GetUserIDByTelegramID(ctx context.Context, telegramID int64) (int64, error) {
 	var id int64
 	err := db.db.GetContext(ctx, &id, "SELECT id FROM users WHERE telegram_id = $1", telegramID)
-	return id, err
+	return id, customerrs.NotFound(err)
+}
+
+func (db *PostgreSQL) GetUserIDByAPITokenHash(ctx context.Context, tokenHash string) (int64, error) {
+	var id int64
+	err := db.db.GetContext(ctx, &id, "SELECT id FROM users WHERE api_token_hash = $1", tokenHash)
+	return id, customerrs.NotFound(err)
+}
+
+func (db *PostgreSQL) SetAPITokenHash(ctx context.Context, userId int64, tokenHash string) error {
+	query := `UPDATE users SET api_token_hash = $1 WHERE id = $2`
+	_, err := db.db.ExecContext(ctx, query, tokenHash, userId)
+	return err
 }
 
 func (db *PostgreSQL) GetAllLinksByUser(ctx context.Context, userId int64) ([]types.LinkData, error) {
-	query := `SELECT * FROM links WHERE user_id = $1`
+	query := `SELECT id, user_id, original_link, short_code, created_at, updated_at FROM links WHERE user_id = $1 ORDER BY id`
 	var links []types.LinkData
 	err := db.db.SelectContext(ctx, &links, query, userId)
 	return links, err
 }
-func (db *PostgreSQL) DeleteAllLinksByUser(ctx context.Context, userId int64) error {
-	query := `DELETE FROM links WHERE user_id = $1`
-	_, err := db.db.ExecContext(ctx, query, userId)
-	return err
+func (db *PostgreSQL) DeleteAllLinksByUser(ctx context.Context, userId int64) ([]string, error) {
+	query := `DELETE FROM links WHERE user_id = $1 RETURNING short_code`
+	var codes []string
+	err := db.db.SelectContext(ctx, &codes, query, userId)
+	return codes, err
 }
 
 func (db *PostgreSQL) CreateLink(ctx context.Context, userID int64, originalLink string) (int64, error) {
@@ -99,9 +120,15 @@ func (db *PostgreSQL) CreateLink(ctx context.Context, userID int64, originalLink
 	return id, err
 }
 
+// SetShortCode returns customerrs.ErrCodeIsBusy if another link already
+// uses the code.
 func (db *PostgreSQL) SetShortCode(ctx context.Context, id int64, shortCode string) error {
 	query := `UPDATE links SET short_code = $1 WHERE id = $2`
 	_, err := db.db.ExecContext(ctx, query, shortCode, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return customerrs.ErrCodeIsBusy
+	}
 	return err
 }
 
@@ -117,18 +144,58 @@ func (db *PostgreSQL) DeleteLinkByCode(ctx context.Context, userId int64, shortC
 	return err
 }
 
-func (db *PostgreSQL) DeleteLinkById(ctx context.Context, userId, linkId int64) error {
-	query := `DELETE FROM links WHERE user_id = $1 AND id = $2`
-	_, err := db.db.ExecContext(ctx, query, userId, linkId)
-	return err
+// DeleteLinkById returns the short code of the deleted link, which is empty
+// if there was no such link or its code had not been set yet.
+func (db *PostgreSQL) DeleteLinkById(ctx context.Context, userId, linkId int64) (string, error) {
+	query := `DELETE FROM links WHERE user_id = $1 AND id = $2 RETURNING short_code`
+	var shortCode string
+	err := db.db.GetContext(ctx, &shortCode, query, userId, linkId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return shortCode, err
+}
+
+// SetLinkCacheTTL sets how long the link stays cached; zero restores the
+// global TTL.
+func (db *PostgreSQL) SetLinkCacheTTL(ctx context.Context, userId int64, shortCode string, ttl time.Duration) error {
+	query := `UPDATE links SET cache_ttl_seconds = NULLIF($1, 0) WHERE user_id = $2 AND short_code = $3`
+	res, err := db.db.ExecContext(ctx, query, int64(ttl/time.Second), userId, shortCode)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return customerrs.NotFound(sql.ErrNoRows)
+	}
+	return nil
 }
 
 func (db *PostgreSQL) GetLink(ctx context.Context, shortCode string) (*types.LinkCache, error) {
-	query := `SELECT original_link, user_id FROM links WHERE short_code = $1`
+	query := `
+		SELECT l.original_link, l.user_id, u.geo_city_opt_out AS city_opt_out,
+			COALESCE(l.cache_ttl_seconds, 0) AS cache_ttl_seconds
+		FROM links l JOIN users u ON u.id = l.user_id
+		WHERE l.short_code = $1`
 	var linkCache types.LinkCache
 	err := db.db.GetContext(ctx, &linkCache, query, shortCode)
 	if err != nil {
-		return nil, err
+		return nil, customerrs.NotFound(err)
 	}
 	return &linkCache, err
 }
+
+func (db *PostgreSQL) GetUserSettings(ctx context.Context, userId int64) (*types.UserSettings, error) {
+	query := `SELECT geo_city_opt_out AS city_opt_out, timezone FROM users WHERE id = $1`
+	var settings types.UserSettings
+	err := db.db.GetContext(ctx, &settings, query, userId)
+	if err != nil {
+		return nil, customerrs.NotFound(err)
+	}
+	return &settings, nil
+}
+
+func (db *PostgreSQL) SetCityOptOut(ctx context.Context, userId int64, optOut bool) error {
+	query := `UPDATE users SET geo_city_opt_out = $1 WHERE id = $2`
+	_, err := db.db.ExecContext(ctx, query, optOut, userId)
+	return err
+}
@@ -0,0 +1,67 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/storagetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// connectTest connects to the database in TEST_POSTGRES_URL and migrates it.
+// The tests only add rows, so it can be any development database.
+func connectTest(t *testing.T) *PostgreSQL {
+	t.Helper()
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	pg, err := Connect(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pg.Close() })
+	return pg
+}
+
+// TestGetAllLinksByUserAfterMigrations scans link rows of the latest schema,
+// including a link with its own cache TTL.
+func TestGetAllLinksByUserAfterMigrations(t *testing.T) {
+	pg := connectTest(t)
+	ctx := context.Background()
+
+	telegramID := time.Now().UnixNano()
+	if err := pg.CreateUser(ctx, telegramID, "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	userId, err := pg.GetUserIDByTelegramID(ctx, telegramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := pg.CreateLink(ctx, userId, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := fmt.Sprintf("t%x", telegramID)
+	if err := pg.SetShortCode(ctx, id, code); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.SetLinkCacheTTL(ctx, userId, code, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := pg.GetAllLinksByUser(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].Id != id || links[0].ShortCode != code || links[0].OriginalLink != "https://example.com" {
+		t.Errorf("GetAllLinksByUser = %+v, want link %d with code %s", links, id, code)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	pg := connectTest(t)
+	storagetest.SQL(t, func(*testing.T) database.SQL { return pg })
+}
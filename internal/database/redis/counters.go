@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/types"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// counterBucket is the resolution of the per-day counters. Every time
+	// zone offset is a multiple of it, so a local day is a whole number of
+	// buckets.
+	counterBucket = 15 * time.Minute
+	// dailyCounterTTL keeps the buckets of a UTC day long enough to cover
+	// the local day of any time zone.
+	dailyCounterTTL = 3 * 24 * time.Hour
+)
+
+// counterKey builds the keys of one link's counters. The hash tag keeps all
+// of them in one Redis Cluster slot. Keys include the owner, so a custom
+// code that is deleted and taken by someone else starts from zero.
+func (c *Redis) counterKey(kind string, userId int64, shortCode string) string {
+	return c.key(kind + ":{" + strconv.FormatInt(userId, 10) + ":" + shortCode + "}")
+}
+
+// InitClickCounters starts the counters of a newly published link from
+// zero. Only initialised counters are reported by GetClickCounters: links
+// published before the counters existed had clicks they never saw.
+func (c *Redis) InitClickCounters(ctx context.Context, userId int64, shortCode string) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.Del(ctx, c.counterKeys(userId, shortCode)...)
+	pipe.Set(ctx, c.counterKey("counted", userId, shortCode), 1, 0)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeleteClickCounters drops all counters of the given links.
+func (c *Redis) DeleteClickCounters(ctx context.Context, userId int64, shortCodes ...string) error {
+	if len(shortCodes) == 0 {
+		return nil
+	}
+	// One DEL per link, since the keys of different links may live in
+	// different cluster slots.
+	pipe := c.rdb.Pipeline()
+	for _, code := range shortCodes {
+		pipe.Del(ctx, append(c.counterKeys(userId, code), c.counterKey("counted", userId, code))...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// counterKeys returns the count keys of a link, including the daily ones
+// that have not expired yet.
+func (c *Redis) counterKeys(userId int64, shortCode string) []string {
+	keys := []string{c.counterKey("clicks", userId, shortCode), c.counterKey("visitors", userId, shortCode)}
+	today := time.Now().UTC()
+	for day := today.Add(-dailyCounterTTL); !day.After(today.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		keys = append(keys, c.dailyKey(userId, shortCode, day))
+	}
+	return keys
+}
+
+// dailyKey is the hash of a link's clicks on the UTC day of t, with one
+// field per counterBucket named by its UTC start time.
+func (c *Redis) dailyKey(userId int64, shortCode string, t time.Time) string {
+	return c.counterKey("clicks", userId, shortCode) + ":buckets:" + t.UTC().Format(time.DateOnly)
+}
+
+// CountClick updates the real-time counters of a link: total clicks, clicks
+// per counterBucket and a HyperLogLog of visitors. Visitors are added as
+// ingest.VisitorHash, so no IP is stored in Redis.
+func (c *Redis) CountClick(ctx context.Context, data types.ClickData) error {
+	daily := c.dailyKey(data.UserId, data.ShortCode, data.ClickedAt)
+	bucket := data.ClickedAt.UTC().Truncate(counterBucket).Format("15:04")
+	visitor := ingest.VisitorHash(data.IP, data.UserAgent)
+
+	pipe := c.rdb.Pipeline()
+	pipe.Incr(ctx, c.counterKey("clicks", data.UserId, data.ShortCode))
+	pipe.HIncrBy(ctx, daily, bucket, 1)
+	pipe.Expire(ctx, daily, dailyCounterTTL)
+	pipe.PFAdd(ctx, c.counterKey("visitors", data.UserId, data.ShortCode), strconv.FormatUint(visitor, 16))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetClickCounters returns the real-time counters of the given links of a
+// user, with Today counting clicks on the date of day in day's location.
+// Links whose counters were never initialised are missing from the result.
+func (c *Redis) GetClickCounters(ctx context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error) {
+	type reply struct {
+		counted  *redis.IntCmd
+		total    *redis.StringCmd
+		buckets  []*redis.MapStringStringCmd
+		visitors *redis.IntCmd
+	}
+	replies := make(map[string]reply, len(shortCodes))
+
+	// The local day spans up to three UTC days.
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	var utcDays []time.Time
+	for d := start.UTC().Truncate(24 * time.Hour); d.Before(end); d = d.Add(24 * time.Hour) {
+		utcDays = append(utcDays, d)
+	}
+
+	pipe := c.rdb.Pipeline()
+	for _, code := range shortCodes {
+		r := reply{
+			counted:  pipe.Exists(ctx, c.counterKey("counted", userId, code)),
+			total:    pipe.Get(ctx, c.counterKey("clicks", userId, code)),
+			visitors: pipe.PFCount(ctx, c.counterKey("visitors", userId, code)),
+		}
+		for _, d := range utcDays {
+			r.buckets = append(r.buckets, pipe.HGetAll(ctx, c.dailyKey(userId, code, d)))
+		}
+		replies[code] = r
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	counters := make(map[string]types.ClickCounters, len(shortCodes))
+	for code, r := range replies {
+		if r.counted.Val() == 0 {
+			continue
+		}
+		total, err := r.total.Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		var today int64
+		for i, buckets := range r.buckets {
+			for field, value := range buckets.Val() {
+				at, err := time.Parse(time.DateOnly+" 15:04", utcDays[i].Format(time.DateOnly)+" "+field)
+				if err != nil || at.Before(start) || !at.Before(end) {
+					continue
+				}
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				today += n
+			}
+		}
+		counters[code] = types.ClickCounters{Total: total, Today: today, Visitors: r.visitors.Val()}
+	}
+	return counters, nil
+}
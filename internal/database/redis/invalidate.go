@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const invalidateChannel = "cache:invalidate"
+
+// PublishInvalidation tells every instance to drop shortCode from its
+// in-process cache.
+func (c *Redis) PublishInvalidation(ctx context.Context, shortCode string) error {
+	return c.rdb.Publish(ctx, c.key(invalidateChannel), shortCode).Err()
+}
+
+// InvalidationSubscription receives the short codes invalidated by any
+// instance, including this one.
+type InvalidationSubscription struct {
+	ps    *redis.PubSub
+	codes chan string
+}
+
+func (c *Redis) SubscribeInvalidations(ctx context.Context) *InvalidationSubscription {
+	s := &InvalidationSubscription{
+		ps:    c.rdb.Subscribe(ctx, c.key(invalidateChannel)),
+		codes: make(chan string, 256),
+	}
+	go s.receive()
+	return s
+}
+
+func (s *InvalidationSubscription) receive() {
+	defer close(s.codes)
+	for msg := range s.ps.Channel() {
+		s.codes <- msg.Payload
+	}
+}
+
+func (s *InvalidationSubscription) Codes() <-chan string {
+	return s.codes
+}
+
+func (s *InvalidationSubscription) Close() error {
+	return s.ps.Close()
+}
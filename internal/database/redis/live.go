@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"linkshortener/internal/types"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const livePrefix = "live:"
+
+func (c *Redis) PublishClick(ctx context.Context, event types.ClickEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Publish(ctx, c.key(livePrefix+event.ShortCode), data).Err()
+}
+
+// CountWatchers returns the number of instances subscribed to the clicks of
+// shortCode. PUBSUB NUMSUB only counts the subscribers of the node that
+// answers it, so a cluster reports errors.ErrUnsupported rather than miss
+// the watchers connected to other nodes.
+func (c *Redis) CountWatchers(ctx context.Context, shortCode string) (int64, error) {
+	if _, ok := c.rdb.(*redis.ClusterClient); ok {
+		return 0, errors.ErrUnsupported
+	}
+	channel := c.key(livePrefix + shortCode)
+	counts, err := c.rdb.PubSubNumSub(ctx, channel).Result()
+	if err != nil {
+		return 0, err
+	}
+	return counts[channel], nil
+}
+
+// ClickSubscription receives click events for the short codes it watches.
+// Codes are added and removed as local subscribers come and go, so an
+// instance only receives the clicks somebody on it is interested in.
+type ClickSubscription struct {
+	ps      *redis.PubSub
+	channel string
+	events  chan types.ClickEvent
+}
+
+func (c *Redis) SubscribeClicks(ctx context.Context) *ClickSubscription {
+	s := &ClickSubscription{
+		ps:      c.rdb.Subscribe(ctx),
+		channel: c.key(livePrefix),
+		events:  make(chan types.ClickEvent, 256),
+	}
+	go s.receive()
+	return s
+}
+
+func (s *ClickSubscription) receive() {
+	defer close(s.events)
+	for msg := range s.ps.Channel() {
+		var event types.ClickEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			slog.Warn("Skipping malformed click event", "channel", msg.Channel, "error", err)
+			continue
+		}
+		s.events <- event
+	}
+}
+
+func (s *ClickSubscription) Watch(ctx context.Context, shortCode string) error {
+	return s.ps.Subscribe(ctx, s.channel+shortCode)
+}
+
+func (s *ClickSubscription) Unwatch(ctx context.Context, shortCode string) error {
+	return s.ps.Unsubscribe(ctx, s.channel+shortCode)
+}
+
+func (s *ClickSubscription) Events() <-chan types.ClickEvent {
+	return s.events
+}
+
+func (s *ClickSubscription) Close() error {
+	return s.ps.Close()
+}
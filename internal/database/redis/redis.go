@@ -2,40 +2,103 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	customerrs "linkshortener/internal/customErrs"
 	"linkshortener/internal/types"
+	"os"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type Redis struct {
-	rdb *redis.Client
+	rdb redis.UniversalClient
+	// prefix namespaces every key and channel, so that several environments
+	// can share one Redis.
+	prefix string
 }
 
 const codePrefix = "code:"
 
-func Connect(url, password string) (*Redis, error) {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     url,
-		Password: password,
-		DB:       0,
-	})
+// Config selects the deployment through go-redis UniversalClient rules: a
+// MasterName means Sentinel (Addrs are the sentinels), several Addrs mean
+// Cluster, and a single address is a standalone server.
+type Config struct {
+	Addrs            []string
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	// DB is ignored by Redis Cluster, which only has database 0.
+	DB        int
+	TLS       bool
+	TLSCAFile string
+	KeyPrefix string
+}
+
+func Connect(cfg Config) (*Redis, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("redis: no address configured")
+	}
+	if cfg.MasterName == "" && len(cfg.Addrs) > 1 && cfg.DB != 0 {
+		return nil, errors.New("redis: cluster mode supports only DB 0")
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		MasterName:       cfg.MasterName,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelPassword: cfg.SentinelPassword,
+		DB:               cfg.DB,
+	}
+	if cfg.TLS {
+		tlsConfig, err := newTLSConfig(cfg.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	rdb := redis.NewUniversalClient(opts)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, err
+	}
+
+	return &Redis{rdb: rdb, prefix: cfg.KeyPrefix}, nil
+}
+
+func newTLSConfig(caFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
 		return nil, err
 	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("redis: no certificates found in %s", caFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
 
-	return &Redis{rdb: rdb}, nil
+func (c *Redis) key(name string) string {
+	return c.prefix + name
 }
 
 func (c *Redis) Get(ctx context.Context, shortCode string) (*types.LinkCache, error) {
-	val, err := c.rdb.Get(ctx, codePrefix+shortCode).Result()
+	val, err := c.rdb.Get(ctx, c.key(codePrefix+shortCode)).Result()
 
 	if errors.Is(err, redis.Nil) {
 		return nil, customerrs.ErrNoFound
@@ -58,23 +121,16 @@ func (c *Redis) Set(ctx context.Context, shortCode string, cache *types.LinkCach
 	if err != nil {
 		return err
 	}
-	return c.rdb.Set(ctx, codePrefix+shortCode, data, expiration).Err()
+	return c.rdb.Set(ctx, c.key(codePrefix+shortCode), data, expiration).Err()
 }
 
 func (c *Redis) Delete(ctx context.Context, shortCode string) error {
-	return c.rdb.Del(ctx, codePrefix+shortCode).Err()
+	return c.rdb.Del(ctx, c.key(codePrefix+shortCode)).Err()
 }
 
+// Update overwrites the cached entry, whether or not the code was cached.
 func (c *Redis) Update(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error {
-	_, err := c.Get(ctx, shortCode)
-	if err != nil {
-		return err
-	}
-	err = c.Delete(ctx, codePrefix+shortCode)
-	if err != nil {
-		return err
-	}
-	return c.Set(ctx, codePrefix+shortCode, cache, expiration)
+	return c.Set(ctx, shortCode, cache, expiration)
 }
 
 func (c *Redis) Close() error {
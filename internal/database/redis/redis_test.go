@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/storagetest"
+	"os"
+	"testing"
+)
+
+func TestConnectRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "no address", cfg: Config{}},
+		{name: "cluster with DB", cfg: Config{Addrs: []string{"a:6379", "b:6379"}, DB: 1}},
+		{name: "missing CA file", cfg: Config{Addrs: []string{"a:6379"}, TLS: true, TLSCAFile: "does-not-exist.pem"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Connect(tt.cfg); err == nil {
+				t.Error("Connect() succeeded, want error")
+			}
+		})
+	}
+}
+
+// TestConformance needs a Redis server in TEST_REDIS_ADDR. Keys are written
+// under the "storagetest:" prefix.
+func TestConformance(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+	r, err := Connect(Config{Addrs: []string{addr}, KeyPrefix: "storagetest:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	storagetest.Cache(t, func(*testing.T) database.Cache { return r })
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"linkshortener/internal/types"
+	"time"
+)
+
+const linkAlertColumns = `l.user_id, l.short_code, a.enabled, a.spike_factor, a.min_clicks, a.drop_alerts, a.muted_until`
+
+func (s *SQLite) GetLinkAlert(ctx context.Context, userId int64, shortCode string) (*types.LinkAlert, error) {
+	query := `
+		SELECT ` + linkAlertColumns + `
+		FROM link_alerts a JOIN links l ON l.id = a.link_id
+		WHERE l.user_id = ? AND l.short_code = ?`
+	var alert types.LinkAlert
+	err := s.db.GetContext(ctx, &alert, query, userId, shortCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		alert = types.DefaultLinkAlert(userId, shortCode)
+		return &alert, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (s *SQLite) ListLinkAlerts(ctx context.Context) ([]types.LinkAlert, error) {
+	query := `SELECT ` + linkAlertColumns + ` FROM link_alerts a JOIN links l ON l.id = a.link_id`
+	var alerts []types.LinkAlert
+	err := s.db.SelectContext(ctx, &alerts, query)
+	return alerts, err
+}
+
+func (s *SQLite) SaveLinkAlert(ctx context.Context, alert types.LinkAlert) error {
+	var mutedUntil *time.Time
+	if alert.MutedUntil != nil {
+		t := utc(*alert.MutedUntil)
+		mutedUntil = &t
+	}
+	query := `
+		INSERT INTO link_alerts (link_id, enabled, spike_factor, min_clicks, drop_alerts, muted_until)
+		SELECT id, ?, ?, ?, ?, ? FROM links WHERE user_id = ? AND short_code = ?
+		ON CONFLICT (link_id) DO UPDATE SET
+			enabled = excluded.enabled,
+			spike_factor = excluded.spike_factor,
+			min_clicks = excluded.min_clicks,
+			drop_alerts = excluded.drop_alerts,
+			muted_until = excluded.muted_until`
+	_, err := s.db.ExecContext(ctx, query,
+		alert.Enabled, alert.SpikeFactor, alert.MinClicks, alert.DropAlerts, mutedUntil,
+		alert.UserId, alert.ShortCode)
+	return err
+}
+
+// ClaimAlert records that an alert for the link is being sent unless one was
+// already sent after cutoff.
+func (s *SQLite) ClaimAlert(ctx context.Context, userId int64, shortCode string, cutoff time.Time) (bool, error) {
+	query := `
+		INSERT INTO link_alerts (link_id, last_alert_at)
+		SELECT id, ? FROM links WHERE user_id = ? AND short_code = ?
+		ON CONFLICT (link_id) DO UPDATE SET last_alert_at = excluded.last_alert_at
+		WHERE link_alerts.last_alert_at IS NULL OR julianday(link_alerts.last_alert_at) < julianday(?)
+		RETURNING link_id`
+	var linkId int64
+	err := s.db.GetContext(ctx, &linkId, query, utc(time.Now()), userId, shortCode, utc(cutoff))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
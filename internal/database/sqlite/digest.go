@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"time"
+)
+
+const digestColumns = `d.user_id, d.period, d.send_hour, d.weekday, d.next_run_at, u.timezone`
+
+func (s *SQLite) GetDigestSubscriptions(ctx context.Context, userId int64) ([]types.DigestSubscription, error) {
+	query := `
+		SELECT ` + digestColumns + `
+		FROM digest_subscriptions d JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = ?
+		ORDER BY d.period`
+	var subs []types.DigestSubscription
+	err := s.db.SelectContext(ctx, &subs, query, userId)
+	return subs, err
+}
+
+func (s *SQLite) SaveDigestSubscription(ctx context.Context, sub types.DigestSubscription) error {
+	query := `
+		INSERT INTO digest_subscriptions (user_id, period, send_hour, weekday, next_run_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, period) DO UPDATE SET
+			send_hour = excluded.send_hour,
+			weekday = excluded.weekday,
+			next_run_at = excluded.next_run_at`
+	_, err := s.db.ExecContext(ctx, query, sub.UserId, sub.Period, sub.Hour, sub.Weekday, utc(sub.NextRunAt))
+	return err
+}
+
+func (s *SQLite) DeleteDigestSubscription(ctx context.Context, userId int64, period string) error {
+	query := `DELETE FROM digest_subscriptions WHERE user_id = ? AND period = ?`
+	_, err := s.db.ExecContext(ctx, query, userId, period)
+	return err
+}
+
+// SetTimezone changes the user's timezone and moves their digest
+// subscriptions to the run computed by next in the new timezone.
+func (s *SQLite) SetTimezone(ctx context.Context, userId int64, timezone string, next func(types.DigestSubscription) time.Time) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET timezone = ? WHERE id = ?`, timezone, userId); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT ` + digestColumns + `
+		FROM digest_subscriptions d JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = ?`
+	var subs []types.DigestSubscription
+	if err := tx.SelectContext(ctx, &subs, query, userId); err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE digest_subscriptions SET next_run_at = ? WHERE user_id = ? AND period = ?`,
+			utc(next(sub)), sub.UserId, sub.Period)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimDueDigests returns the subscriptions due at now and moves each of them
+// to the run computed by next in the same transaction. A SQLite database has
+// a single writer, so no further locking is needed.
+func (s *SQLite) ClaimDueDigests(ctx context.Context, now time.Time, next func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + digestColumns + `
+		FROM digest_subscriptions d JOIN users u ON u.id = d.user_id
+		WHERE julianday(d.next_run_at) <= julianday(?)
+		ORDER BY julianday(d.next_run_at)`
+	var due []types.DigestSubscription
+	if err := tx.SelectContext(ctx, &due, query, utc(now)); err != nil {
+		return nil, err
+	}
+
+	for _, sub := range due {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE digest_subscriptions SET next_run_at = ? WHERE user_id = ? AND period = ?`,
+			utc(next(sub)), sub.UserId, sub.Period)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
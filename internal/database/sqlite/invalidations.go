@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+	"linkshortener/internal/types"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PendingInvalidations returns the oldest invalidations recorded by the
+// triggers of migration 000002.
+func (s *SQLite) PendingInvalidations(ctx context.Context, limit int) ([]types.Invalidation, error) {
+	query := `SELECT id, short_code FROM cache_invalidations ORDER BY id LIMIT ?`
+	var pending []types.Invalidation
+	err := s.db.SelectContext(ctx, &pending, query, limit)
+	return pending, err
+}
+
+func (s *SQLite) AckInvalidations(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`DELETE FROM cache_invalidations WHERE id IN (?)`, ids)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"time"
+)
+
+const linkColumns = `id, user_id, original_link, short_code, created_at, updated_at`
+
+func (s *SQLite) CreateLink(ctx context.Context, userID int64, originalLink string) (int64, error) {
+	var id int64
+	query := `INSERT INTO links (user_id, original_link) VALUES (?, ?) RETURNING id`
+	err := s.db.QueryRowContext(ctx, query, userID, originalLink).Scan(&id)
+	return id, err
+}
+
+// SetShortCode returns customerrs.ErrCodeIsBusy if another link already
+// uses the code.
+func (s *SQLite) SetShortCode(ctx context.Context, id int64, shortCode string) error {
+	query := `UPDATE links SET short_code = ? WHERE id = ?`
+	_, err := s.db.ExecContext(ctx, query, shortCode, id)
+	if isUniqueViolation(err) {
+		return customerrs.ErrCodeIsBusy
+	}
+	return err
+}
+
+func (s *SQLite) GetLink(ctx context.Context, shortCode string) (*types.LinkCache, error) {
+	if shortCode == "" {
+		return nil, customerrs.NotFound(sql.ErrNoRows)
+	}
+	query := `
+		SELECT l.original_link, l.user_id, u.geo_city_opt_out AS city_opt_out,
+			COALESCE(l.cache_ttl_seconds, 0) AS cache_ttl_seconds
+		FROM links l JOIN users u ON u.id = l.user_id
+		WHERE l.short_code = ?`
+	var linkCache types.LinkCache
+	err := s.db.GetContext(ctx, &linkCache, query, shortCode)
+	if err != nil {
+		return nil, customerrs.NotFound(err)
+	}
+	return &linkCache, nil
+}
+
+func (s *SQLite) GetAllLinksByUser(ctx context.Context, userId int64) ([]types.LinkData, error) {
+	query := `SELECT ` + linkColumns + ` FROM links WHERE user_id = ? ORDER BY id`
+	var links []types.LinkData
+	err := s.db.SelectContext(ctx, &links, query, userId)
+	return links, err
+}
+
+func (s *SQLite) UpdateLink(ctx context.Context, userId int64, shortCode, newLink string) error {
+	query := `UPDATE links SET original_link = ?, updated_at = ? WHERE user_id = ? AND short_code = ?`
+	_, err := s.db.ExecContext(ctx, query, newLink, utc(time.Now()), userId, shortCode)
+	return err
+}
+
+// SetLinkCacheTTL sets how long the link stays cached; zero restores the
+// global TTL.
+func (s *SQLite) SetLinkCacheTTL(ctx context.Context, userId int64, shortCode string, ttl time.Duration) error {
+	query := `UPDATE links SET cache_ttl_seconds = NULLIF(?, 0) WHERE user_id = ? AND short_code = ?`
+	res, err := s.db.ExecContext(ctx, query, int64(ttl/time.Second), userId, shortCode)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return customerrs.NotFound(sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (s *SQLite) DeleteLinkByCode(ctx context.Context, userId int64, shortCode string) error {
+	query := `DELETE FROM links WHERE user_id = ? AND short_code = ?`
+	_, err := s.db.ExecContext(ctx, query, userId, shortCode)
+	return err
+}
+
+// DeleteLinkById returns the short code of the deleted link, which is empty
+// if there was no such link or its code had not been set yet.
+func (s *SQLite) DeleteLinkById(ctx context.Context, userId, linkId int64) (string, error) {
+	query := `DELETE FROM links WHERE user_id = ? AND id = ? RETURNING short_code`
+	var shortCode string
+	err := s.db.GetContext(ctx, &shortCode, query, userId, linkId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return shortCode, err
+}
+
+func (s *SQLite) DeleteAllLinksByUser(ctx context.Context, userId int64) ([]string, error) {
+	query := `DELETE FROM links WHERE user_id = ? RETURNING short_code`
+	var codes []string
+	err := s.db.SelectContext(ctx, &codes, query, userId)
+	return codes, err
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS digest_subscriptions;
+DROP TABLE IF EXISTS link_alerts;
+DROP TABLE IF EXISTS links;
+DROP TABLE IF EXISTS users;
\ No newline at end of file
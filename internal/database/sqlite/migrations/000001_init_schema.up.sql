@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL UNIQUE,
+    geo_city_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    api_token_hash TEXT UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    original_link TEXT NOT NULL,
+    short_code TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    cache_ttl_seconds INTEGER CHECK (cache_ttl_seconds > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_links_short_code ON links(short_code) WHERE short_code <> '';
+CREATE INDEX IF NOT EXISTS idx_links_user_id ON links(user_id);
+
+CREATE TABLE IF NOT EXISTS link_alerts (
+    link_id INTEGER PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    spike_factor REAL NOT NULL DEFAULT 3,
+    min_clicks INTEGER NOT NULL DEFAULT 20,
+    drop_alerts BOOLEAN NOT NULL DEFAULT TRUE,
+    muted_until DATETIME,
+    last_alert_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period TEXT NOT NULL CHECK (period IN ('daily', 'weekly')),
+    send_hour INTEGER NOT NULL DEFAULT 9 CHECK (send_hour BETWEEN 0 AND 23),
+    weekday INTEGER NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 1 AND 7),
+    next_run_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_subscriptions_next_run_at ON digest_subscriptions(next_run_at);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS users_cache_invalidation;
+DROP TRIGGER IF EXISTS links_cache_invalidation_delete;
+DROP TRIGGER IF EXISTS links_cache_invalidation_update;
+DROP TRIGGER IF EXISTS links_cache_invalidation_insert;
+DROP TABLE IF EXISTS cache_invalidations;
\ No newline at end of file
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS cache_invalidations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_code TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every change to a published link records its code in the same
+-- transaction, so the cache entry is dropped even if the process dies right
+-- after the commit. The application drains the table.
+CREATE TRIGGER IF NOT EXISTS links_cache_invalidation_insert
+AFTER INSERT ON links WHEN NEW.short_code <> ''
+BEGIN
+    INSERT INTO cache_invalidations (short_code) VALUES (NEW.short_code);
+END;
+
+CREATE TRIGGER IF NOT EXISTS links_cache_invalidation_update
+AFTER UPDATE ON links
+BEGIN
+    INSERT INTO cache_invalidations (short_code) SELECT OLD.short_code WHERE OLD.short_code <> '';
+    INSERT INTO cache_invalidations (short_code) SELECT NEW.short_code WHERE NEW.short_code <> '' AND NEW.short_code <> OLD.short_code;
+END;
+
+CREATE TRIGGER IF NOT EXISTS links_cache_invalidation_delete
+AFTER DELETE ON links WHEN OLD.short_code <> ''
+BEGIN
+    INSERT INTO cache_invalidations (short_code) VALUES (OLD.short_code);
+END;
+
+-- Cached links carry the owner's city opt-out.
+CREATE TRIGGER IF NOT EXISTS users_cache_invalidation
+AFTER UPDATE OF geo_city_opt_out ON users WHEN OLD.geo_city_opt_out IS NOT NEW.geo_city_opt_out
+BEGIN
+    INSERT INTO cache_invalidations (short_code) SELECT short_code FROM links WHERE user_id = NEW.id AND short_code <> '';
+END;
\ No newline at end of file
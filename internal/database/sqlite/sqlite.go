@@ -0,0 +1,157 @@
+// Package sqlite implements database.SQL on SQLite for small self-hosted
+// deployments. It uses a pure-Go driver, so no cgo is needed.
+package sqlite
+
+import (
+	"context"
+	"embed"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	msqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var migrationsSQLiteFS embed.FS
+
+// Scheme prefixes the DSN of a SQLite database, e.g. "sqlite://links.db",
+// "sqlite:///var/lib/linkshortener/links.db" or "sqlite://:memory:".
+const Scheme = "sqlite://"
+
+// connParams are added to every DSN. Times are stored as sortable text in
+// UTC, see utc.
+const connParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+
+type SQLite struct {
+	db *sqlx.DB
+}
+
+// Connect opens the database at dsn, which must start with Scheme, and
+// applies the migrations. SQLite allows a single writer, so the pool is
+// limited to one connection; this also keeps ":memory:" databases from
+// splitting into one database per connection.
+func Connect(ctx context.Context, dsn string) (*SQLite, error) {
+	path, ok := strings.CutPrefix(dsn, Scheme)
+	if !ok || path == "" {
+		return nil, errors.New("sqlite: DSN must look like " + Scheme + "path/to/file.db")
+	}
+	if strings.Contains(path, "?") {
+		path += "&" + connParams
+	} else {
+		path += "?" + connParams
+	}
+
+	db, err := sqlx.ConnectContext(ctx, "sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{db: db}
+
+	if err := s.runMigrations(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *SQLite) runMigrations() error {
+	d, err := iofs.New(migrationsSQLiteFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	driver, err := migratesqlite.WithInstance(s.db.DB, &migratesqlite.Config{})
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance(
+		"iofs", d,
+		"sqlite", driver)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	slog.Info("Database migrations applied successfully")
+	return nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// utc normalizes times before they are written or compared. Times are
+// stored as text, so values in different zones would not sort correctly.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *msqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// CreateUser registers a user with the given timezone. The timezone of an
+// existing user is left untouched.
+func (s *SQLite) CreateUser(ctx context.Context, telegramID int64, timezone string) error {
+	query := `INSERT INTO users (telegram_id, timezone) VALUES (?, ?) ON CONFLICT (telegram_id) DO NOTHING`
+	_, err := s.db.ExecContext(ctx, query, telegramID, timezone)
+	return err
+}
+
+func (s *SQLite) GetUserIDByTelegramID(ctx context.Context, telegramID int64) (int64, error) {
+	var id int64
+	err := s.db.GetContext(ctx, &id, "SELECT id FROM users WHERE telegram_id = ?", telegramID)
+	return id, customerrs.NotFound(err)
+}
+
+func (s *SQLite) GetUserIDByAPITokenHash(ctx context.Context, tokenHash string) (int64, error) {
+	var id int64
+	err := s.db.GetContext(ctx, &id, "SELECT id FROM users WHERE api_token_hash = ?", tokenHash)
+	return id, customerrs.NotFound(err)
+}
+
+func (s *SQLite) GetTelegramIDByUserID(ctx context.Context, userId int64) (int64, error) {
+	var telegramID int64
+	err := s.db.GetContext(ctx, &telegramID, "SELECT telegram_id FROM users WHERE id = ?", userId)
+	return telegramID, customerrs.NotFound(err)
+}
+
+func (s *SQLite) SetAPITokenHash(ctx context.Context, userId int64, tokenHash string) error {
+	query := `UPDATE users SET api_token_hash = ? WHERE id = ?`
+	_, err := s.db.ExecContext(ctx, query, tokenHash, userId)
+	return err
+}
+
+func (s *SQLite) GetUserSettings(ctx context.Context, userId int64) (*types.UserSettings, error) {
+	query := `SELECT geo_city_opt_out AS city_opt_out, timezone FROM users WHERE id = ?`
+	var settings types.UserSettings
+	err := s.db.GetContext(ctx, &settings, query, userId)
+	if err != nil {
+		return nil, customerrs.NotFound(err)
+	}
+	return &settings, nil
+}
+
+func (s *SQLite) SetCityOptOut(ctx context.Context, userId int64, optOut bool) error {
+	query := `UPDATE users SET geo_city_opt_out = ? WHERE id = ?`
+	_, err := s.db.ExecContext(ctx, query, optOut, userId)
+	return err
+}
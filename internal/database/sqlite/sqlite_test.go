@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"context"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/storagetest"
+	"path/filepath"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.SQL(t, func(t *testing.T) database.SQL {
+		s, err := Connect(context.Background(), Scheme+filepath.Join(t.TempDir(), "links.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func TestConnectInMemory(t *testing.T) {
+	s, err := Connect(context.Background(), Scheme+":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.CreateUser(context.Background(), 1, "UTC"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,480 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/ingest"
+	"linkshortener/internal/types"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// waitTimeout bounds how long the analytics tests wait for pushed clicks to
+// become visible, since most stores write them in batches.
+const waitTimeout = 10 * time.Second
+
+// Analytics runs the conformance tests for database.Analytics. open must
+// return a started store that enriches clicks without a GeoIP resolver.
+// Stores that write asynchronously should flush often, see waitTimeout.
+func Analytics(t *testing.T, open func(t *testing.T) database.Analytics) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, a database.Analytics)
+	}{
+		{"Enrichment", testAnalyticsEnrichment},
+		{"Ownership", testAnalyticsOwnership},
+		{"Range", testAnalyticsRange},
+		{"UnalignedRange", testAnalyticsUnalignedRange},
+		{"Buckets", testAnalyticsBuckets},
+		{"TopStats", testAnalyticsTopStats},
+		{"TrafficSources", testAnalyticsTrafficSources},
+		{"UniqueVisitors", testAnalyticsUniqueVisitors},
+		{"Export", testAnalyticsExport},
+		{"LinkRates", testAnalyticsLinkRates},
+		{"HotCodes", testAnalyticsHotCodes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// analyticsDay is the UTC midnight two days ago. The tests click around it,
+// so that the clicks are in the past but within any retention period.
+func analyticsDay() time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2)
+}
+
+// push pushes clicks and waits until all of them are visible in
+// GetAllAnalytic of their users.
+func push(t *testing.T, a database.Analytics, clicks ...types.ClickData) {
+	t.Helper()
+	perUser := make(map[int64]int)
+	for _, c := range clicks {
+		a.PushClick(c)
+		perUser[c.UserId]++
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(waitTimeout)
+	for userId, want := range perUser {
+		for {
+			got, err := a.GetAllAnalytic(ctx, userId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) >= want {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("user %d has %d clicks after %v, want %d", userId, len(got), waitTimeout, want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+}
+
+func testAnalyticsEnrichment(t *testing.T, a database.Analytics) {
+	data := types.ClickData{
+		UserId:      uniq(),
+		ShortCode:   uniqCode(),
+		IP:          "192.0.2.10",
+		UserAgent:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+		Referer:     "https://t.me/some_channel",
+		UTMSource:   "telegram",
+		UTMMedium:   "social",
+		UTMCampaign: "launch",
+		UTMTerm:     "term",
+		UTMContent:  "content",
+		ClickedAt:   analyticsDay().Add(9*time.Hour + 123*time.Millisecond),
+	}
+	push(t, a, data)
+
+	got, err := a.GetAllAnalytic(context.Background(), data.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ingest.Enrich(nil, data).Analytic()
+	if len(got) != 1 {
+		t.Fatalf("GetAllAnalytic = %+v, want one click", got)
+	}
+	if !got[0].ClickedAt.Equal(want.ClickedAt) {
+		t.Errorf("ClickedAt = %v, want %v", got[0].ClickedAt, want.ClickedAt)
+	}
+	got[0].ClickedAt = want.ClickedAt
+	if got[0] != want {
+		t.Errorf("GetAllAnalytic = %+v, want %+v", got[0], want)
+	}
+}
+
+func testAnalyticsOwnership(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	day := analyticsDay()
+	owner, other := uniq(), uniq()
+	code, otherCode := uniqCode(), uniqCode()
+	push(t, a,
+		types.ClickData{UserId: owner, ShortCode: code, ClickedAt: day},
+		types.ClickData{UserId: owner, ShortCode: otherCode, ClickedAt: day},
+	)
+
+	if got, err := a.GetAnalyticByCode(ctx, code, owner); err != nil || len(got) != 1 || got[0].ShortCode != code {
+		t.Errorf("GetAnalyticByCode of the owner = %+v, %v; want one click of %s", got, err, code)
+	}
+	if got, err := a.GetAllAnalytic(ctx, other); err != nil || len(got) != 0 {
+		t.Errorf("GetAllAnalytic of another user = %+v, %v; want none", got, err)
+	}
+	if got, err := a.GetAnalyticByCode(ctx, code, other); err != nil || len(got) != 0 {
+		t.Errorf("GetAnalyticByCode of another user = %+v, %v; want none", got, err)
+	}
+
+	filter := types.AnalyticsFilter{UserId: other, ShortCode: code, From: day.Add(-time.Hour), To: day.Add(time.Hour)}
+	if got, err := a.GetDailyClicks(ctx, filter); err != nil || len(got) != 0 {
+		t.Errorf("GetDailyClicks of another user = %+v, %v; want none", got, err)
+	}
+	if got, err := a.GetTopLinks(ctx, filter, 10); err != nil || len(got) != 0 {
+		t.Errorf("GetTopLinks of another user = %+v, %v; want none", got, err)
+	}
+	if got, err := a.GetUniqueVisitors(ctx, filter); err != nil || got != 0 {
+		t.Errorf("GetUniqueVisitors of another user = %d, %v; want 0", got, err)
+	}
+}
+
+func testAnalyticsRange(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	day := analyticsDay()
+	userId, code := uniq(), uniqCode()
+	push(t, a,
+		types.ClickData{UserId: userId, ShortCode: code, ClickedAt: day.Add(-time.Second)},
+		types.ClickData{UserId: userId, ShortCode: code, ClickedAt: day},
+		types.ClickData{UserId: userId, ShortCode: code, ClickedAt: day.Add(time.Hour)},
+		types.ClickData{UserId: userId, ShortCode: code, ClickedAt: day.Add(2 * time.Hour)},
+	)
+
+	// The range is half-open: the click at From is in, the one at To is not.
+	filter := types.AnalyticsFilter{UserId: userId, From: day, To: day.Add(2 * time.Hour)}
+	got, err := a.GetTopLinks(ctx, filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.CountStat{{Key: code, Clicks: 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTopLinks = %+v, want %+v", got, want)
+	}
+}
+
+// testAnalyticsUnalignedRange checks that a range starting or ending inside
+// an hour or day only counts the clicks within it, as stores that keep
+// hourly or daily rollups must not round the range to whole buckets.
+func testAnalyticsUnalignedRange(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	day := analyticsDay()
+	userId, code := uniq(), uniqCode()
+	push(t, a,
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.1", ClickedAt: day.Add(time.Hour)},
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.2", ClickedAt: day.Add(4*time.Hour + 10*time.Minute)},
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.3", ClickedAt: day.Add(4*time.Hour + 40*time.Minute)},
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.4", ClickedAt: day.Add(30 * time.Hour)},
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.5", ClickedAt: day.Add(30*time.Hour + 50*time.Minute)},
+	)
+
+	// Only the clicks at 4:40 and at 30:00 are in the range.
+	filter := types.AnalyticsFilter{UserId: userId, From: day.Add(4*time.Hour + 30*time.Minute), To: day.Add(30*time.Hour + 30*time.Minute)}
+	if got, err := a.GetTotalClicks(ctx, filter); err != nil || got != 2 {
+		t.Errorf("GetTotalClicks = %d, %v; want 2", got, err)
+	}
+	if got, err := a.GetUniqueVisitors(ctx, filter); err != nil || got != 2 {
+		t.Errorf("GetUniqueVisitors = %d, %v; want 2", got, err)
+	}
+	if got, err := a.GetTopLinks(ctx, filter, 10); err != nil || !reflect.DeepEqual(got, []types.CountStat{{Key: code, Clicks: 2}}) {
+		t.Errorf("GetTopLinks = %+v, %v; want 2 clicks of %s", got, err, code)
+	}
+	if got, err := a.GetTopCountries(ctx, filter, 10); err != nil || len(got) != 1 || got[0].Clicks != 2 {
+		t.Errorf("GetTopCountries = %+v, %v; want one country with 2 clicks", got, err)
+	}
+
+	daily, err := a.GetDailyClicks(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, d := range daily {
+		total += d.Clicks
+	}
+	if len(daily) != 2 || total != 2 {
+		t.Errorf("GetDailyClicks = %+v, want one click on each of two days", daily)
+	}
+
+	hourly, err := a.GetHourlyClicks(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total = 0
+	for _, h := range hourly {
+		total += h.Clicks
+	}
+	if total != 2 {
+		t.Errorf("GetHourlyClicks = %+v, want 2 clicks", hourly)
+	}
+}
+
+func testAnalyticsBuckets(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	loc, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skip(err)
+	}
+	day := analyticsDay()
+	userId := uniq()
+	// The late click falls on the next day in Kyiv, which is ahead of UTC.
+	early, late := day.Add(10*time.Hour), day.Add(22*time.Hour+30*time.Minute)
+	push(t, a,
+		types.ClickData{UserId: userId, ShortCode: uniqCode(), ClickedAt: early},
+		types.ClickData{UserId: userId, ShortCode: uniqCode(), ClickedAt: late},
+	)
+	filter := types.AnalyticsFilter{UserId: userId, From: day, To: day.AddDate(0, 0, 1)}
+
+	for _, tz := range []string{"", "Europe/Kyiv"} {
+		in := time.UTC
+		if tz != "" {
+			in = loc
+		}
+		filter.Timezone = tz
+
+		var wantDaily []string
+		var wantHourly []types.HourlyClicks
+		for _, ts := range []time.Time{early, late} {
+			local := ts.In(in)
+			if d := local.Format(time.DateOnly); !slices.Contains(wantDaily, d) {
+				wantDaily = append(wantDaily, d)
+			}
+			wantHourly = append(wantHourly, types.HourlyClicks{Weekday: (int(local.Weekday())+6)%7 + 1, Hour: local.Hour(), Clicks: 1})
+		}
+		slices.SortFunc(wantHourly, func(a, b types.HourlyClicks) int {
+			return (a.Weekday*24 + a.Hour) - (b.Weekday*24 + b.Hour)
+		})
+
+		daily, err := a.GetDailyClicks(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotDaily []string
+		var total int64
+		for _, d := range daily {
+			gotDaily = append(gotDaily, d.Day.Format(time.DateOnly))
+			total += d.Clicks
+		}
+		if !slices.Equal(gotDaily, wantDaily) || total != 2 {
+			t.Errorf("timezone %q: GetDailyClicks = %+v, want days %v with 2 clicks", tz, daily, wantDaily)
+		}
+
+		hourly, err := a.GetHourlyClicks(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(hourly, wantHourly) {
+			t.Errorf("timezone %q: GetHourlyClicks = %+v, want %+v", tz, hourly, wantHourly)
+		}
+	}
+}
+
+func testAnalyticsTopStats(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	day := analyticsDay()
+	userId := uniq()
+	codes := []string{uniqCode(), uniqCode(), uniqCode()}
+	var clicks []types.ClickData
+	// codes[0] gets one click, codes[1] two and codes[2] three.
+	for i, code := range codes {
+		for range i + 1 {
+			clicks = append(clicks, types.ClickData{UserId: userId, ShortCode: code, ClickedAt: day})
+		}
+	}
+	push(t, a, clicks...)
+	filter := types.AnalyticsFilter{UserId: userId, From: day, To: day.Add(time.Hour)}
+
+	links, err := a.GetTopLinks(ctx, filter, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.CountStat{{Key: codes[2], Clicks: 3}, {Key: codes[1], Clicks: 2}}; !reflect.DeepEqual(links, want) {
+		t.Errorf("GetTopLinks = %+v, want %+v", links, want)
+	}
+
+	filter.ShortCode = codes[1]
+	countries, err := a.GetTopCountries(ctx, filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(countries) != 1 || countries[0].Clicks != 2 {
+		t.Errorf("GetTopCountries of %s = %+v, want one country with 2 clicks", codes[1], countries)
+	}
+}
+
+func testAnalyticsTrafficSources(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	day := analyticsDay()
+	userId, code := uniq(), uniqCode()
+	clicks := []types.ClickData{
+		{UserId: userId, ShortCode: code, Referer: "https://t.me/some_channel", ClickedAt: day},
+		{UserId: userId, ShortCode: code, Referer: "https://t.me/other_channel", ClickedAt: day},
+		{UserId: userId, ShortCode: code, UTMSource: "newsletter", ClickedAt: day},
+		{UserId: userId, ShortCode: code, ClickedAt: day},
+	}
+	push(t, a, clicks...)
+	filter := types.AnalyticsFilter{UserId: userId, ShortCode: code, From: day, To: day.Add(time.Hour)}
+
+	total, err := a.GetTotalClicks(ctx, filter)
+	if err != nil || total != int64(len(clicks)) {
+		t.Errorf("GetTotalClicks = %d, %v; want %d", total, err, len(clicks))
+	}
+
+	// Clicks without a referer are attributed to their UTM source, and
+	// clicks with neither are left out.
+	sources, err := a.GetTopSources(ctx, filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.CountStat{{Key: "t.me", Clicks: 2}, {Key: "newsletter", Clicks: 1}}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("GetTopSources = %+v, want %+v", sources, want)
+	}
+
+	wantChannels := make(map[string]int64)
+	for _, c := range clicks {
+		wantChannels[ingest.Enrich(nil, c).Channel]++
+	}
+	channels, err := a.GetTopChannels(ctx, filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotChannels := make(map[string]int64)
+	for _, c := range channels {
+		gotChannels[c.Key] = c.Clicks
+	}
+	if !reflect.DeepEqual(gotChannels, wantChannels) {
+		t.Errorf("GetTopChannels = %+v, want %v", channels, wantChannels)
+	}
+
+	cities, err := a.GetTopCities(ctx, filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cities) != 1 || cities[0].Clicks != int64(len(clicks)) {
+		t.Errorf("GetTopCities = %+v, want one city with %d clicks", cities, len(clicks))
+	}
+}
+
+func testAnalyticsUniqueVisitors(t *testing.T, a database.Analytics) {
+	day := analyticsDay()
+	userId, code := uniq(), uniqCode()
+	push(t, a,
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day},
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day.Add(time.Minute)},
+		types.ClickData{UserId: userId, ShortCode: code, IP: "192.0.2.1", UserAgent: "ua-2", ClickedAt: day.Add(2 * time.Minute)},
+	)
+
+	got, err := a.GetUniqueVisitors(context.Background(), types.AnalyticsFilter{UserId: userId, From: day, To: day.Add(time.Hour)})
+	if err != nil || got != 2 {
+		t.Errorf("GetUniqueVisitors = %d, %v; want 2", got, err)
+	}
+}
+
+func testAnalyticsExport(t *testing.T, a database.Analytics) {
+	ctx := context.Background()
+	day := analyticsDay()
+	userId, code := uniq(), uniqCode()
+	offsets := []time.Duration{3, 1, 4, 0, 2}
+	var clicks []types.ClickData
+	for _, o := range offsets {
+		clicks = append(clicks, types.ClickData{UserId: userId, ShortCode: code, ClickedAt: day.Add(o * time.Minute)})
+	}
+	push(t, a, clicks...)
+	filter := types.AnalyticsFilter{UserId: userId, From: day, To: day.Add(time.Hour)}
+
+	var got []time.Time
+	err := a.ExportClicks(ctx, filter, 3, func(c types.Analytic) error {
+		got = append(got, c.ClickedAt)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{day, day.Add(time.Minute), day.Add(2 * time.Minute)}
+	if !slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Errorf("ExportClicks = %v, want the first 3 clicks in order %v", got, want)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = a.ExportClicks(ctx, filter, 10, func(types.Analytic) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("ExportClicks with a failing callback = %v after %d calls, want %v after 1", err, calls, errStop)
+	}
+}
+
+func testAnalyticsLinkRates(t *testing.T, a database.Analytics) {
+	day := analyticsDay()
+	userId, otherUser, code := uniq(), uniq(), uniqCode()
+	baselineFrom, windowFrom, to := day, day.Add(2*time.Hour), day.Add(3*time.Hour)
+	var clicks []types.ClickData
+	for _, at := range []time.Time{
+		baselineFrom.Add(-time.Minute), // before the baseline
+		baselineFrom, baselineFrom.Add(time.Hour),
+		windowFrom, windowFrom.Add(time.Minute), windowFrom.Add(2 * time.Minute),
+		to, // after the window
+	} {
+		clicks = append(clicks, types.ClickData{UserId: userId, ShortCode: code, ClickedAt: at})
+	}
+	clicks = append(clicks, types.ClickData{UserId: otherUser, ShortCode: code, ClickedAt: windowFrom})
+	push(t, a, clicks...)
+
+	rates, err := a.GetLinkRates(context.Background(), baselineFrom, windowFrom, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []types.LinkRate
+	for _, r := range rates {
+		if r.ShortCode == code {
+			got = append(got, r)
+		}
+	}
+	slices.SortFunc(got, func(a, b types.LinkRate) int { return int(a.UserId - b.UserId) })
+	want := []types.LinkRate{
+		{UserId: userId, ShortCode: code, Recent: 3, Baseline: 2},
+		{UserId: otherUser, ShortCode: code, Recent: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLinkRates of %s = %+v, want %+v", code, got, want)
+	}
+}
+
+func testAnalyticsHotCodes(t *testing.T, a database.Analytics) {
+	since := analyticsDay()
+	userId := uniq()
+	hot, warm, cold := uniqCode(), uniqCode(), uniqCode()
+	push(t, a,
+		types.ClickData{UserId: userId, ShortCode: hot, ClickedAt: since},
+		types.ClickData{UserId: userId, ShortCode: hot, ClickedAt: since.Add(time.Hour)},
+		types.ClickData{UserId: userId, ShortCode: warm, ClickedAt: since.Add(time.Hour)},
+		types.ClickData{UserId: userId, ShortCode: cold, ClickedAt: since.Add(-time.Second)},
+	)
+
+	// Other data may share the store, so only the relative order of these
+	// codes is checked.
+	codes, err := a.GetHotCodes(context.Background(), since, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hotAt, warmAt := slices.Index(codes, hot), slices.Index(codes, warm)
+	if hotAt < 0 || warmAt < 0 || hotAt > warmAt {
+		t.Errorf("GetHotCodes has %s at %d and %s at %d, want both with the first ahead", hot, hotAt, warm, warmAt)
+	}
+	if slices.Contains(codes, cold) {
+		t.Errorf("GetHotCodes includes %s, which was only clicked before since", cold)
+	}
+}
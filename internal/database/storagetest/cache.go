@@ -0,0 +1,185 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/database"
+	"linkshortener/internal/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Cache runs the conformance tests for database.Cache. A miss, an expired
+// and a deleted entry all return customerrs.ErrNoFound.
+func Cache(t *testing.T, open func(t *testing.T) database.Cache) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, cache database.Cache)
+	}{
+		{"Miss", testCacheMiss},
+		{"SetGet", testCacheSetGet},
+		{"UpdateDelete", testCacheUpdateDelete},
+		{"Expiry", testCacheExpiry},
+		{"ClickCounters", testClickCounters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+func wantNoFound(t *testing.T, cache database.Cache, code string) {
+	t.Helper()
+	if got, err := cache.Get(context.Background(), code); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("Get(%q) = %+v, %v; want ErrNoFound", code, got, err)
+	}
+}
+
+func testCacheMiss(t *testing.T, cache database.Cache) {
+	wantNoFound(t, cache, uniqCode())
+}
+
+func testCacheSetGet(t *testing.T, cache database.Cache) {
+	ctx := context.Background()
+	for _, want := range []types.LinkCache{
+		{OriginalLink: "https://example.com/cached", UserID: uniq(), CityOptOut: true},
+		{NotFound: true},
+	} {
+		code := uniqCode()
+		if err := cache.Set(ctx, code, &want, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		got, err := cache.Get(ctx, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *got != want {
+			t.Errorf("Get(%q) = %+v, want %+v", code, *got, want)
+		}
+	}
+}
+
+func testCacheUpdateDelete(t *testing.T, cache database.Cache) {
+	ctx := context.Background()
+	code := uniqCode()
+	userId := uniq()
+	if err := cache.Set(ctx, code, &types.LinkCache{OriginalLink: "https://example.com/old", UserID: userId}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	want := types.LinkCache{OriginalLink: "https://example.com/new", UserID: userId}
+	if err := cache.Update(ctx, code, &want, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cache.Get(ctx, code); err != nil || *got != want {
+		t.Errorf("Get after Update = %+v, %v; want %+v", got, err, want)
+	}
+
+	if err := cache.Delete(ctx, code); err != nil {
+		t.Fatal(err)
+	}
+	wantNoFound(t, cache, code)
+	if err := cache.Delete(ctx, code); err != nil {
+		t.Errorf("Delete of a missing code: %v", err)
+	}
+}
+
+func testCacheExpiry(t *testing.T, cache database.Cache) {
+	ctx := context.Background()
+	code := uniqCode()
+	if err := cache.Set(ctx, code, &types.LinkCache{OriginalLink: "https://example.com/short-lived"}, 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, code); err != nil {
+		t.Fatalf("Get before expiry: %v", err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	wantNoFound(t, cache, code)
+}
+
+func testClickCounters(t *testing.T, cache database.Cache) {
+	ctx := context.Background()
+	day := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	userId, otherUser := uniq(), uniq()
+	a, b, never, uncounted, late := uniqCode(), uniqCode(), uniqCode(), uniqCode(), uniqCode()
+	// Local midnight in India is 18:30 UTC the day before.
+	india := time.FixedZone("IST", 5*60*60+30*60)
+
+	for _, link := range []struct {
+		userId int64
+		code   string
+	}{{userId, a}, {userId, b}, {userId, never}, {userId, late}, {otherUser, a}} {
+		if err := cache.InitClickCounters(ctx, link.userId, link.code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []types.ClickData{
+		{UserId: userId, ShortCode: a, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day},
+		{UserId: userId, ShortCode: a, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day.Add(time.Hour)},
+		{UserId: userId, ShortCode: a, IP: "192.0.2.2", UserAgent: "ua-1", ClickedAt: day.Add(-24 * time.Hour)},
+		{UserId: userId, ShortCode: b, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day},
+		// The same code of another user is counted separately.
+		{UserId: otherUser, ShortCode: a, IP: "192.0.2.3", UserAgent: "ua-2", ClickedAt: day},
+		// Today in India, but yesterday in UTC.
+		{UserId: userId, ShortCode: late, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day.Add(-13 * time.Hour)},
+		{UserId: userId, ShortCode: late, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day.Add(-17*time.Hour - 30*time.Minute)},
+		// Yesterday in both.
+		{UserId: userId, ShortCode: late, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day.Add(-17*time.Hour - 45*time.Minute)},
+		// Today in UTC, but tomorrow in India.
+		{UserId: userId, ShortCode: late, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day.Add(11 * time.Hour)},
+		// Counters that were never initialised are not reported.
+		{UserId: userId, ShortCode: uncounted, IP: "192.0.2.1", UserAgent: "ua-1", ClickedAt: day},
+	} {
+		if err := cache.CountClick(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := cache.GetClickCounters(ctx, userId, []string{a, b, never, uncounted}, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]types.ClickCounters{
+		a:     {Total: 3, Today: 2, Visitors: 2},
+		b:     {Total: 1, Today: 1, Visitors: 1},
+		never: {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetClickCounters = %+v, want %+v", got, want)
+	}
+
+	for _, tt := range []struct {
+		day   time.Time
+		today int64
+	}{{day, 1}, {day.In(india), 2}} {
+		got, err := cache.GetClickCounters(ctx, userId, []string{late}, tt.day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]types.ClickCounters{late: {Total: 4, Today: tt.today, Visitors: 1}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetClickCounters in %s = %+v, want %+v", tt.day.Location(), got, want)
+		}
+	}
+
+	got, err = cache.GetClickCounters(ctx, otherUser, []string{a, b}, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]types.ClickCounters{a: {Total: 1, Today: 1, Visitors: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetClickCounters of another user = %+v, want %+v", got, want)
+	}
+
+	if err := cache.DeleteClickCounters(ctx, userId, a, never); err != nil {
+		t.Fatal(err)
+	}
+	got, err = cache.GetClickCounters(ctx, userId, []string{a, b, never}, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]types.ClickCounters{b: {Total: 1, Today: 1, Visitors: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetClickCounters after DeleteClickCounters = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,577 @@
+// Package storagetest holds conformance tests that every implementation of
+// the storage interfaces in package database must pass. Implementations call
+// them from their own tests.
+//
+// The tests may share one database: every test registers its own users and
+// codes and only looks at those, so they also run against a database with
+// other data in it.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/database"
+	"linkshortener/internal/types"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var seq atomic.Int64
+
+func init() {
+	seq.Store(time.Now().UnixNano() / 1000)
+}
+
+// uniq returns a number no other test of this process has used, and which
+// is unlikely to have been used by an earlier run against the same database.
+func uniq() int64 {
+	return seq.Add(1)
+}
+
+func uniqCode() string {
+	return fmt.Sprintf("t%x", uniq())
+}
+
+// SQL runs the conformance tests for database.SQL. open is called once per
+// test and may return the same repository each time. Missing users and
+// links return customerrs.ErrNoFound, which the service and the bot check
+// for.
+func SQL(t *testing.T, open func(t *testing.T) database.SQL) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo database.SQL)
+	}{
+		{"Users", testUsers},
+		{"UserNotFound", testUserNotFound},
+		{"Links", testLinks},
+		{"LinkNotFound", testLinkNotFound},
+		{"ShortCodeCollision", testShortCodeCollision},
+		{"LinkOwnership", testLinkOwnership},
+		{"DeleteLinks", testDeleteLinks},
+		{"LinkCacheTTL", testLinkCacheTTL},
+		{"Invalidations", testInvalidations},
+		{"Alerts", testAlerts},
+		{"Digests", testDigests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+func newUser(t *testing.T, repo database.UsersRepo, timezone string) int64 {
+	t.Helper()
+	ctx := context.Background()
+	telegramID := uniq()
+	if err := repo.CreateUser(ctx, telegramID, timezone); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	id, err := repo.GetUserIDByTelegramID(ctx, telegramID)
+	if err != nil {
+		t.Fatalf("GetUserIDByTelegramID: %v", err)
+	}
+	return id
+}
+
+func newLink(t *testing.T, repo database.LinksRepo, userId int64, originalLink string) (int64, string) {
+	t.Helper()
+	ctx := context.Background()
+	id, err := repo.CreateLink(ctx, userId, originalLink)
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	code := uniqCode()
+	if err := repo.SetShortCode(ctx, id, code); err != nil {
+		t.Fatalf("SetShortCode: %v", err)
+	}
+	return id, code
+}
+
+func testUsers(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	telegramID := uniq()
+	if err := repo.CreateUser(ctx, telegramID, "Europe/Kyiv"); err != nil {
+		t.Fatal(err)
+	}
+	// Registering again keeps the user and their timezone.
+	if err := repo.CreateUser(ctx, telegramID, "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	userId, err := repo.GetUserIDByTelegramID(ctx, telegramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetTelegramIDByUserID(ctx, userId); err != nil || got != telegramID {
+		t.Errorf("GetTelegramIDByUserID = %d, %v; want %d", got, err, telegramID)
+	}
+
+	settings, err := repo.GetUserSettings(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.UserSettings{Timezone: "Europe/Kyiv"}); *settings != want {
+		t.Errorf("settings = %+v, want %+v", *settings, want)
+	}
+
+	if err := repo.SetCityOptOut(ctx, userId, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetTimezone(ctx, userId, "America/New_York", func(types.DigestSubscription) time.Time { return time.Now() }); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = repo.GetUserSettings(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.UserSettings{CityOptOut: true, Timezone: "America/New_York"}); *settings != want {
+		t.Errorf("settings = %+v, want %+v", *settings, want)
+	}
+
+	hash := uniqCode()
+	if err := repo.SetAPITokenHash(ctx, userId, hash); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetUserIDByAPITokenHash(ctx, hash); err != nil || got != userId {
+		t.Errorf("GetUserIDByAPITokenHash = %d, %v; want %d", got, err, userId)
+	}
+	if err := repo.SetAPITokenHash(ctx, userId, uniqCode()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetUserIDByAPITokenHash(ctx, hash); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("old token: err = %v, want customerrs.ErrNoFound", err)
+	}
+}
+
+func testUserNotFound(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	if _, err := repo.GetUserIDByTelegramID(ctx, uniq()); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetUserIDByTelegramID: err = %v, want customerrs.ErrNoFound", err)
+	}
+	if _, err := repo.GetUserIDByAPITokenHash(ctx, uniqCode()); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetUserIDByAPITokenHash: err = %v, want customerrs.ErrNoFound", err)
+	}
+	if _, err := repo.GetTelegramIDByUserID(ctx, -uniq()); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetTelegramIDByUserID: err = %v, want customerrs.ErrNoFound", err)
+	}
+	if _, err := repo.GetUserSettings(ctx, -uniq()); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetUserSettings: err = %v, want customerrs.ErrNoFound", err)
+	}
+}
+
+func testLinks(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	userId := newUser(t, repo, "UTC")
+
+	// Links are created before their code is known, so several links may
+	// be waiting for a code at the same time.
+	firstId, err := repo.CreateLink(ctx, userId, "https://example.com/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondId, err := repo.CreateLink(ctx, userId, "https://example.com/2")
+	if err != nil {
+		t.Fatalf("second link without a code: %v", err)
+	}
+	if secondId <= firstId {
+		t.Errorf("link ids are not increasing: %d then %d", firstId, secondId)
+	}
+	firstCode, secondCode := uniqCode(), uniqCode()
+	if err := repo.SetShortCode(ctx, secondId, secondCode); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetShortCode(ctx, firstId, firstCode); err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := repo.GetLink(ctx, firstCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.LinkCache{OriginalLink: "https://example.com/1", UserID: userId}); *link != want {
+		t.Errorf("GetLink = %+v, want %+v", *link, want)
+	}
+
+	if err := repo.SetCityOptOut(ctx, userId, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateLink(ctx, userId, firstCode, "https://example.com/updated"); err != nil {
+		t.Fatal(err)
+	}
+	link, err = repo.GetLink(ctx, firstCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.LinkCache{OriginalLink: "https://example.com/updated", UserID: userId, CityOptOut: true}); *link != want {
+		t.Errorf("GetLink after update = %+v, want %+v", *link, want)
+	}
+
+	links, err := repo.GetAllLinksByUser(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range links {
+		if l.UserId != userId {
+			t.Errorf("link %d belongs to user %d, want %d", l.Id, l.UserId, userId)
+		}
+		if l.CreatedAt.IsZero() {
+			t.Errorf("link %d has no creation time", l.Id)
+		}
+		got = append(got, l.ShortCode)
+	}
+	if want := []string{firstCode, secondCode}; !slices.Equal(got, want) {
+		t.Errorf("GetAllLinksByUser codes = %v, want %v in creation order", got, want)
+	}
+}
+
+func testLinkNotFound(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	if _, err := repo.GetLink(ctx, uniqCode()); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetLink: err = %v, want customerrs.ErrNoFound", err)
+	}
+	userId := newUser(t, repo, "UTC")
+	if _, err := repo.CreateLink(ctx, userId, "https://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	// A link whose code is not set yet must not be found by the empty code.
+	if _, err := repo.GetLink(ctx, ""); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetLink(\"\"): err = %v, want customerrs.ErrNoFound", err)
+	}
+	links, err := repo.GetAllLinksByUser(ctx, newUser(t, repo, "UTC"))
+	if err != nil || len(links) != 0 {
+		t.Errorf("GetAllLinksByUser of a new user = %v, %v; want no links", links, err)
+	}
+}
+
+func testShortCodeCollision(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	owner := newUser(t, repo, "UTC")
+	other := newUser(t, repo, "UTC")
+	_, code := newLink(t, repo, owner, "https://example.com/owner")
+
+	id, err := repo.CreateLink(ctx, other, "https://example.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetShortCode(ctx, id, code); !errors.Is(err, customerrs.ErrCodeIsBusy) {
+		t.Fatalf("SetShortCode with a taken code: err = %v, want customerrs.ErrCodeIsBusy", err)
+	}
+	link, err := repo.GetLink(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.UserID != owner || link.OriginalLink != "https://example.com/owner" {
+		t.Errorf("the taken code now points to %+v", *link)
+	}
+	// Setting a link's own code again is not a collision.
+	links, err := repo.GetAllLinksByUser(ctx, owner)
+	if err != nil || len(links) != 1 {
+		t.Fatalf("GetAllLinksByUser = %v, %v", links, err)
+	}
+	if err := repo.SetShortCode(ctx, links[0].Id, code); err != nil {
+		t.Errorf("SetShortCode with the link's own code: %v", err)
+	}
+}
+
+func testLinkOwnership(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	owner := newUser(t, repo, "UTC")
+	other := newUser(t, repo, "UTC")
+	id, code := newLink(t, repo, owner, "https://example.com")
+
+	if err := repo.UpdateLink(ctx, other, code, "https://evil.example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteLinkByCode(ctx, other, code); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := repo.DeleteLinkById(ctx, other, id); err != nil || deleted != "" {
+		t.Errorf("DeleteLinkById by another user = %q, %v; want nothing deleted", deleted, err)
+	}
+	if codes, err := repo.DeleteAllLinksByUser(ctx, other); err != nil || len(codes) != 0 {
+		t.Errorf("DeleteAllLinksByUser of another user = %v, %v; want nothing deleted", codes, err)
+	}
+	if err := repo.SetLinkCacheTTL(ctx, other, code, time.Hour); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("SetLinkCacheTTL by another user: err = %v, want customerrs.ErrNoFound", err)
+	}
+
+	link, err := repo.GetLink(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.LinkCache{OriginalLink: "https://example.com", UserID: owner}); *link != want {
+		t.Errorf("link changed by another user: %+v, want %+v", *link, want)
+	}
+}
+
+func testDeleteLinks(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	userId := newUser(t, repo, "UTC")
+	_, byCode := newLink(t, repo, userId, "https://example.com/1")
+	byId, idCode := newLink(t, repo, userId, "https://example.com/2")
+	_, rest1 := newLink(t, repo, userId, "https://example.com/3")
+	_, rest2 := newLink(t, repo, userId, "https://example.com/4")
+
+	if err := repo.DeleteLinkByCode(ctx, userId, byCode); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetLink(ctx, byCode); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("GetLink after DeleteLinkByCode: err = %v, want customerrs.ErrNoFound", err)
+	}
+
+	if deleted, err := repo.DeleteLinkById(ctx, userId, byId); err != nil || deleted != idCode {
+		t.Errorf("DeleteLinkById = %q, %v; want %q", deleted, err, idCode)
+	}
+	if deleted, err := repo.DeleteLinkById(ctx, userId, byId); err != nil || deleted != "" {
+		t.Errorf("DeleteLinkById twice = %q, %v; want nothing deleted", deleted, err)
+	}
+
+	codes, err := repo.DeleteAllLinksByUser(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(codes)
+	want := []string{rest1, rest2}
+	slices.Sort(want)
+	if !slices.Equal(codes, want) {
+		t.Errorf("DeleteAllLinksByUser = %v, want %v", codes, want)
+	}
+	if links, err := repo.GetAllLinksByUser(ctx, userId); err != nil || len(links) != 0 {
+		t.Errorf("links left after DeleteAllLinksByUser: %v, %v", links, err)
+	}
+}
+
+func testLinkCacheTTL(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	userId := newUser(t, repo, "UTC")
+	_, code := newLink(t, repo, userId, "https://example.com")
+
+	if err := repo.SetLinkCacheTTL(ctx, userId, code, 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if link, err := repo.GetLink(ctx, code); err != nil || link.CacheTTLSeconds != 90 {
+		t.Errorf("GetLink = %+v, %v; want a cache TTL of 90 seconds", link, err)
+	}
+	if err := repo.SetLinkCacheTTL(ctx, userId, code, 0); err != nil {
+		t.Fatal(err)
+	}
+	if link, err := repo.GetLink(ctx, code); err != nil || link.CacheTTLSeconds != 0 {
+		t.Errorf("GetLink = %+v, %v; want the default cache TTL", link, err)
+	}
+	if err := repo.SetLinkCacheTTL(ctx, userId, uniqCode(), time.Hour); !errors.Is(err, customerrs.ErrNoFound) {
+		t.Errorf("SetLinkCacheTTL of a missing link: err = %v, want customerrs.ErrNoFound", err)
+	}
+}
+
+// drainInvalidations acknowledges every pending invalidation and returns
+// their codes.
+func drainInvalidations(t *testing.T, repo database.InvalidationsRepo) []string {
+	t.Helper()
+	ctx := context.Background()
+	var codes []string
+	for {
+		pending, err := repo.PendingInvalidations(ctx, 100)
+		if err != nil {
+			t.Fatalf("PendingInvalidations: %v", err)
+		}
+		if len(pending) == 0 {
+			return codes
+		}
+		ids := make([]int64, 0, len(pending))
+		for _, inv := range pending {
+			ids = append(ids, inv.Id)
+			codes = append(codes, inv.ShortCode)
+		}
+		if err := repo.AckInvalidations(ctx, ids); err != nil {
+			t.Fatalf("AckInvalidations: %v", err)
+		}
+	}
+}
+
+func testInvalidations(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	userId := newUser(t, repo, "UTC")
+	id, code := newLink(t, repo, userId, "https://example.com")
+	if _, err := repo.CreateLink(ctx, userId, "https://unpublished.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Publishing drops the negative cache entry the code may have.
+	if got := drainInvalidations(t, repo); !slices.Contains(got, code) {
+		t.Errorf("invalidations after SetShortCode = %v, want %s", got, code)
+	}
+
+	steps := []struct {
+		name   string
+		mutate func() error
+		want   bool
+	}{
+		{"UpdateLink", func() error { return repo.UpdateLink(ctx, userId, code, "https://new.example.com") }, true},
+		{"SetLinkCacheTTL", func() error { return repo.SetLinkCacheTTL(ctx, userId, code, time.Hour) }, true},
+		{"SetCityOptOut", func() error { return repo.SetCityOptOut(ctx, userId, true) }, true},
+		{"SetCityOptOut unchanged", func() error { return repo.SetCityOptOut(ctx, userId, true) }, false},
+		{"DeleteLinkById", func() error { _, err := repo.DeleteLinkById(ctx, userId, id); return err }, true},
+		{"DeleteAllLinksByUser without published links", func() error { _, err := repo.DeleteAllLinksByUser(ctx, userId); return err }, false},
+	}
+	for _, step := range steps {
+		if err := step.mutate(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		got := drainInvalidations(t, repo)
+		if slices.Contains(got, code) != step.want || slices.Contains(got, "") {
+			t.Errorf("invalidations after %s = %v, want %s: %v", step.name, got, code, step.want)
+		}
+	}
+}
+
+func testAlerts(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	userId := newUser(t, repo, "UTC")
+	_, code := newLink(t, repo, userId, "https://example.com")
+
+	alert, err := repo.GetLinkAlert(ctx, userId, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := types.DefaultLinkAlert(userId, code); *alert != want {
+		t.Errorf("GetLinkAlert without settings = %+v, want %+v", *alert, want)
+	}
+
+	mutedUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	saved := types.LinkAlert{
+		UserId:      userId,
+		ShortCode:   code,
+		SpikeFactor: 5,
+		MinClicks:   50,
+		MutedUntil:  &mutedUntil,
+	}
+	if err := repo.SaveLinkAlert(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+	alert, err = repo.GetLinkAlert(ctx, userId, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.MutedUntil == nil || !alert.MutedUntil.Equal(mutedUntil) {
+		t.Errorf("MutedUntil = %v, want %v", alert.MutedUntil, mutedUntil)
+	}
+	alert.MutedUntil, saved.MutedUntil = nil, nil
+	if *alert != saved {
+		t.Errorf("GetLinkAlert = %+v, want %+v", *alert, saved)
+	}
+
+	alerts, err := repo.ListLinkAlerts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(alerts, func(a types.LinkAlert) bool { return a.ShortCode == code && a.SpikeFactor == 5 }) {
+		t.Errorf("ListLinkAlerts does not contain the saved settings of %s", code)
+	}
+
+	// Another user's code is not theirs to configure.
+	other := newUser(t, repo, "UTC")
+	if err := repo.SaveLinkAlert(ctx, types.LinkAlert{UserId: other, ShortCode: code, SpikeFactor: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if alert, err := repo.GetLinkAlert(ctx, userId, code); err != nil || alert.SpikeFactor != 5 {
+		t.Errorf("settings changed by another user: %+v, %v", alert, err)
+	}
+
+	now := time.Now()
+	if ok, err := repo.ClaimAlert(ctx, userId, code, now.Add(-time.Hour)); err != nil || !ok {
+		t.Errorf("first ClaimAlert = %v, %v; want true", ok, err)
+	}
+	if ok, err := repo.ClaimAlert(ctx, userId, code, now.Add(-time.Hour)); err != nil || ok {
+		t.Errorf("ClaimAlert within the cooldown = %v, %v; want false", ok, err)
+	}
+	if ok, err := repo.ClaimAlert(ctx, userId, code, now.Add(time.Hour)); err != nil || !ok {
+		t.Errorf("ClaimAlert after the cooldown = %v, %v; want true", ok, err)
+	}
+	if ok, err := repo.ClaimAlert(ctx, other, code, now.Add(time.Hour)); err != nil || ok {
+		t.Errorf("ClaimAlert by another user = %v, %v; want false", ok, err)
+	}
+}
+
+func testDigests(t *testing.T, repo database.SQL) {
+	ctx := context.Background()
+	userId := newUser(t, repo, "Europe/Kyiv")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	weekly := types.DigestSubscription{UserId: userId, Period: types.DigestWeekly, Hour: 9, Weekday: 1, NextRunAt: now.Add(-time.Minute)}
+	daily := types.DigestSubscription{UserId: userId, Period: types.DigestDaily, Hour: 8, Weekday: 1, NextRunAt: now.Add(time.Hour)}
+	for _, sub := range []types.DigestSubscription{weekly, daily} {
+		if err := repo.SaveDigestSubscription(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subs, err := repo.GetDigestSubscriptions(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || subs[0].Period != types.DigestDaily || subs[1].Period != types.DigestWeekly {
+		t.Fatalf("GetDigestSubscriptions = %+v, want daily then weekly", subs)
+	}
+	if subs[0].Timezone != "Europe/Kyiv" || subs[0].Hour != 8 || !subs[0].NextRunAt.Equal(daily.NextRunAt) {
+		t.Errorf("daily subscription = %+v", subs[0])
+	}
+
+	nextRun := now.Add(7 * 24 * time.Hour)
+	due, err := repo.ClaimDueDigests(ctx, now, func(types.DigestSubscription) time.Time { return nextRun })
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine := slices.DeleteFunc(due, func(s types.DigestSubscription) bool { return s.UserId != userId })
+	if len(mine) != 1 || mine[0].Period != types.DigestWeekly || mine[0].Timezone != "Europe/Kyiv" {
+		t.Fatalf("ClaimDueDigests = %+v, want the weekly digest", mine)
+	}
+	due, err = repo.ClaimDueDigests(ctx, now, func(types.DigestSubscription) time.Time { return nextRun })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(due, func(s types.DigestSubscription) bool { return s.UserId == userId }) {
+		t.Errorf("a claimed digest was handed out again: %+v", due)
+	}
+	subs, err = repo.GetDigestSubscriptions(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || !subs[1].NextRunAt.Equal(nextRun) {
+		t.Errorf("weekly digest was not moved to %v: %+v", nextRun, subs)
+	}
+
+	// Changing the timezone reschedules the subscriptions with the new one.
+	moved := now.Add(3 * time.Hour)
+	var rescheduled []string
+	err = repo.SetTimezone(ctx, userId, "America/New_York", func(sub types.DigestSubscription) time.Time {
+		rescheduled = append(rescheduled, sub.Period+" "+sub.Timezone)
+		return moved
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(rescheduled)
+	if want := []string{types.DigestDaily + " America/New_York", types.DigestWeekly + " America/New_York"}; !slices.Equal(rescheduled, want) {
+		t.Errorf("SetTimezone rescheduled %v, want %v", rescheduled, want)
+	}
+	subs, err = repo.GetDigestSubscriptions(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || !subs[0].NextRunAt.Equal(moved) || !subs[1].NextRunAt.Equal(moved) {
+		t.Errorf("digests were not moved to %v: %+v", moved, subs)
+	}
+
+	if err := repo.DeleteDigestSubscription(ctx, userId, types.DigestDaily); err != nil {
+		t.Fatal(err)
+	}
+	subs, err = repo.GetDigestSubscriptions(ctx, userId)
+	if err != nil || len(subs) != 1 || subs[0].Period != types.DigestWeekly {
+		t.Errorf("after deleting the daily digest: %+v, %v", subs, err)
+	}
+}
@@ -0,0 +1,20 @@
+package tiered
+
+import (
+	"context"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/memory"
+	"linkshortener/internal/database/storagetest"
+	"testing"
+	"time"
+)
+
+type nopInvalidator struct{}
+
+func (nopInvalidator) PublishInvalidation(context.Context, string) error { return nil }
+
+func TestConformance(t *testing.T) {
+	storagetest.Cache(t, func(*testing.T) database.Cache {
+		return New(Config{Size: 100, TTL: 5 * time.Second}, memory.NewCache(), nopInvalidator{})
+	})
+}
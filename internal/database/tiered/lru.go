@@ -0,0 +1,87 @@
+package tiered
+
+import (
+	"container/list"
+	"linkshortener/internal/types"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key     string
+	value   *types.LinkCache
+	expires time.Time
+}
+
+// lru is a size-bounded least recently used map whose entries also expire
+// after their own TTL.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (l *lru) get(key string, now time.Time) (*types.LinkCache, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !now.Before(e.expires) {
+		l.order.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (l *lru) set(key string, value *types.LinkCache, expires time.Time) {
+	if l.size <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expires = value, expires
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&entry{key: key, value: value, expires: expires})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*entry).key)
+	}
+}
+
+func (l *lru) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		l.order.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,175 @@
+// Package tiered puts a small in-process LRU in front of the shared link
+// cache, so that hot short codes are resolved without a Redis round trip.
+//
+// Local entries live for a short TTL only. Deletes and updates are
+// broadcast through an Invalidator, and every instance drops the code from
+// its own tier when the broadcast arrives; the TTL bounds staleness if a
+// broadcast is lost.
+package tiered
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	localHits   = expvar.NewInt("cache_local_hits")
+	localMisses = expvar.NewInt("cache_local_misses")
+	redisHits   = expvar.NewInt("cache_redis_hits")
+	redisMisses = expvar.NewInt("cache_redis_misses")
+)
+
+// Remote is the shared cache tier; it matches database.Cache.
+type Remote interface {
+	Set(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error
+	Get(ctx context.Context, shortCode string) (*types.LinkCache, error)
+	Update(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error
+	Delete(ctx context.Context, shortCode string) error
+	InitClickCounters(ctx context.Context, userId int64, shortCode string) error
+	DeleteClickCounters(ctx context.Context, userId int64, shortCodes ...string) error
+	CountClick(ctx context.Context, data types.ClickData) error
+	GetClickCounters(ctx context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error)
+	Close() error
+}
+
+type Invalidator interface {
+	PublishInvalidation(ctx context.Context, shortCode string) error
+}
+
+type Config struct {
+	// Size is the maximum number of codes kept in process; zero disables
+	// the local tier.
+	Size int
+	TTL  time.Duration
+}
+
+type Cache struct {
+	cfg    Config
+	local  *lru
+	remote Remote
+	inv    Invalidator
+	// epoch changes on every invalidation, so that a value read from the
+	// remote tier before an invalidation is not stored locally after it.
+	epoch atomic.Uint64
+	now   func() time.Time
+}
+
+func New(cfg Config, remote Remote, inv Invalidator) *Cache {
+	return &Cache{
+		cfg:    cfg,
+		local:  newLRU(cfg.Size),
+		remote: remote,
+		inv:    inv,
+		now:    time.Now,
+	}
+}
+
+// Run drops the codes received from codes from the local tier until the
+// channel is closed or ctx is done.
+func (c *Cache) Run(ctx context.Context, codes <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case code, ok := <-codes:
+			if !ok {
+				return
+			}
+			c.forget(code)
+		}
+	}
+}
+
+func (c *Cache) Get(ctx context.Context, shortCode string) (*types.LinkCache, error) {
+	if value, ok := c.local.get(shortCode, c.now()); ok {
+		localHits.Add(1)
+		return value, nil
+	}
+	localMisses.Add(1)
+
+	epoch := c.epoch.Load()
+	value, err := c.remote.Get(ctx, shortCode)
+	if err != nil {
+		if errors.Is(err, customerrs.ErrNoFound) {
+			redisMisses.Add(1)
+		}
+		return nil, err
+	}
+	redisHits.Add(1)
+	c.store(shortCode, value, c.cfg.TTL, epoch)
+	return value, nil
+}
+
+func (c *Cache) Set(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error {
+	epoch := c.epoch.Load()
+	if err := c.remote.Set(ctx, shortCode, cache, expiration); err != nil {
+		return err
+	}
+	c.store(shortCode, cache, min(c.cfg.TTL, expiration), epoch)
+	return nil
+}
+
+func (c *Cache) Update(ctx context.Context, shortCode string, cache *types.LinkCache, expiration time.Duration) error {
+	defer c.invalidate(ctx, shortCode)
+	return c.remote.Update(ctx, shortCode, cache, expiration)
+}
+
+func (c *Cache) Delete(ctx context.Context, shortCode string) error {
+	defer c.invalidate(ctx, shortCode)
+	return c.remote.Delete(ctx, shortCode)
+}
+
+func (c *Cache) InitClickCounters(ctx context.Context, userId int64, shortCode string) error {
+	return c.remote.InitClickCounters(ctx, userId, shortCode)
+}
+
+func (c *Cache) DeleteClickCounters(ctx context.Context, userId int64, shortCodes ...string) error {
+	return c.remote.DeleteClickCounters(ctx, userId, shortCodes...)
+}
+
+// CountClick and GetClickCounters are not cached locally: counters change
+// on every click.
+func (c *Cache) CountClick(ctx context.Context, data types.ClickData) error {
+	return c.remote.CountClick(ctx, data)
+}
+
+func (c *Cache) GetClickCounters(ctx context.Context, userId int64, shortCodes []string, day time.Time) (map[string]types.ClickCounters, error) {
+	return c.remote.GetClickCounters(ctx, userId, shortCodes, day)
+}
+
+func (c *Cache) Close() error {
+	return c.remote.Close()
+}
+
+// store keeps value in the local tier. Not-found entries are left to the
+// remote tier: a code created after the miss would otherwise stay a 404
+// here for the whole local TTL if its broadcast is lost.
+func (c *Cache) store(shortCode string, value *types.LinkCache, ttl time.Duration, epoch uint64) {
+	if ttl <= 0 || value.NotFound || c.epoch.Load() != epoch {
+		return
+	}
+	c.local.set(shortCode, value, c.now().Add(ttl))
+}
+
+func (c *Cache) forget(shortCode string) {
+	c.epoch.Add(1)
+	c.local.remove(shortCode)
+}
+
+// invalidate drops shortCode here right away and on the other instances
+// once they receive the broadcast.
+func (c *Cache) invalidate(ctx context.Context, shortCode string) {
+	c.forget(shortCode)
+	if c.inv == nil {
+		return
+	}
+	if err := c.inv.PublishInvalidation(ctx, shortCode); err != nil {
+		slog.Warn("Failed to broadcast cache invalidation", "short_code", shortCode, "error", err)
+	}
+}
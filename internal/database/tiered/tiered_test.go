@@ -0,0 +1,208 @@
+package tiered
+
+import (
+	"context"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"testing"
+	"time"
+)
+
+type fakeRemote struct {
+	values    map[string]*types.LinkCache
+	gets      int
+	onGet     func()
+	published []string
+}
+
+func newFakeRemote() *fakeRemote {
+	return &fakeRemote{values: make(map[string]*types.LinkCache)}
+}
+
+func (f *fakeRemote) Set(_ context.Context, shortCode string, cache *types.LinkCache, _ time.Duration) error {
+	f.values[shortCode] = cache
+	return nil
+}
+
+func (f *fakeRemote) Get(_ context.Context, shortCode string) (*types.LinkCache, error) {
+	f.gets++
+	if f.onGet != nil {
+		f.onGet()
+	}
+	value, ok := f.values[shortCode]
+	if !ok {
+		return nil, customerrs.ErrNoFound
+	}
+	return value, nil
+}
+
+func (f *fakeRemote) Update(_ context.Context, shortCode string, cache *types.LinkCache, _ time.Duration) error {
+	f.values[shortCode] = cache
+	return nil
+}
+
+func (f *fakeRemote) Delete(_ context.Context, shortCode string) error {
+	delete(f.values, shortCode)
+	return nil
+}
+
+func (f *fakeRemote) InitClickCounters(context.Context, int64, string) error { return nil }
+
+func (f *fakeRemote) DeleteClickCounters(context.Context, int64, ...string) error { return nil }
+
+func (f *fakeRemote) CountClick(context.Context, types.ClickData) error { return nil }
+
+func (f *fakeRemote) GetClickCounters(context.Context, int64, []string, time.Time) (map[string]types.ClickCounters, error) {
+	return nil, nil
+}
+
+func (f *fakeRemote) Close() error { return nil }
+
+func (f *fakeRemote) PublishInvalidation(_ context.Context, shortCode string) error {
+	f.published = append(f.published, shortCode)
+	return nil
+}
+
+func newTestCache(remote *fakeRemote, size int) (*Cache, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(Config{Size: size, TTL: 5 * time.Second}, remote, remote)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestGetServesHotCodesLocally(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	remote.values["abc"] = &types.LinkCache{OriginalLink: "https://example.com"}
+	c, now := newTestCache(remote, 10)
+
+	for range 3 {
+		got, err := c.Get(ctx, "abc")
+		if err != nil || got.OriginalLink != "https://example.com" {
+			t.Fatalf("Get() = %v, %v", got, err)
+		}
+	}
+	if remote.gets != 1 {
+		t.Errorf("remote gets = %d, want 1", remote.gets)
+	}
+
+	*now = now.Add(5 * time.Second)
+	if _, err := c.Get(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if remote.gets != 2 {
+		t.Errorf("remote gets after TTL = %d, want 2", remote.gets)
+	}
+}
+
+func TestGetMissIsNotCached(t *testing.T) {
+	c, _ := newTestCache(newFakeRemote(), 10)
+	for range 2 {
+		if _, err := c.Get(context.Background(), "nope"); err != customerrs.ErrNoFound {
+			t.Fatalf("Get() error = %v, want ErrNoFound", err)
+		}
+	}
+	if c.local.len() != 0 {
+		t.Errorf("local entries = %d, want 0", c.local.len())
+	}
+}
+
+func TestNotFoundEntriesAreNotStoredLocally(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	c, _ := newTestCache(remote, 10)
+
+	if err := c.Set(ctx, "new", &types.LinkCache{NotFound: true}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		got, err := c.Get(ctx, "new")
+		if err != nil || !got.NotFound {
+			t.Fatalf("Get() = %v, %v, want a not-found entry", got, err)
+		}
+	}
+	if c.local.len() != 0 {
+		t.Errorf("local entries = %d, want 0", c.local.len())
+	}
+
+	// The code is created and the broadcast is lost: the next lookup still
+	// sees it.
+	remote.values["new"] = &types.LinkCache{OriginalLink: "https://example.com"}
+	if got, err := c.Get(ctx, "new"); err != nil || got.OriginalLink != "https://example.com" {
+		t.Fatalf("Get() after create = %v, %v", got, err)
+	}
+}
+
+func TestDeleteBroadcastsInvalidation(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	c, _ := newTestCache(remote, 10)
+
+	if err := c.Set(ctx, "abc", &types.LinkCache{OriginalLink: "https://example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "abc"); err != customerrs.ErrNoFound {
+		t.Errorf("Get() after Delete error = %v, want ErrNoFound", err)
+	}
+	if len(remote.published) != 1 || remote.published[0] != "abc" {
+		t.Errorf("published = %v, want [abc]", remote.published)
+	}
+}
+
+func TestRunDropsCodesInvalidatedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	remote.values["abc"] = &types.LinkCache{OriginalLink: "https://old.example.com"}
+	c, _ := newTestCache(remote, 10)
+	if _, err := c.Get(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance updates the link and broadcasts the code.
+	remote.values["abc"] = &types.LinkCache{OriginalLink: "https://new.example.com"}
+	codes := make(chan string, 1)
+	codes <- "abc"
+	close(codes)
+	c.Run(ctx, codes)
+
+	got, err := c.Get(ctx, "abc")
+	if err != nil || got.OriginalLink != "https://new.example.com" {
+		t.Errorf("Get() = %v, %v, want the new link", got, err)
+	}
+}
+
+func TestInvalidationDuringRemoteGet(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	remote.values["abc"] = &types.LinkCache{OriginalLink: "https://old.example.com"}
+	c, _ := newTestCache(remote, 10)
+
+	remote.onGet = func() { c.forget("abc") }
+	if _, err := c.Get(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if c.local.len() != 0 {
+		t.Error("value read before an invalidation was stored locally")
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	l := newLRU(2)
+	l.set("a", &types.LinkCache{}, now.Add(time.Minute))
+	l.set("b", &types.LinkCache{}, now.Add(time.Minute))
+	l.get("a", now)
+	l.set("c", &types.LinkCache{}, now.Add(time.Minute))
+
+	if _, ok := l.get("b", now); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := l.get(key, now); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+}
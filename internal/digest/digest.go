@@ -0,0 +1,186 @@
+// Package digest schedules and builds the periodic analytics summaries sent
+// to users through the bot.
+package digest
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"log/slog"
+	"time"
+)
+
+const (
+	topLinksLimit = 5
+	// countriesLimit is above the number of countries in the GeoIP database,
+	// so that a country is never reported as new just for being rare.
+	countriesLimit = 1000
+)
+
+type Store interface {
+	ClaimDueDigests(ctx context.Context, now time.Time, next func(types.DigestSubscription) time.Time) ([]types.DigestSubscription, error)
+}
+
+type Source interface {
+	GetDailyClicks(ctx context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error)
+	GetTopLinks(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+	GetTopCountries(ctx context.Context, filter types.AnalyticsFilter, limit int) ([]types.CountStat, error)
+}
+
+type Sender interface {
+	SendDigest(ctx context.Context, digest types.Digest) error
+}
+
+type Scheduler struct {
+	interval time.Duration
+	store    Store
+	src      Source
+	sender   Sender
+	now      func() time.Time
+}
+
+func NewScheduler(interval time.Duration, store Store, src Source, sender Sender) *Scheduler {
+	return &Scheduler{interval: interval, store: store, src: src, sender: sender, now: time.Now}
+}
+
+// Run sends due digests every interval until ctx is done. A non-positive
+// interval disables the scheduler.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		slog.Info("Digest scheduler is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Tick(ctx); err != nil {
+				slog.Error("Digest scheduler tick failed", "error", err)
+			}
+		}
+	}
+}
+
+// Tick claims the digests that are due and sends them. Claiming moves each
+// subscription to its next run first, so a digest that fails to build or
+// send is skipped rather than sent twice.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	now := s.now()
+	due, err := s.store.ClaimDueDigests(ctx, now, func(sub types.DigestSubscription) time.Time {
+		return NextRun(sub, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range due {
+		digest, err := Build(ctx, s.src, sub, now)
+		if err != nil {
+			slog.Error("Failed to build digest", "user_id", sub.UserId, "period", sub.Period, "error", err)
+			continue
+		}
+		if digest.Total == 0 && digest.PreviousTotal == 0 {
+			continue
+		}
+		if err := s.sender.SendDigest(ctx, digest); err != nil {
+			slog.Error("Failed to send digest", "user_id", sub.UserId, "period", sub.Period, "error", err)
+			continue
+		}
+		slog.Info("Digest sent", "user_id", sub.UserId, "period", sub.Period, "clicks", digest.Total)
+	}
+	return nil
+}
+
+func location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// NextRun returns the first scheduled time of sub strictly after after.
+func NextRun(sub types.DigestSubscription, after time.Time) time.Time {
+	loc := location(sub.Timezone)
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), sub.Hour, 0, 0, 0, loc)
+
+	step := 1
+	if sub.Period == types.DigestWeekly {
+		step = 7
+		want := time.Weekday(sub.Weekday % 7)
+		next = next.AddDate(0, 0, (int(want)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		next = next.AddDate(0, 0, step)
+	}
+	return next
+}
+
+// Period returns the range a digest sent at runAt covers: the last full day
+// or the last seven full days in the subscriber's timezone.
+func Period(sub types.DigestSubscription, runAt time.Time) (time.Time, time.Time) {
+	local := runAt.In(location(sub.Timezone))
+	to := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	if sub.Period == types.DigestWeekly {
+		return to.AddDate(0, 0, -7), to
+	}
+	return to.AddDate(0, 0, -1), to
+}
+
+func Build(ctx context.Context, src Source, sub types.DigestSubscription, runAt time.Time) (types.Digest, error) {
+	from, to := Period(sub, runAt)
+	prevFrom := from.Add(-to.Sub(from))
+	digest := types.Digest{UserId: sub.UserId, Period: sub.Period, From: from, To: to}
+
+	current := types.AnalyticsFilter{UserId: sub.UserId, From: from, To: to, Timezone: sub.Timezone}
+	previous := current
+	previous.From, previous.To = prevFrom, from
+
+	var err error
+	if digest.Total, err = totalClicks(ctx, src, current); err != nil {
+		return digest, err
+	}
+	if digest.PreviousTotal, err = totalClicks(ctx, src, previous); err != nil {
+		return digest, err
+	}
+	if digest.TopLinks, err = src.GetTopLinks(ctx, current, topLinksLimit); err != nil {
+		return digest, err
+	}
+
+	countries, err := src.GetTopCountries(ctx, current, countriesLimit)
+	if err != nil {
+		return digest, err
+	}
+	history := current
+	history.From, history.To = time.Unix(0, 0), from
+	seen, err := src.GetTopCountries(ctx, history, countriesLimit)
+	if err != nil {
+		return digest, err
+	}
+	known := make(map[string]bool, len(seen))
+	for _, c := range seen {
+		known[c.Key] = true
+	}
+	for _, c := range countries {
+		if c.Key != "" && c.Key != "Unknown" && !known[c.Key] {
+			digest.NewCountries = append(digest.NewCountries, c.Key)
+		}
+	}
+	return digest, nil
+}
+
+func totalClicks(ctx context.Context, src Source, filter types.AnalyticsFilter) (int64, error) {
+	daily, err := src.GetDailyClicks(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, d := range daily {
+		total += d.Clicks
+	}
+	return total, nil
+}
@@ -0,0 +1,204 @@
+package digest
+
+import (
+	"context"
+	"linkshortener/internal/types"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNextRun(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skip("tzdata not available:", err)
+	}
+
+	tests := []struct {
+		name  string
+		sub   types.DigestSubscription
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "daily later today",
+			sub:   types.DigestSubscription{Period: types.DigestDaily, Hour: 9, Timezone: "Europe/Kyiv"},
+			after: time.Date(2026, 3, 10, 7, 30, 0, 0, kyiv),
+			want:  time.Date(2026, 3, 10, 9, 0, 0, 0, kyiv),
+		},
+		{
+			name:  "daily at the run time moves to tomorrow",
+			sub:   types.DigestSubscription{Period: types.DigestDaily, Hour: 9, Timezone: "Europe/Kyiv"},
+			after: time.Date(2026, 3, 10, 9, 0, 0, 0, kyiv),
+			want:  time.Date(2026, 3, 11, 9, 0, 0, 0, kyiv),
+		},
+		{
+			name:  "daily across DST change",
+			sub:   types.DigestSubscription{Period: types.DigestDaily, Hour: 9, Timezone: "Europe/Kyiv"},
+			after: time.Date(2026, 3, 28, 10, 0, 0, 0, kyiv),
+			want:  time.Date(2026, 3, 29, 9, 0, 0, 0, kyiv),
+		},
+		{
+			name:  "weekly later this week",
+			sub:   types.DigestSubscription{Period: types.DigestWeekly, Hour: 9, Weekday: 5, Timezone: "Europe/Kyiv"},
+			after: time.Date(2026, 3, 10, 12, 0, 0, 0, kyiv), // Tuesday
+			want:  time.Date(2026, 3, 13, 9, 0, 0, 0, kyiv),
+		},
+		{
+			name:  "weekly on sunday",
+			sub:   types.DigestSubscription{Period: types.DigestWeekly, Hour: 18, Weekday: 7, Timezone: "Europe/Kyiv"},
+			after: time.Date(2026, 3, 10, 12, 0, 0, 0, kyiv),
+			want:  time.Date(2026, 3, 15, 18, 0, 0, 0, kyiv),
+		},
+		{
+			name:  "weekly passed today moves to next week",
+			sub:   types.DigestSubscription{Period: types.DigestWeekly, Hour: 9, Weekday: 2, Timezone: "Europe/Kyiv"},
+			after: time.Date(2026, 3, 10, 12, 0, 0, 0, kyiv),
+			want:  time.Date(2026, 3, 17, 9, 0, 0, 0, kyiv),
+		},
+		{
+			name:  "unknown timezone falls back to UTC",
+			sub:   types.DigestSubscription{Period: types.DigestDaily, Hour: 9, Timezone: "Mars/Olympus"},
+			after: time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextRun(tt.sub, tt.after); !got.Equal(tt.want) {
+				t.Errorf("NextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeSource struct {
+	daily     map[time.Time]int64
+	links     []types.CountStat
+	countries map[time.Time][]types.CountStat
+}
+
+func (f *fakeSource) GetDailyClicks(_ context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	return []types.DailyClicks{{Day: filter.From, Clicks: f.daily[filter.From]}}, nil
+}
+
+func (f *fakeSource) GetTopLinks(context.Context, types.AnalyticsFilter, int) ([]types.CountStat, error) {
+	return f.links, nil
+}
+
+func (f *fakeSource) GetTopCountries(_ context.Context, filter types.AnalyticsFilter, _ int) ([]types.CountStat, error) {
+	return f.countries[filter.From], nil
+}
+
+func TestBuild(t *testing.T) {
+	sub := types.DigestSubscription{UserId: 1, Period: types.DigestWeekly, Hour: 9, Weekday: 1, Timezone: "UTC"}
+	runAt := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+	from := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	prevFrom := from.AddDate(0, 0, -7)
+
+	src := &fakeSource{
+		daily: map[time.Time]int64{from: 120, prevFrom: 80},
+		links: []types.CountStat{{Key: "abc", Clicks: 100}, {Key: "xyz", Clicks: 20}},
+		countries: map[time.Time][]types.CountStat{
+			from:            {{Key: "Ukraine", Clicks: 90}, {Key: "Poland", Clicks: 20}, {Key: "Unknown", Clicks: 10}},
+			time.Unix(0, 0): {{Key: "Ukraine", Clicks: 500}},
+		},
+	}
+
+	got, err := Build(context.Background(), src, sub, runAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.From.Equal(from) || !got.To.Equal(from.AddDate(0, 0, 7)) {
+		t.Errorf("period = %v..%v", got.From, got.To)
+	}
+	if got.Total != 120 || got.PreviousTotal != 80 {
+		t.Errorf("totals = %d, %d, want 120, 80", got.Total, got.PreviousTotal)
+	}
+	if len(got.TopLinks) != 2 {
+		t.Errorf("top links = %v", got.TopLinks)
+	}
+	if !slices.Equal(got.NewCountries, []string{"Poland"}) {
+		t.Errorf("new countries = %v, want [Poland]", got.NewCountries)
+	}
+}
+
+type click struct {
+	at      time.Time
+	code    string
+	country string
+}
+
+// clickSource answers from individual clicks, counting exactly those in the
+// filter's range.
+type clickSource []click
+
+func (s clickSource) count(filter types.AnalyticsFilter, key func(click) string) []types.CountStat {
+	counts := make(map[string]int64)
+	for _, c := range s {
+		if !c.at.Before(filter.From) && c.at.Before(filter.To) {
+			counts[key(c)]++
+		}
+	}
+	var stats []types.CountStat
+	for k, n := range counts {
+		stats = append(stats, types.CountStat{Key: k, Clicks: n})
+	}
+	slices.SortFunc(stats, func(a, b types.CountStat) int { return int(b.Clicks - a.Clicks) })
+	return stats
+}
+
+func (s clickSource) GetDailyClicks(_ context.Context, filter types.AnalyticsFilter) ([]types.DailyClicks, error) {
+	loc := location(filter.Timezone)
+	var daily []types.DailyClicks
+	for _, stat := range s.count(filter, func(c click) string { return c.at.In(loc).Format(time.DateOnly) }) {
+		day, _ := time.Parse(time.DateOnly, stat.Key)
+		daily = append(daily, types.DailyClicks{Day: day, Clicks: stat.Clicks})
+	}
+	return daily, nil
+}
+
+func (s clickSource) GetTopLinks(_ context.Context, filter types.AnalyticsFilter, _ int) ([]types.CountStat, error) {
+	return s.count(filter, func(c click) string { return c.code }), nil
+}
+
+func (s clickSource) GetTopCountries(_ context.Context, filter types.AnalyticsFilter, _ int) ([]types.CountStat, error) {
+	return s.count(filter, func(c click) string { return c.country }), nil
+}
+
+func TestBuildInTimezone(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skip(err)
+	}
+	sub := types.DigestSubscription{UserId: 1, Period: types.DigestDaily, Hour: 9, Timezone: "Europe/Kyiv"}
+	runAt := time.Date(2026, 3, 10, 9, 0, 0, 0, kyiv)
+	from := time.Date(2026, 3, 9, 0, 0, 0, 0, kyiv)
+
+	// Kyiv is two hours ahead of UTC in March: the digest covers
+	// 2026-03-08 22:00 to 2026-03-09 22:00 UTC, not the UTC day.
+	src := clickSource{
+		{at: from.Add(-time.Minute), code: "before", country: "Germany"},
+		{at: from, code: "abc", country: "Ukraine"},
+		{at: from.Add(23 * time.Hour), code: "abc", country: "Poland"},
+		{at: from.Add(24 * time.Hour), code: "after", country: "France"},
+		{at: from.Add(-25 * time.Hour), code: "abc", country: "Ukraine"},
+	}
+
+	got, err := Build(context.Background(), src, sub, runAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.From.Equal(from) || !got.To.Equal(from.AddDate(0, 0, 1)) {
+		t.Errorf("period = %v..%v, want the Kyiv day of %v", got.From, got.To, from)
+	}
+	if got.Total != 2 || got.PreviousTotal != 1 {
+		t.Errorf("totals = %d, %d, want 2, 1", got.Total, got.PreviousTotal)
+	}
+	if want := []types.CountStat{{Key: "abc", Clicks: 2}}; !slices.Equal(got.TopLinks, want) {
+		t.Errorf("top links = %v, want %v", got.TopLinks, want)
+	}
+	if !slices.Equal(got.NewCountries, []string{"Poland"}) {
+		t.Errorf("new countries = %v, want [Poland]", got.NewCountries)
+	}
+}
@@ -0,0 +1,152 @@
+// Package export writes raw clicks as CSV or newline-delimited JSON.
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"linkshortener/internal/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+var ErrInvalidFormat = errors.New("invalid export format, expected csv or ndjson")
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "csv":
+		return FormatCSV, nil
+	case "ndjson", "json", "jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", ErrInvalidFormat
+}
+
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func (f Format) Extension() string {
+	if f == FormatNDJSON {
+		return ".ndjson"
+	}
+	return ".csv"
+}
+
+type Source interface {
+	ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error
+}
+
+var header = []string{
+	"clicked_at", "short_code", "country", "region", "city", "asn", "isp",
+	"device", "browser", "os", "user_agent",
+	"referer", "referer_host", "channel", "utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+}
+
+// Write streams at most limit clicks from src to w and returns how many rows
+// were written.
+func Write(ctx context.Context, w io.Writer, src Source, filter types.AnalyticsFilter, format Format, limit int) (int, error) {
+	var write func(types.Analytic) error
+	var flush func() error
+
+	switch format {
+	case FormatNDJSON:
+		buf := bufio.NewWriter(w)
+		enc := json.NewEncoder(buf)
+		write = func(click types.Analytic) error { return enc.Encode(click) }
+		flush = buf.Flush
+	default:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return 0, err
+		}
+		write = func(click types.Analytic) error { return cw.Write(record(click)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+
+	rows := 0
+	err := src.ExportClicks(ctx, filter, limit, func(click types.Analytic) error {
+		rows++
+		return write(click)
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, flush()
+}
+
+func record(c types.Analytic) []string {
+	row := []string{
+		c.ClickedAt.UTC().Format(time.RFC3339Nano), c.ShortCode, c.Country, c.Region, c.City,
+		strconv.FormatUint(uint64(c.ASN), 10), c.ISP,
+		c.Device, c.Browser, c.OS, c.UserAgent,
+		c.Referer, c.RefererHost, c.Channel, c.UTMSource, c.UTMMedium, c.UTMCampaign, c.UTMTerm, c.UTMContent,
+	}
+	for i, cell := range row {
+		row[i] = escapeFormula(cell)
+	}
+	return row
+}
+
+// escapeFormula keeps spreadsheets from evaluating a cell as a formula.
+// Most cells are written by whoever clicked the link, e.g. the referer or
+// the UTM parameters, so a leading quote makes them plain text.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// ParseRange parses optional inclusive dates (YYYY-MM-DD, in loc) or RFC 3339
+// timestamps into a half-open time range. Missing bounds default to the
+// beginning of time and now.
+func ParseRange(from, to string, loc *time.Location) (time.Time, time.Time, error) {
+	start, end := time.Unix(0, 0), time.Now()
+	if from != "" {
+		t, _, err := parseTime(from, loc)
+		if err != nil {
+			return start, end, err
+		}
+		start = t
+	}
+	if to != "" {
+		t, isDate, err := parseTime(to, loc)
+		if err != nil {
+			return start, end, err
+		}
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+		end = t
+	}
+	if !start.Before(end) {
+		return start, end, errors.New("empty export range")
+	}
+	return start, end, nil
+}
+
+func parseTime(s string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, false, err
+}
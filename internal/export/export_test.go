@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"linkshortener/internal/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sliceSource []types.Analytic
+
+func (s sliceSource) ExportClicks(_ context.Context, _ types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error {
+	for i, click := range s {
+		if i == limit {
+			break
+		}
+		if err := fn(click); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	src := sliceSource{
+		{ShortCode: "abc", City: "Kyiv", Referer: "https://t.co/x,y", ClickedAt: at},
+		{ShortCode: "abc", City: "Lviv", ClickedAt: at.Add(time.Minute)},
+		{ShortCode: "abc", City: "Odesa", ClickedAt: at.Add(2 * time.Minute)},
+	}
+
+	var buf bytes.Buffer
+	rows, err := Write(context.Background(), &buf, src, types.AnalyticsFilter{}, FormatCSV, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if rows != 2 || len(lines) != 3 {
+		t.Fatalf("rows = %d, lines = %d, want 2 rows plus header", rows, len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "clicked_at,short_code,") {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `"https://t.co/x,y"`) {
+		t.Errorf("referer is not quoted: %q", lines[1])
+	}
+
+	buf.Reset()
+	rows, err = Write(context.Background(), &buf, src, types.AnalyticsFilter{}, FormatNDJSON, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	for i := range rows {
+		var click types.Analytic
+		if err := dec.Decode(&click); err != nil {
+			t.Fatal(err)
+		}
+		if click.City != src[i].City {
+			t.Errorf("row %d city = %q, want %q", i, click.City, src[i].City)
+		}
+	}
+	if rows != 3 {
+		t.Errorf("rows = %d, want 3", rows)
+	}
+}
+
+func TestWriteEscapesFormulas(t *testing.T) {
+	src := sliceSource{{
+		ShortCode:   "abc",
+		City:        "Kyiv",
+		Referer:     "=HYPERLINK(\"https://evil.example\")",
+		UserAgent:   "@SUM(1+1)",
+		UTMSource:   "+cmd",
+		UTMMedium:   "-2+3",
+		UTMCampaign: "\tleading tab",
+		UTMTerm:     "\rleading return",
+		UTMContent:  "a=b",
+		ClickedAt:   time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC),
+	}}
+
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), &buf, src, types.AnalyticsFilter{}, FormatCSV, 10); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want the header and one click", len(rows))
+	}
+	got := make(map[string]string)
+	for i, name := range rows[0] {
+		got[name] = rows[1][i]
+	}
+	want := map[string]string{
+		"short_code":   "abc",
+		"city":         "Kyiv",
+		"referer":      "'=HYPERLINK(\"https://evil.example\")",
+		"user_agent":   "'@SUM(1+1)",
+		"utm_source":   "'+cmd",
+		"utm_medium":   "'-2+3",
+		"utm_campaign": "'\tleading tab",
+		"utm_term":     "'\rleading return",
+		"utm_content":  "a=b",
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+
+	from, to, err := ParseRange("2026-01-01", "2026-01-31", kyiv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 1, 1, 0, 0, 0, 0, kyiv); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	if want := time.Date(2026, 2, 1, 0, 0, 0, 0, kyiv); !to.Equal(want) {
+		t.Errorf("to = %v, want %v", to, want)
+	}
+
+	if _, _, err := ParseRange("2026-02-01", "2026-01-01", kyiv); err == nil {
+		t.Error("expected error for reversed range")
+	}
+	if _, _, err := ParseRange("yesterday", "", kyiv); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
@@ -0,0 +1,169 @@
+package geoip
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+const Unknown = "Unknown"
+
+type Location struct {
+	Country string
+	Region  string
+	City    string
+	ASN     uint32
+	ISP     string
+}
+
+// database is a single MaxMind file that is reopened whenever its
+// modification time changes on disk.
+type database struct {
+	path    string
+	mu      sync.RWMutex
+	reader  *geoip2.Reader
+	modTime time.Time
+}
+
+type Resolver struct {
+	city *database
+	asn  *database
+}
+
+// Open loads the City database and, if asnPath is set, the ASN database.
+// Missing files are not an error: lookups return Unknown until the file
+// appears and is picked up by Watch.
+func Open(cityPath, asnPath string) *Resolver {
+	r := &Resolver{city: &database{path: cityPath}}
+	if asnPath != "" {
+		r.asn = &database{path: asnPath}
+	}
+	r.reload()
+	return r
+}
+
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+func (r *Resolver) reload() {
+	r.city.reload()
+	if r.asn != nil {
+		r.asn.reload()
+	}
+}
+
+func (d *database) reload() {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			d.mu.RLock()
+			loaded := d.reader != nil
+			d.mu.RUnlock()
+			if !loaded {
+				slog.Warn("GeoIP database not found, locations will be Unknown", "path", d.path)
+			}
+			return
+		}
+		slog.Warn("Failed to stat GeoIP database", "path", d.path, "error", err)
+		return
+	}
+
+	d.mu.RLock()
+	unchanged := d.reader != nil && info.ModTime().Equal(d.modTime)
+	d.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	reader, err := geoip2.Open(d.path)
+	if err != nil {
+		slog.Error("Failed to open GeoIP database", "path", d.path, "error", err)
+		return
+	}
+
+	d.mu.Lock()
+	old := d.reader
+	d.reader = reader
+	d.modTime = info.ModTime()
+	d.mu.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			slog.Warn("Failed to close previous GeoIP database", "path", d.path, "error", err)
+		}
+	}
+	slog.Info("GeoIP database loaded", "path", d.path, "build", reader.Metadata().BuildEpoch)
+}
+
+func (r *Resolver) Lookup(rawIP string) Location {
+	loc := Location{Country: Unknown, Region: Unknown, City: Unknown, ISP: Unknown}
+	ip := net.ParseIP(rawIP)
+	if r == nil || ip == nil {
+		return loc
+	}
+
+	r.city.mu.RLock()
+	if r.city.reader != nil {
+		if record, err := r.city.reader.City(ip); err == nil {
+			if name, ok := record.Country.Names["en"]; ok {
+				loc.Country = name
+			}
+			if len(record.Subdivisions) > 0 {
+				if name, ok := record.Subdivisions[0].Names["en"]; ok {
+					loc.Region = name
+				}
+			}
+			if name, ok := record.City.Names["en"]; ok {
+				loc.City = name
+			}
+		}
+	}
+	r.city.mu.RUnlock()
+
+	if r.asn != nil {
+		r.asn.mu.RLock()
+		if r.asn.reader != nil {
+			if record, err := r.asn.reader.ASN(ip); err == nil && record.AutonomousSystemNumber != 0 {
+				loc.ASN = uint32(record.AutonomousSystemNumber)
+				loc.ISP = record.AutonomousSystemOrganization
+			}
+		}
+		r.asn.mu.RUnlock()
+	}
+
+	return loc
+}
+
+func (r *Resolver) Close() error {
+	var errs []error
+	for _, d := range []*database{r.city, r.asn} {
+		if d == nil {
+			continue
+		}
+		d.mu.Lock()
+		if d.reader != nil {
+			errs = append(errs, d.reader.Close())
+			d.reader = nil
+		}
+		d.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
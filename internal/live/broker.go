@@ -0,0 +1,214 @@
+// Package live fans click events out to real-time subscribers. Events are
+// published through an optional Publisher (Redis pub/sub) and come back via
+// a Subscriber, so watchers on every server instance see every click. Without
+// them the broker delivers events in-process only.
+package live
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"linkshortener/internal/types"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	subscriberBuffer = 64
+	// watchedTTL is how long the broker trusts a WatcherCounter answer.
+	// A stream opened meanwhile on another instance may miss that long of
+	// clicks.
+	watchedTTL = time.Second
+	// watchedPruneSize is the number of cached answers above which expired
+	// ones are dropped.
+	watchedPruneSize = 1024
+)
+
+var (
+	liveSubscribers = expvar.NewInt("live_subscribers")
+	liveDropped     = expvar.NewInt("live_dropped")
+)
+
+type Publisher interface {
+	PublishClick(ctx context.Context, event types.ClickEvent) error
+}
+
+type Subscriber interface {
+	Watch(ctx context.Context, shortCode string) error
+	Unwatch(ctx context.Context, shortCode string) error
+	Events() <-chan types.ClickEvent
+}
+
+// WatcherCounter may be implemented by a Publisher that can tell how many
+// instances watch a short code, so that clicks nobody watches are not
+// published. It returns errors.ErrUnsupported when it cannot tell.
+type WatcherCounter interface {
+	CountWatchers(ctx context.Context, shortCode string) (int64, error)
+}
+
+type Broker struct {
+	pub Publisher
+	sub Subscriber
+	// subMu serializes Watch and Unwatch calls, so that mu is not held
+	// during network calls to the Subscriber.
+	subMu    sync.Mutex
+	mu       sync.Mutex
+	watchers map[string]map[chan types.ClickEvent]struct{}
+
+	watchedMu sync.Mutex
+	watched   map[string]watchedEntry
+}
+
+type watchedEntry struct {
+	watched bool
+	expires time.Time
+}
+
+func NewBroker(pub Publisher, sub Subscriber) *Broker {
+	return &Broker{
+		pub:      pub,
+		sub:      sub,
+		watchers: make(map[string]map[chan types.ClickEvent]struct{}),
+		watched:  make(map[string]watchedEntry),
+	}
+}
+
+// Run delivers events received from the Subscriber until it is exhausted or
+// ctx is done.
+func (b *Broker) Run(ctx context.Context) {
+	if b.sub == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-b.sub.Events():
+			if !ok {
+				return
+			}
+			b.deliver(event)
+		}
+	}
+}
+
+// Watched reports whether anybody may be watching shortCode. It is true
+// when the Publisher cannot tell or fails to answer.
+func (b *Broker) Watched(ctx context.Context, shortCode string) bool {
+	if b.pub == nil {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.watchers[shortCode]) > 0
+	}
+	counter, ok := b.pub.(WatcherCounter)
+	if !ok {
+		return true
+	}
+
+	now := time.Now()
+	b.watchedMu.Lock()
+	entry, ok := b.watched[shortCode]
+	b.watchedMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.watched
+	}
+
+	n, err := counter.CountWatchers(ctx, shortCode)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return true
+	}
+	if err != nil {
+		slog.Warn("Failed to count live watchers", "short_code", shortCode, "error", err)
+		return true
+	}
+
+	b.watchedMu.Lock()
+	defer b.watchedMu.Unlock()
+	if len(b.watched) >= watchedPruneSize {
+		for code, e := range b.watched {
+			if !now.Before(e.expires) {
+				delete(b.watched, code)
+			}
+		}
+	}
+	b.watched[shortCode] = watchedEntry{watched: n > 0, expires: now.Add(watchedTTL)}
+	return n > 0
+}
+
+func (b *Broker) Publish(ctx context.Context, event types.ClickEvent) error {
+	if b.pub == nil {
+		b.deliver(event)
+		return nil
+	}
+	return b.pub.PublishClick(ctx, event)
+}
+
+// Subscribe returns a channel of click events for shortCode and a function
+// that must be called to stop receiving them. Events are dropped for
+// subscribers that do not keep up.
+func (b *Broker) Subscribe(ctx context.Context, shortCode string) (<-chan types.ClickEvent, func(), error) {
+	ch := make(chan types.ClickEvent, subscriberBuffer)
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	_, watching := b.watchers[shortCode]
+	b.mu.Unlock()
+	if !watching && b.sub != nil {
+		if err := b.sub.Watch(ctx, shortCode); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	b.mu.Lock()
+	set, ok := b.watchers[shortCode]
+	if !ok {
+		set = make(map[chan types.ClickEvent]struct{})
+		b.watchers[shortCode] = set
+	}
+	set[ch] = struct{}{}
+	b.mu.Unlock()
+	liveSubscribers.Add(1)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { b.unsubscribe(shortCode, ch) })
+	}
+	return ch, cancel, nil
+}
+
+func (b *Broker) unsubscribe(shortCode string, ch chan types.ClickEvent) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	set := b.watchers[shortCode]
+	delete(set, ch)
+	last := len(set) == 0
+	if last {
+		delete(b.watchers, shortCode)
+	}
+	b.mu.Unlock()
+	liveSubscribers.Add(-1)
+
+	if last && b.sub != nil {
+		if err := b.sub.Unwatch(context.Background(), shortCode); err != nil {
+			slog.Warn("Failed to stop watching live clicks", "short_code", shortCode, "error", err)
+		}
+	}
+}
+
+func (b *Broker) deliver(event types.ClickEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.watchers[event.ShortCode] {
+		select {
+		case ch <- event:
+		default:
+			liveDropped.Add(1)
+		}
+	}
+}
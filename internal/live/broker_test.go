@@ -0,0 +1,164 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"linkshortener/internal/types"
+	"testing"
+	"time"
+)
+
+type fakeSubscriber struct {
+	watched map[string]int
+	events  chan types.ClickEvent
+}
+
+func (f *fakeSubscriber) Watch(_ context.Context, code string) error {
+	f.watched[code]++
+	return nil
+}
+
+func (f *fakeSubscriber) Unwatch(_ context.Context, code string) error {
+	f.watched[code]--
+	return nil
+}
+
+func (f *fakeSubscriber) Events() <-chan types.ClickEvent { return f.events }
+
+type loopback struct{ sub *fakeSubscriber }
+
+func (l loopback) PublishClick(_ context.Context, event types.ClickEvent) error {
+	l.sub.events <- event
+	return nil
+}
+
+func receive(t *testing.T, ch <-chan types.ClickEvent) types.ClickEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return types.ClickEvent{}
+	}
+}
+
+func TestBrokerInProcess(t *testing.T) {
+	b := NewBroker(nil, nil)
+	ctx := context.Background()
+
+	abc, cancelAbc, err := b.Subscribe(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xyz, cancelXyz, _ := b.Subscribe(ctx, "xyz")
+	defer cancelXyz()
+
+	_ = b.Publish(ctx, types.ClickEvent{ShortCode: "abc", Country: "Ukraine"})
+	if got := receive(t, abc); got.Country != "Ukraine" {
+		t.Errorf("got %+v", got)
+	}
+	select {
+	case event := <-xyz:
+		t.Errorf("xyz received foreign event %+v", event)
+	default:
+	}
+
+	cancelAbc()
+	cancelAbc()
+	_ = b.Publish(ctx, types.ClickEvent{ShortCode: "abc"})
+	if len(abc) != 0 {
+		t.Error("event delivered after unsubscribe")
+	}
+}
+
+func TestBrokerWatchesCodesThroughSubscriber(t *testing.T) {
+	sub := &fakeSubscriber{watched: map[string]int{}, events: make(chan types.ClickEvent, 1)}
+	b := NewBroker(loopback{sub}, sub)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	first, cancelFirst, _ := b.Subscribe(ctx, "abc")
+	second, cancelSecond, _ := b.Subscribe(ctx, "abc")
+	if sub.watched["abc"] != 1 {
+		t.Fatalf("watched = %d, want a single Watch per code", sub.watched["abc"])
+	}
+
+	_ = b.Publish(ctx, types.ClickEvent{ShortCode: "abc", Device: "mobile"})
+	receive(t, first)
+	receive(t, second)
+
+	cancelFirst()
+	if sub.watched["abc"] != 1 {
+		t.Error("code unwatched while a subscriber is left")
+	}
+	cancelSecond()
+	if sub.watched["abc"] != 0 {
+		t.Error("code still watched after the last subscriber left")
+	}
+}
+
+func TestBrokerDropsForSlowSubscribers(t *testing.T) {
+	b := NewBroker(nil, nil)
+	ctx := context.Background()
+	ch, cancel, _ := b.Subscribe(ctx, "abc")
+	defer cancel()
+
+	for range subscriberBuffer + 10 {
+		_ = b.Publish(ctx, types.ClickEvent{ShortCode: "abc"})
+	}
+	if len(ch) != subscriberBuffer {
+		t.Errorf("buffered %d events, want %d", len(ch), subscriberBuffer)
+	}
+}
+
+type countingPublisher struct {
+	watchers int64
+	err      error
+	calls    int
+}
+
+func (p *countingPublisher) PublishClick(context.Context, types.ClickEvent) error { return nil }
+
+func (p *countingPublisher) CountWatchers(context.Context, string) (int64, error) {
+	p.calls++
+	return p.watchers, p.err
+}
+
+func TestBrokerWatched(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewBroker(nil, nil)
+	if b.Watched(ctx, "abc") {
+		t.Error("in-process code watched before anybody subscribed")
+	}
+	_, cancel, _ := b.Subscribe(ctx, "abc")
+	if !b.Watched(ctx, "abc") {
+		t.Error("in-process code not watched while subscribed")
+	}
+	cancel()
+	if b.Watched(ctx, "abc") {
+		t.Error("in-process code watched after the subscriber left")
+	}
+
+	pub := &countingPublisher{}
+	b = NewBroker(pub, nil)
+	if b.Watched(ctx, "abc") || b.Watched(ctx, "abc") {
+		t.Error("code watched while the publisher counts no watchers")
+	}
+	if pub.calls != 1 {
+		t.Errorf("CountWatchers called %d times, want the answer cached", pub.calls)
+	}
+	pub.watchers = 1
+	if !b.Watched(ctx, "xyz") {
+		t.Error("code not watched while the publisher counts a watcher")
+	}
+	if !NewBroker(loopback{}, nil).Watched(ctx, "abc") {
+		t.Error("code not watched although the publisher cannot count watchers")
+	}
+	// A Redis Cluster cannot count the watchers on other nodes.
+	if !NewBroker(&countingPublisher{err: errors.ErrUnsupported}, nil).Watched(ctx, "abc") {
+		t.Error("code not watched although the publisher cannot tell")
+	}
+}
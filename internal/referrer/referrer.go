@@ -0,0 +1,143 @@
+package referrer
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	ChannelDirect    = "direct"
+	ChannelSearch    = "search"
+	ChannelSocial    = "social"
+	ChannelMessenger = "messenger"
+	ChannelEmail     = "email"
+	ChannelReferral  = "referral"
+)
+
+// hosts maps well-known domains to their channel. Subdomains inherit the
+// channel of their parent unless listed explicitly (mail.google.com is email,
+// not search).
+var hosts = map[string]string{
+	"t.co":                  ChannelSocial,
+	"x.com":                 ChannelSocial,
+	"fb.com":                ChannelSocial,
+	"fb.me":                 ChannelSocial,
+	"youtu.be":              ChannelSocial,
+	"lnkd.in":               ChannelSocial,
+	"threads.net":           ChannelSocial,
+	"vk.com":                ChannelSocial,
+	"tumblr.com":            ChannelSocial,
+	"mastodon.social":       ChannelSocial,
+	"news.ycombinator.com":  ChannelSocial,
+	"t.me":                  ChannelMessenger,
+	"wa.me":                 ChannelMessenger,
+	"discord.gg":            ChannelMessenger,
+	"messenger.com":         ChannelMessenger,
+	"signal.org":            ChannelMessenger,
+	"mail.google.com":       ChannelEmail,
+	"outlook.live.com":      ChannelEmail,
+	"outlook.office.com":    ChannelEmail,
+	"outlook.office365.com": ChannelEmail,
+	"mail.yahoo.com":        ChannelEmail,
+	"mail.proton.me":        ChannelEmail,
+	"mail.ukr.net":          ChannelEmail,
+	"e.mail.ru":             ChannelEmail,
+	"mail.yandex.ru":        ChannelEmail,
+	"search.brave.com":      ChannelSearch,
+	"ecosia.org":            ChannelSearch,
+	"startpage.com":         ChannelSearch,
+}
+
+// brands matches a domain label regardless of TLD (google.com.ua, bing.com)
+// and is also used for utm_source values such as "telegram" or "facebook".
+var brands = map[string]string{
+	"google":     ChannelSearch,
+	"bing":       ChannelSearch,
+	"yahoo":      ChannelSearch,
+	"duckduckgo": ChannelSearch,
+	"yandex":     ChannelSearch,
+	"baidu":      ChannelSearch,
+	"facebook":   ChannelSocial,
+	"instagram":  ChannelSocial,
+	"twitter":    ChannelSocial,
+	"linkedin":   ChannelSocial,
+	"reddit":     ChannelSocial,
+	"youtube":    ChannelSocial,
+	"tiktok":     ChannelSocial,
+	"pinterest":  ChannelSocial,
+	"telegram":   ChannelMessenger,
+	"whatsapp":   ChannelMessenger,
+	"viber":      ChannelMessenger,
+	"discord":    ChannelMessenger,
+	"slack":      ChannelMessenger,
+	"newsletter": ChannelEmail,
+	"email":      ChannelEmail,
+}
+
+var emailMediums = map[string]bool{"email": true, "e-mail": true, "newsletter": true}
+
+// Normalize reduces a Referer header to a lowercase host without "www." or
+// "m." prefixes. It returns "" for empty or unparsable values.
+func Normalize(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, prefix := range []string{"www.", "m.", "mobile."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host
+}
+
+// Classify assigns a channel from the normalized referer host and the
+// utm_source/utm_medium of the visited URL. An explicit email medium wins,
+// then the referer host, then the utm_source.
+func Classify(host, utmSource, utmMedium string) string {
+	if emailMediums[strings.ToLower(utmMedium)] {
+		return ChannelEmail
+	}
+	if host != "" {
+		if channel, ok := lookupHost(host); ok {
+			return channel
+		}
+		return ChannelReferral
+	}
+	if utmSource != "" {
+		source := strings.ToLower(utmSource)
+		if channel, ok := lookupHost(source); ok {
+			return channel
+		}
+		if channel, ok := brands[source]; ok {
+			return channel
+		}
+		return ChannelReferral
+	}
+	return ChannelDirect
+}
+
+func lookupHost(host string) (string, bool) {
+	for domain := host; domain != ""; {
+		if channel, ok := hosts[domain]; ok {
+			return channel, true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	for _, label := range strings.Split(host, ".") {
+		if channel, ok := brands[label]; ok {
+			return channel, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,43 @@
+package referrer
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"":                                      "",
+		"https://t.co/abc":                      "t.co",
+		"https://t.co/xyz?s=1":                  "t.co",
+		"https://www.Google.com.ua/":            "google.com.ua",
+		"https://m.facebook.com/story":          "facebook.com",
+		"android-app://org.telegram.messenger/": "org.telegram.messenger",
+		"example.org/path":                      "example.org",
+	}
+	for raw, want := range tests {
+		if got := Normalize(raw); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		host, source, medium string
+		want                 string
+	}{
+		{"", "", "", ChannelDirect},
+		{"t.co", "", "", ChannelSocial},
+		{"google.com.ua", "", "", ChannelSearch},
+		{"mail.google.com", "", "", ChannelEmail},
+		{"t.me", "", "", ChannelMessenger},
+		{"org.telegram.messenger", "", "", ChannelMessenger},
+		{"blog.example.org", "", "", ChannelReferral},
+		{"", "Telegram", "", ChannelMessenger},
+		{"", "partner", "", ChannelReferral},
+		{"t.co", "", "newsletter", ChannelEmail},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.host, tt.source, tt.medium); got != tt.want {
+			t.Errorf("Classify(%q, %q, %q) = %q, want %q", tt.host, tt.source, tt.medium, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"linkshortener/internal/apitoken"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/export"
+	"linkshortener/internal/types"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultExportMaxRows = 1_000_000
+
+// authenticate resolves the user of a request carrying an
+// "Authorization: Bearer <token>" header. Browsers cannot set headers on an
+// EventSource, so streaming endpoints set queryToken to accept an
+// access_token query parameter as well. Everywhere else it is refused, since
+// URLs end up in logs and browser history. On failure it writes the
+// response.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, queryToken bool) (int64, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && queryToken {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	userId, err := s.db.GetUserIDByAPITokenHash(r.Context(), apitoken.Hash(strings.TrimSpace(token)))
+	if err != nil {
+		if errors.Is(err, customerrs.ErrNoFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return 0, false
+		}
+		slog.Error("failed to authenticate api request", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return 0, false
+	}
+	return userId, true
+}
+
+// handleExport streams the caller's clicks as CSV or NDJSON. Query
+// parameters: code, from, to (YYYY-MM-DD or RFC 3339), tz, format, limit.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	userId, ok := s.authenticate(w, r, false)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	format, err := export.ParseFormat(query.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loc := time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, "invalid tz", http.StatusBadRequest)
+			return
+		}
+	}
+	from, to, err := export.ParseRange(query.Get("from"), query.Get("to"), loc)
+	if err != nil {
+		http.Error(w, "invalid date range: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := s.exportMaxRows
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, s.exportMaxRows)
+	}
+
+	code := query.Get("code")
+	filename := "clicks-all"
+	if code != "" {
+		if !shortCodePattern.MatchString(code) {
+			http.Error(w, "invalid code", http.StatusBadRequest)
+			return
+		}
+		filename = "clicks-" + code
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + format.Extension()}))
+	w.Header().Set("X-Export-Limit", strconv.Itoa(limit))
+
+	filter := types.AnalyticsFilter{UserId: userId, ShortCode: code, From: from, To: to, Timezone: loc.String()}
+	rows, err := export.Write(r.Context(), w, s.db, filter, format, limit)
+	if err != nil {
+		slog.Error("export failed", "user_id", userId, "short_code", code, "rows", rows, "error", err)
+		return
+	}
+	slog.Info("export finished", "user_id", userId, "short_code", code, "format", format, "rows", rows)
+}
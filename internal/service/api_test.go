@@ -0,0 +1,70 @@
+package service
+
+import (
+	"linkshortener/internal/apitoken"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestHandleExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := NewMockServerDB(ctrl)
+	s := NewServer(ServerConfig{ExportMaxRows: 10}, db, nil)
+
+	db.EXPECT().GetUserIDByAPITokenHash(gomock.Any(), apitoken.Hash("bad")).Return(int64(0), customerrs.ErrNoFound)
+	db.EXPECT().GetUserIDByAPITokenHash(gomock.Any(), apitoken.Hash("good")).Return(int64(7), nil).Times(3)
+	db.EXPECT().
+		ExportClicks(gomock.Any(), gomock.Any(), 10, gomock.Any()).
+		DoAndReturn(func(_ any, filter types.AnalyticsFilter, _ int, fn func(types.Analytic) error) error {
+			if filter.UserId != 7 || filter.ShortCode != "abc" {
+				t.Errorf("unexpected filter %+v", filter)
+			}
+			if want := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC); !filter.To.Equal(want) {
+				t.Errorf("filter.To = %v, want %v", filter.To, want)
+			}
+			return fn(types.Analytic{ShortCode: "abc", Country: "Ukraine", ClickedAt: time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)})
+		})
+
+	tests := []struct {
+		name        string
+		token       string
+		query       string
+		status      int
+		contains    string
+		disposition string
+	}{
+		{name: "missing token", query: "", status: http.StatusUnauthorized},
+		{name: "invalid token", token: "bad", status: http.StatusUnauthorized},
+		{name: "token in the query", query: "access_token=good", status: http.StatusUnauthorized},
+		{name: "invalid format", token: "good", query: "format=xml", status: http.StatusBadRequest},
+		{name: "invalid code", token: "good", query: "code=a%22%0d%0aX-Evil:%201", status: http.StatusBadRequest},
+		{name: "csv", token: "good", query: "code=abc&from=2026-01-01&to=2026-01-31&limit=50", status: http.StatusOK, contains: "2026-01-05T10:00:00Z,abc,Ukraine", disposition: "attachment; filename=clicks-abc.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/export?"+tt.query, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			s.handleExport(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("body %q does not contain %q", w.Body.String(), tt.contains)
+			}
+			if got := w.Header().Get("Content-Disposition"); tt.disposition != "" && got != tt.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.disposition)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs or single
+// addresses, e.g. "10.0.0.0/8, 172.16.0.0/12, 127.0.0.1".
+func ParseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func (s *Server) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForwardedHeader names the single header the trusted proxies use to pass
+// on the client address.
+type ForwardedHeader string
+
+const (
+	HeaderXForwardedFor ForwardedHeader = "X-Forwarded-For"
+	HeaderForwarded     ForwardedHeader = "Forwarded"
+	HeaderXRealIP       ForwardedHeader = "X-Real-IP"
+)
+
+func ParseForwardedHeader(header string) (ForwardedHeader, error) {
+	for _, h := range []ForwardedHeader{HeaderXForwardedFor, HeaderForwarded, HeaderXRealIP} {
+		if strings.EqualFold(header, string(h)) {
+			return h, nil
+		}
+	}
+	return "", fmt.Errorf("unknown forwarded header %q", header)
+}
+
+// getClientIP only looks at forwarding headers when the request comes from a
+// trusted proxy, and then only at the configured one: the proxies overwrite
+// or append to that header, while any other forwarding header is passed
+// through exactly as the client sent it. The chain is walked from right to
+// left and the first address that is not a trusted proxy is the client;
+// anything to the left of it could have been written by the client itself.
+func (s *Server) getClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	remote = remote.Unmap()
+	if !s.isTrustedProxy(remote) {
+		return remote.String()
+	}
+
+	hops := forwardedFor(r, s.forwardedHeader)
+	if len(hops) == 0 {
+		return remote.String()
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := parseHop(hops[i])
+		if err != nil {
+			return ""
+		}
+		if i == 0 || !s.isTrustedProxy(addr) {
+			return addr.String()
+		}
+	}
+	return remote.String()
+}
+
+// forwardedFor returns the client chain from the given header, ordered from
+// the original client to the proxy closest to us.
+func forwardedFor(r *http.Request, header ForwardedHeader) []string {
+	var hops []string
+	for _, value := range r.Header.Values(string(header)) {
+		switch header {
+		case HeaderForwarded:
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+					if found && strings.EqualFold(key, "for") {
+						hops = append(hops, val)
+					}
+				}
+			}
+		case HeaderXRealIP:
+			hops = append(hops, value)
+		default:
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	}
+	return hops
+}
+
+func parseHop(hop string) (netip.Addr, error) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+
+	if strings.HasPrefix(hop, "[") {
+		end := strings.Index(hop, "]")
+		if end == -1 {
+			return netip.Addr{}, fmt.Errorf("invalid address %q", hop)
+		}
+		hop = hop[1:end]
+	} else if strings.Count(hop, ":") == 1 {
+		hop, _, _ = strings.Cut(hop, ":")
+	}
+
+	addr, err := netip.ParseAddr(hop)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
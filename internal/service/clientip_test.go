@@ -0,0 +1,190 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 2001:db8:ffff::/48, 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     ForwardedHeader
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "203.0.113.5:5123",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted peer cannot spoof X-Forwarded-For",
+			remoteAddr: "203.0.113.5:5123",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8"}},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted peer cannot spoof X-Real-IP",
+			remoteAddr: "203.0.113.5:5123",
+			headers:    map[string][]string{"X-Real-IP": {"8.8.8.8"}},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted peer cannot spoof Forwarded",
+			remoteAddr: "203.0.113.5:5123",
+			header:     HeaderForwarded,
+			headers:    map[string][]string{"Forwarded": {"for=8.8.8.8"}},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy appends client",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "client-supplied entries left of the real client are ignored",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8, 1.1.1.1, 198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "chain of trusted proxies is skipped",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8, 198.51.100.7, 10.1.2.3, 192.0.2.1"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "multiple X-Forwarded-For headers are joined in order",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8", "198.51.100.7, 10.1.2.3"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "all hops trusted returns the leftmost one",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.9.9.9, 10.1.2.3"}},
+			want:       "10.9.9.9",
+		},
+		{
+			name:       "garbage hop yields no address",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7, not-an-ip"}},
+			want:       "",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "10.0.0.2:80",
+			header:     HeaderXRealIP,
+			headers:    map[string][]string{"X-Real-IP": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "trusted proxy without forwarding headers",
+			remoteAddr: "10.0.0.2:80",
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "Forwarded with port, quotes and IPv6",
+			remoteAddr: "10.0.0.2:80",
+			header:     HeaderForwarded,
+			headers:    map[string][]string{"Forwarded": {`for=8.8.8.8, for="[2001:db8:cafe::17]:4711";proto=https, for=10.1.2.3:8080`}},
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name:       "client-supplied Forwarded is ignored when proxies set X-Forwarded-For",
+			remoteAddr: "10.0.0.2:80",
+			headers: map[string][]string{
+				"Forwarded":       {"for=1.2.3.4"},
+				"X-Forwarded-For": {"198.51.100.7"},
+			},
+			want: "198.51.100.7",
+		},
+		{
+			name:       "client-supplied X-Real-IP is ignored when proxies set X-Forwarded-For",
+			remoteAddr: "10.0.0.2:80",
+			headers:    map[string][]string{"X-Real-IP": {"1.2.3.4"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "client-supplied X-Forwarded-For is ignored when proxies set Forwarded",
+			remoteAddr: "10.0.0.2:80",
+			header:     HeaderForwarded,
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "obfuscated Forwarded identifier yields no address",
+			remoteAddr: "10.0.0.2:80",
+			header:     HeaderForwarded,
+			headers:    map[string][]string{"Forwarded": {"for=_hidden"}},
+			want:       "",
+		},
+		{
+			name:       "IPv6 trusted proxy",
+			remoteAddr: "[2001:db8:ffff::1]:443",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8, 198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "IPv4-mapped IPv6 peer is matched against IPv4 ranges",
+			remoteAddr: "[::ffff:10.0.0.2]:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(ServerConfig{TrustedProxies: proxies, ForwardedHeader: tt.header}, nil, nil)
+			r := httptest.NewRequest("GET", "/abc", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for key, values := range tt.headers {
+				for _, v := range values {
+					r.Header.Add(key, v)
+				}
+			}
+			if got := s.getClientIP(r); got != tt.want {
+				t.Errorf("getClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForwardedHeader(t *testing.T) {
+	header, err := ParseForwardedHeader("x-forwarded-for")
+	if err != nil || header != HeaderXForwardedFor {
+		t.Errorf("ParseForwardedHeader() = %q, %v, want %q", header, err, HeaderXForwardedFor)
+	}
+	if _, err := ParseForwardedHeader("X-Client-IP"); err == nil {
+		t.Error("expected error for unknown header")
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies(" 10.0.0.0/8 ,,::1, 192.168.1.7/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "::1/128", "192.168.0.0/16"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("got %v, want %v", prefixes, want)
+	}
+	for i, p := range prefixes {
+		if p.String() != want[i] {
+			t.Errorf("prefix %d = %s, want %s", i, p, want[i])
+		}
+	}
+
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+	if _, err := ParseTrustedProxies("proxy.local"); err == nil {
+		t.Error("expected error for hostname")
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"linkshortener/internal/apitoken"
+	"linkshortener/internal/database"
+	"linkshortener/internal/database/memory"
+	"linkshortener/internal/live"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRedirectToExport runs a link from creation through a redirect to the
+// export API on the in-memory backends.
+func TestRedirectToExport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := database.CreateDatabase(ctx, memory.NewAnalytics(nil), memory.NewSQL(), memory.NewCache(), database.CacheConfig{})
+	shortener := NewShortener(db)
+	s := NewServer(ServerConfig{ExportMaxRows: 100, Live: live.NewBroker(nil, nil)}, db, shortener)
+
+	if err := db.CreateUser(ctx, 42, "Europe/Kyiv"); err != nil {
+		t.Fatal(err)
+	}
+	userId, err := db.GetUserIDByTelegramID(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetAPITokenHash(ctx, userId, apitoken.Hash("token")); err != nil {
+		t.Fatal(err)
+	}
+	code, err := shortener.CreateNewShortLink(ctx, "https://example.com/page", userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/"+code+"?utm_source=newsletter", nil)
+	r.SetPathValue("code", code)
+	w := httptest.NewRecorder()
+	s.handlerRedirect(w, r)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/page" {
+		t.Fatalf("redirect = %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	r.SetPathValue("code", "unknown")
+	w = httptest.NewRecorder()
+	s.handlerRedirect(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown code status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// The click is stored in the background.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		counters, err := db.GetClickCounters(ctx, userId, []string{code}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counters[code].Total == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("click was not counted: %+v", counters)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/export?code="+code, nil)
+	r.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	s.handleExport(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), code) || !strings.Contains(w.Body.String(), "newsletter") {
+		t.Errorf("export does not contain the click: %s", w.Body.String())
+	}
+}
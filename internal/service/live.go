@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"linkshortener/internal/referrer"
+	"linkshortener/internal/types"
+	"linkshortener/internal/useragent"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const liveHeartbeat = 15 * time.Second
+
+func (s *Server) publishLive(data types.ClickData) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if s.live == nil || !s.live.Watched(ctx, data.ShortCode) {
+		return
+	}
+
+	ua := useragent.Parse(data.UserAgent)
+	host := referrer.Normalize(data.Referer)
+	event := types.ClickEvent{
+		ShortCode:   data.ShortCode,
+		Country:     s.geo.Lookup(data.IP).Country,
+		Device:      ua.Device,
+		Browser:     ua.Browser,
+		OS:          ua.OS,
+		RefererHost: host,
+		Channel:     referrer.Classify(host, data.UTMSource, data.UTMMedium),
+		ClickedAt:   data.ClickedAt,
+	}
+	if err := s.live.Publish(ctx, event); err != nil {
+		slog.Warn("Failed to publish live click", "link", data.ShortCode, "error", err)
+	}
+}
+
+// handleLive streams clicks on one of the caller's links as Server-Sent
+// Events until the client disconnects.
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	if s.live == nil {
+		http.Error(w, "live stream is disabled", http.StatusServiceUnavailable)
+		return
+	}
+	userId, ok := s.authenticate(w, r, true)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	code := r.PathValue("code")
+	link, err := s.db.GetLinkCacheByCode(ctx, code)
+	if err != nil || link.UserID != userId {
+		http.NotFound(w, r)
+		return
+	}
+
+	events, cancel, err := s.live.Subscribe(ctx, code)
+	if err != nil {
+		slog.Error("failed to subscribe to live clicks", "short_code", code, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(": connected\n\n")); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	slog.Info("live stream opened", "user_id", userId, "short_code", code)
+	defer slog.Info("live stream closed", "user_id", userId, "short_code", code)
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var msg []byte
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.streamsDone:
+			return
+		case <-heartbeat.C:
+			msg = []byte(": ping\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			msg = append([]byte("event: click\ndata: "), data...)
+			msg = append(msg, "\n\n"...)
+		}
+		if _, err := w.Write(msg); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"linkshortener/internal/apitoken"
+	"linkshortener/internal/live"
+	"linkshortener/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestHandleLive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := NewMockServerDB(ctrl)
+	broker := live.NewBroker(nil, nil)
+	s := NewServer(ServerConfig{Live: broker}, db, nil)
+
+	db.EXPECT().GetUserIDByAPITokenHash(gomock.Any(), apitoken.Hash("token")).Return(int64(7), nil).AnyTimes()
+	db.EXPECT().GetLinkCacheByCode(gomock.Any(), "abc").Return(&types.LinkCache{UserID: 7}, nil).AnyTimes()
+	db.EXPECT().GetLinkCacheByCode(gomock.Any(), "foreign").Return(&types.LinkCache{UserID: 8}, nil).AnyTimes()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/links/{code}/live", s.handleLive)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/links/foreign/live?access_token=token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("foreign link status = %d, want 404", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/links/abc/live", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, ": connected") {
+		t.Fatalf("unexpected first line %q", line)
+	}
+
+	s.publishLive(types.ClickData{ShortCode: "abc", UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", Referer: "https://t.co/x"})
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if !strings.Contains(data, `"referer_host":"t.co"`) || !strings.Contains(data, `"channel":"social"`) {
+				t.Errorf("unexpected event %s", data)
+			}
+			return
+		}
+	}
+}
@@ -35,6 +35,34 @@ func (m *MockServerDB) EXPECT() *MockServerDBMockRecorder {
 	return m.recorder
 }
 
+// CountClick mocks base method.
+func (m *MockServerDB) CountClick(ctx context.Context, data types.ClickData) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClick", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CountClick indicates an expected call of CountClick.
+func (mr *MockServerDBMockRecorder) CountClick(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClick", reflect.TypeOf((*MockServerDB)(nil).CountClick), ctx, data)
+}
+
+// ExportClicks mocks base method.
+func (m *MockServerDB) ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportClicks", ctx, filter, limit, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportClicks indicates an expected call of ExportClicks.
+func (mr *MockServerDBMockRecorder) ExportClicks(ctx, filter, limit, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportClicks", reflect.TypeOf((*MockServerDB)(nil).ExportClicks), ctx, filter, limit, fn)
+}
+
 // GetLinkCacheByCode mocks base method.
 func (m *MockServerDB) GetLinkCacheByCode(ctx context.Context, shortCode string) (*types.LinkCache, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +78,21 @@ func (mr *MockServerDBMockRecorder) GetLinkCacheByCode(ctx, shortCode interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkCacheByCode", reflect.TypeOf((*MockServerDB)(nil).GetLinkCacheByCode), ctx, shortCode)
 }
 
+// GetUserIDByAPITokenHash mocks base method.
+func (m *MockServerDB) GetUserIDByAPITokenHash(ctx context.Context, tokenHash string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserIDByAPITokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserIDByAPITokenHash indicates an expected call of GetUserIDByAPITokenHash.
+func (mr *MockServerDBMockRecorder) GetUserIDByAPITokenHash(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDByAPITokenHash", reflect.TypeOf((*MockServerDB)(nil).GetUserIDByAPITokenHash), ctx, tokenHash)
+}
+
 // PushClick mocks base method.
 func (m *MockServerDB) PushClick(data types.ClickData) {
 	m.ctrl.T.Helper()
@@ -79,6 +79,20 @@ func (mr *MockShortenerDBMockRecorder) GetLinkCacheByCode(ctx, shortCode interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkCacheByCode", reflect.TypeOf((*MockShortenerDB)(nil).GetLinkCacheByCode), ctx, shortCode)
 }
 
+// InitClickCounters mocks base method.
+func (m *MockShortenerDB) InitClickCounters(ctx context.Context, userId int64, shortCode string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitClickCounters", ctx, userId, shortCode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitClickCounters indicates an expected call of InitClickCounters.
+func (mr *MockShortenerDBMockRecorder) InitClickCounters(ctx, userId, shortCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitClickCounters", reflect.TypeOf((*MockShortenerDB)(nil).InitClickCounters), ctx, userId, shortCode)
+}
+
 // SetShortCode mocks base method.
 func (m *MockShortenerDB) SetShortCode(ctx context.Context, id int64, shortCode string) error {
 	m.ctrl.T.Helper()
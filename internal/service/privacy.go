@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+type IPMode string
+
+const (
+	IPModeFull     IPMode = "full"
+	IPModeTruncate IPMode = "truncate"
+	IPModeNone     IPMode = "none"
+)
+
+type PrivacyConfig struct {
+	IPMode   IPMode
+	HonorDNT bool
+}
+
+func ParseIPMode(mode string) (IPMode, error) {
+	switch m := IPMode(mode); m {
+	case IPModeFull, IPModeTruncate, IPModeNone:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown ip mode %q", mode)
+	}
+}
+
+// anonymizeIP applies the configured IP mode. Truncation keeps the /24
+// network of IPv4 addresses and the /48 network of IPv6 addresses, which is
+// still enough for country and coarse region lookups.
+func (p PrivacyConfig) anonymizeIP(rawIP string, r *http.Request) string {
+	if p.HonorDNT && optedOut(r) {
+		return ""
+	}
+
+	switch p.IPMode {
+	case IPModeFull:
+		return rawIP
+	case IPModeNone:
+		return ""
+	}
+
+	ip := net.ParseIP(rawIP)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func optedOut(r *http.Request) bool {
+	return r.Header.Get("DNT") == "1" || r.Header.Get("Sec-GPC") == "1"
+}
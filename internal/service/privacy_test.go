@@ -0,0 +1,93 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PrivacyConfig
+		ip      string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:   "full keeps the address",
+			config: PrivacyConfig{IPMode: IPModeFull},
+			ip:     "203.0.113.57",
+			want:   "203.0.113.57",
+		},
+		{
+			name:   "truncate keeps the IPv4 /24",
+			config: PrivacyConfig{IPMode: IPModeTruncate},
+			ip:     "203.0.113.57",
+			want:   "203.0.113.0",
+		},
+		{
+			name:   "truncate keeps the IPv6 /48",
+			config: PrivacyConfig{IPMode: IPModeTruncate},
+			ip:     "2001:db8:abcd:12:3456::1",
+			want:   "2001:db8:abcd::",
+		},
+		{
+			name:   "truncate treats IPv4-mapped IPv6 as IPv4",
+			config: PrivacyConfig{IPMode: IPModeTruncate},
+			ip:     "::ffff:203.0.113.57",
+			want:   "203.0.113.0",
+		},
+		{
+			name:   "truncate drops unparsable addresses",
+			config: PrivacyConfig{IPMode: IPModeTruncate},
+			ip:     "not-an-ip",
+			want:   "",
+		},
+		{
+			name:   "none drops the address",
+			config: PrivacyConfig{IPMode: IPModeNone},
+			ip:     "203.0.113.57",
+			want:   "",
+		},
+		{
+			name:    "DNT drops the address",
+			config:  PrivacyConfig{IPMode: IPModeFull, HonorDNT: true},
+			ip:      "203.0.113.57",
+			headers: map[string]string{"DNT": "1"},
+			want:    "",
+		},
+		{
+			name:    "GPC drops the address",
+			config:  PrivacyConfig{IPMode: IPModeTruncate, HonorDNT: true},
+			ip:      "203.0.113.57",
+			headers: map[string]string{"Sec-GPC": "1"},
+			want:    "",
+		},
+		{
+			name:    "DNT 0 is not an opt-out",
+			config:  PrivacyConfig{IPMode: IPModeFull, HonorDNT: true},
+			ip:      "203.0.113.57",
+			headers: map[string]string{"DNT": "0"},
+			want:    "203.0.113.57",
+		},
+		{
+			name:    "DNT is ignored unless honored",
+			config:  PrivacyConfig{IPMode: IPModeFull},
+			ip:      "203.0.113.57",
+			headers: map[string]string{"DNT": "1", "Sec-GPC": "1"},
+			want:    "203.0.113.57",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/abc", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			if got := tt.config.anonymizeIP(tt.ip, r); got != tt.want {
+				t.Errorf("anonymizeIP(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
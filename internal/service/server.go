@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"expvar"
+	customerrs "linkshortener/internal/customErrs"
+	"linkshortener/internal/geoip"
+	"linkshortener/internal/live"
 	"linkshortener/internal/types"
-	"net"
+	"log/slog"
 	"net/http"
-	"strings"
+	"net/netip"
+	"sync"
 	"time"
 )
 
@@ -15,43 +19,107 @@ import (
 type ServerDB interface {
 	GetLinkCacheByCode(ctx context.Context, shortCode string) (*types.LinkCache, error)
 	PushClick(data types.ClickData)
+	CountClick(ctx context.Context, data types.ClickData) error
+	GetUserIDByAPITokenHash(ctx context.Context, tokenHash string) (int64, error)
+	ExportClicks(ctx context.Context, filter types.AnalyticsFilter, limit int, fn func(types.Analytic) error) error
+}
+
+type ServerConfig struct {
+	Port string
+	// AdminAddr is where /debug/vars is served, e.g. "127.0.0.1:9090"; empty
+	// disables it. Keep it off the public network.
+	AdminAddr      string
+	Privacy        PrivacyConfig
+	TrustedProxies []netip.Prefix
+	// ForwardedHeader is the only header read from trusted proxies, see
+	// getClientIP. Defaults to X-Forwarded-For.
+	ForwardedHeader ForwardedHeader
+	ExportMaxRows   int
+	Live            *live.Broker
+	Geo             *geoip.Resolver
 }
 
 type Server struct {
-	port      string
-	db        ServerDB
-	shortener *Shortener
+	port            string
+	adminAddr       string
+	privacy         PrivacyConfig
+	trustedProxies  []netip.Prefix
+	forwardedHeader ForwardedHeader
+	exportMaxRows   int
+	live            *live.Broker
+	geo             *geoip.Resolver
+	db              ServerDB
+	shortener       *Shortener
+	streamsDone     chan struct{}
+	closeStreams    sync.Once
 }
 
-func NewServer(port string, db ServerDB, shortener *Shortener) *Server {
+func NewServer(cfg ServerConfig, db ServerDB, shortener *Shortener) *Server {
+	if cfg.ExportMaxRows <= 0 {
+		cfg.ExportMaxRows = defaultExportMaxRows
+	}
+	if cfg.ForwardedHeader == "" {
+		cfg.ForwardedHeader = HeaderXForwardedFor
+	}
 	return &Server{
-		port:      port,
-		db:        db,
-		shortener: shortener,
+		port:            cfg.Port,
+		adminAddr:       cfg.AdminAddr,
+		privacy:         cfg.Privacy,
+		trustedProxies:  cfg.TrustedProxies,
+		forwardedHeader: cfg.ForwardedHeader,
+		exportMaxRows:   cfg.ExportMaxRows,
+		live:            cfg.Live,
+		geo:             cfg.Geo,
+		db:              db,
+		shortener:       shortener,
+		streamsDone:     make(chan struct{}),
 	}
 }
 
 func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /{code}", s.handlerRedirect)
+	mux.HandleFunc("GET /api/v1/export", s.handleExport)
+	mux.HandleFunc("GET /api/v1/links/{code}/live", s.handleLive)
 	srv := &http.Server{
 		Addr:    ":" + s.port,
 		Handler: mux,
 	}
-	errChan := make(chan error, 1)
-	go func() { errChan <- srv.ListenAndServe() }()
+	// Shutdown does not cancel request contexts, so long-lived streams are
+	// told to finish explicitly.
+	srv.RegisterOnShutdown(func() {
+		s.closeStreams.Do(func() { close(s.streamsDone) })
+	})
+	servers := []*http.Server{srv}
+
+	if s.adminAddr != "" {
+		admin := http.NewServeMux()
+		admin.Handle("GET /debug/vars", expvar.Handler())
+		servers = append(servers, &http.Server{
+			Addr:    s.adminAddr,
+			Handler: admin,
+		})
+	}
+
+	errChan := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() { errChan <- srv.ListenAndServe() }()
+	}
+
+	var err error
 	select {
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	case err = <-errChan:
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, srv := range servers {
 		_ = srv.Shutdown(shutdownCtx)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
 		return nil
-	case err := <-errChan:
-		if errors.Is(err, http.ErrServerClosed) {
-			return nil
-		}
-		return err
 	}
+	return err
 }
 
 func (s *Server) handlerRedirect(w http.ResponseWriter, r *http.Request) {
@@ -61,12 +129,14 @@ func (s *Server) handlerRedirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	ip := s.getClientIP(r)
+	ip := s.privacy.anonymizeIP(s.getClientIP(r), r)
 	userAgent := r.UserAgent()
 	referer := r.Referer()
+	query := r.URL.Query()
+	clickedAt := time.Now().UTC()
 	linkCache, err := s.db.GetLinkCacheByCode(ctx, code)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, customerrs.ErrNoFound) {
 			http.NotFound(w, r)
 			return
 		}
@@ -76,33 +146,33 @@ func (s *Server) handlerRedirect(w http.ResponseWriter, r *http.Request) {
 
 	go func() {
 		newClickData := types.ClickData{
-			UserId:    linkCache.UserID,
-			ShortCode: code,
-			IP:        ip,
-			UserAgent: userAgent,
-			Referer:   referer,
+			UserId:      linkCache.UserID,
+			ShortCode:   code,
+			IP:          ip,
+			UserAgent:   userAgent,
+			Referer:     referer,
+			UTMSource:   query.Get("utm_source"),
+			UTMMedium:   query.Get("utm_medium"),
+			UTMCampaign: query.Get("utm_campaign"),
+			UTMTerm:     query.Get("utm_term"),
+			UTMContent:  query.Get("utm_content"),
+			ClickedAt:   clickedAt,
+			CityOptOut:  linkCache.CityOptOut,
 		}
 		s.db.PushClick(newClickData)
+		s.countClick(newClickData)
+		s.publishLive(newClickData)
 	}()
 
 	http.Redirect(w, r, linkCache.OriginalLink, http.StatusFound)
 }
 
-func (s *Server) getClientIP(r *http.Request) string {
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		return ip
-	}
-
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+// countClick updates the real-time counters shown in the bot before the
+// click reaches ClickHouse.
+func (s *Server) countClick(data types.ClickData) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.db.CountClick(ctx, data); err != nil {
+		slog.Warn("Failed to update click counters", "link", data.ShortCode, "error", err)
 	}
-	return ip
 }
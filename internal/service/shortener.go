@@ -2,10 +2,10 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	customerrs "linkshortener/internal/customErrs"
 	"linkshortener/internal/types"
+	"log/slog"
 	"regexp"
 	"slices"
 	"strings"
@@ -19,6 +19,7 @@ type ShortenerDB interface {
 	SetShortCode(ctx context.Context, id int64, shortCode string) error
 	CreateLink(ctx context.Context, userID int64, originalLink string) (int64, error)
 	GetLinkCacheByCode(ctx context.Context, shortCode string) (*types.LinkCache, error)
+	InitClickCounters(ctx context.Context, userId int64, shortCode string) error
 }
 type Shortener struct {
 	database ShortenerDB
@@ -37,7 +38,7 @@ func (s *Shortener) CreateNewShortLink(ctx context.Context, originalLink string,
 
 	for {
 		hasLink, err := s.database.GetLinkCacheByCode(ctx, shortCode)
-		if hasLink == nil && errors.Is(err, sql.ErrNoRows) {
+		if hasLink == nil && errors.Is(err, customerrs.ErrNoFound) {
 			break
 		}
 		if err != nil {
@@ -55,8 +56,10 @@ func (s *Shortener) CreateNewShortLink(ctx context.Context, originalLink string,
 	}
 
 	if err := s.database.SetShortCode(ctx, linkId, shortCode); err != nil {
+		s.discardLink(ctx, userId, linkId)
 		return "", err
 	}
+	s.initClickCounters(ctx, userId, shortCode)
 	return shortCode, nil
 }
 
@@ -72,12 +75,30 @@ func (s *Shortener) CreateNewCustomShortLink(ctx context.Context, originalLink,
 	}
 
 	if err := s.database.SetShortCode(ctx, linkId, shortCode); err != nil {
+		// The code may have been taken since it was checked above.
+		s.discardLink(ctx, userId, linkId)
 		return err
 	}
-
+	s.initClickCounters(ctx, userId, shortCode)
 	return nil
 }
 
+// discardLink deletes a link whose short code could not be set, so that the
+// unpublished row does not show up in the user's links.
+func (s *Shortener) discardLink(ctx context.Context, userId, linkId int64) {
+	if err := s.database.DeleteLinkById(context.WithoutCancel(ctx), userId, linkId); err != nil {
+		slog.Warn("Failed to delete unpublished link", "link_id", linkId, "error", err)
+	}
+}
+
+// initClickCounters starts the real-time counters of a new link. The link
+// is already published, so a failure only leaves its counters hidden.
+func (s *Shortener) initClickCounters(ctx context.Context, userId int64, shortCode string) {
+	if err := s.database.InitClickCounters(ctx, userId, shortCode); err != nil {
+		slog.Warn("Failed to initialise click counters", "short_code", shortCode, "error", err)
+	}
+}
+
 func (s *Shortener) base65Encode(linkId int64) string {
 	if linkId == 0 {
 		return string(alphabet[0])
@@ -109,7 +130,8 @@ func (s *Shortener) base65Decode(shortCode string) (int64, error) {
 	return res, nil
 }
 
+var shortCodePattern = regexp.MustCompile(`^[a-zA-Z0-9\-_!~]{1,20}$`)
+
 func (s *Shortener) IsValidShortCode(code string) bool {
-	matched, _ := regexp.MatchString(`^[a-zA-Z0-9\-_!~]{1,20}$`, code)
-	return matched
+	return shortCodePattern.MatchString(code)
 }
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	customerrs "linkshortener/internal/customErrs"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestCreateNewCustomShortLinkDeletesLinkWhenCodeIsTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := NewMockShortenerDB(ctrl)
+	s := NewShortener(db)
+
+	// The code is free when checked but taken before it is set.
+	db.EXPECT().GetLinkCacheByCode(gomock.Any(), "promo").Return(nil, customerrs.ErrNoFound)
+	db.EXPECT().CreateLink(gomock.Any(), int64(7), "https://example.com").Return(int64(12), nil)
+	db.EXPECT().SetShortCode(gomock.Any(), int64(12), "promo").Return(customerrs.ErrCodeIsBusy)
+	db.EXPECT().DeleteLinkById(gomock.Any(), int64(7), int64(12)).Return(nil)
+
+	err := s.CreateNewCustomShortLink(context.Background(), "https://example.com", "promo", 7)
+	if !errors.Is(err, customerrs.ErrCodeIsBusy) {
+		t.Fatalf("err = %v, want customerrs.ErrCodeIsBusy", err)
+	}
+}
+
+func TestCreateNewShortLinkDeletesLinkWhenCodeCannotBeSet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := NewMockShortenerDB(ctrl)
+	s := NewShortener(db)
+	code := s.base65Encode(12)
+
+	db.EXPECT().CreateLink(gomock.Any(), int64(7), "https://example.com").Return(int64(12), nil)
+	db.EXPECT().GetLinkCacheByCode(gomock.Any(), code).Return(nil, customerrs.ErrNoFound)
+	db.EXPECT().SetShortCode(gomock.Any(), int64(12), code).Return(customerrs.ErrCodeIsBusy)
+	db.EXPECT().DeleteLinkById(gomock.Any(), int64(7), int64(12)).Return(nil)
+
+	if _, err := s.CreateNewShortLink(context.Background(), "https://example.com", 7); !errors.Is(err, customerrs.ErrCodeIsBusy) {
+		t.Fatalf("err = %v, want customerrs.ErrCodeIsBusy", err)
+	}
+}
@@ -0,0 +1,54 @@
+package types
+
+import "time"
+
+const (
+	AlertSpike = "spike"
+	AlertDrop  = "drop"
+)
+
+// LinkAlert holds the alert settings of a link. Links without stored
+// settings use DefaultLinkAlert.
+type LinkAlert struct {
+	UserId      int64      `json:"user_id" db:"user_id"`
+	ShortCode   string     `json:"short_code" db:"short_code"`
+	Enabled     bool       `json:"enabled" db:"enabled"`
+	SpikeFactor float64    `json:"spike_factor" db:"spike_factor"`
+	MinClicks   int64      `json:"min_clicks" db:"min_clicks"`
+	DropAlerts  bool       `json:"drop_alerts" db:"drop_alerts"`
+	MutedUntil  *time.Time `json:"muted_until" db:"muted_until"`
+}
+
+func DefaultLinkAlert(userId int64, shortCode string) LinkAlert {
+	return LinkAlert{
+		UserId:      userId,
+		ShortCode:   shortCode,
+		Enabled:     true,
+		SpikeFactor: 3,
+		MinClicks:   20,
+		DropAlerts:  true,
+	}
+}
+
+func (a LinkAlert) Muted(now time.Time) bool {
+	return a.MutedUntil != nil && a.MutedUntil.After(now)
+}
+
+// LinkRate compares the clicks of a link in a recent window with the clicks
+// in the baseline period right before it.
+type LinkRate struct {
+	UserId    int64  `json:"user_id" db:"user_id"`
+	ShortCode string `json:"short_code" db:"short_code"`
+	Recent    int64  `json:"recent" db:"recent"`
+	Baseline  int64  `json:"baseline" db:"baseline"`
+}
+
+type Alert struct {
+	UserId       int64
+	ShortCode    string
+	Kind         string
+	Recent       int64
+	BaselineRate float64
+	WindowStart  time.Time
+	WindowEnd    time.Time
+}
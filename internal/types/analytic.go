@@ -3,19 +3,81 @@ package types
 import "time"
 
 type ClickData struct {
-	UserId    int64  `json:"user_id" db:"user_id"`
-	ShortCode string `json:"short_code" db:"short_code"`
-	IP        string `json:"ip" db:"ip"`
-	UserAgent string `json:"user_agent" db:"user_agent"`
-	Referer   string `json:"referer" db:"referer"`
+	UserId      int64     `json:"user_id" db:"user_id"`
+	ShortCode   string    `json:"short_code" db:"short_code"`
+	IP          string    `json:"ip" db:"ip"`
+	UserAgent   string    `json:"user_agent" db:"user_agent"`
+	Referer     string    `json:"referer" db:"referer"`
+	UTMSource   string    `json:"utm_source" db:"utm_source"`
+	UTMMedium   string    `json:"utm_medium" db:"utm_medium"`
+	UTMCampaign string    `json:"utm_campaign" db:"utm_campaign"`
+	UTMTerm     string    `json:"utm_term" db:"utm_term"`
+	UTMContent  string    `json:"utm_content" db:"utm_content"`
+	ClickedAt   time.Time `json:"clicked_at" db:"clicked_at"`
+	CityOptOut  bool      `json:"city_opt_out" db:"city_opt_out"`
 }
 
 type Analytic struct {
-	UserId    int64     `json:"user_id" db:"user_id"`
-	ShortCode string    `json:"short_code" db:"short_code"`
-	Country   string    `json:"country" db:"country"`
-	City      string    `json:"city" db:"city"`
-	UserAgent string    `json:"user_agent" db:"user_agent"`
-	Referer   string    `json:"referer" db:"referer"`
-	ClickedAt time.Time `json:"clicked_at" db:"clicked_at"`
+	UserId      int64     `json:"user_id" db:"user_id"`
+	ShortCode   string    `json:"short_code" db:"short_code"`
+	Country     string    `json:"country" db:"country"`
+	Region      string    `json:"region" db:"region"`
+	City        string    `json:"city" db:"city"`
+	ASN         uint32    `json:"asn" db:"asn"`
+	ISP         string    `json:"isp" db:"isp"`
+	UserAgent   string    `json:"user_agent" db:"user_agent"`
+	Device      string    `json:"device" db:"device"`
+	Browser     string    `json:"browser" db:"browser"`
+	OS          string    `json:"os" db:"os"`
+	Referer     string    `json:"referer" db:"referer"`
+	RefererHost string    `json:"referer_host" db:"referer_host"`
+	Channel     string    `json:"channel" db:"channel"`
+	UTMSource   string    `json:"utm_source" db:"utm_source"`
+	UTMMedium   string    `json:"utm_medium" db:"utm_medium"`
+	UTMCampaign string    `json:"utm_campaign" db:"utm_campaign"`
+	UTMTerm     string    `json:"utm_term" db:"utm_term"`
+	UTMContent  string    `json:"utm_content" db:"utm_content"`
+	ClickedAt   time.Time `json:"clicked_at" db:"clicked_at"`
+}
+
+// ClickEvent is the live view of a click pushed to real-time subscribers
+// before it is enriched and stored.
+type ClickEvent struct {
+	ShortCode   string    `json:"short_code"`
+	Country     string    `json:"country"`
+	Device      string    `json:"device"`
+	Browser     string    `json:"browser"`
+	OS          string    `json:"os"`
+	RefererHost string    `json:"referer_host"`
+	Channel     string    `json:"channel"`
+	ClickedAt   time.Time `json:"clicked_at"`
+}
+
+// AnalyticsFilter selects clicks of a user (or one of their links) in the
+// half-open range [From, To). Timezone is an IANA name used to bucket hours
+// and days; empty means UTC.
+type AnalyticsFilter struct {
+	UserId    int64
+	ShortCode string
+	From      time.Time
+	To        time.Time
+	Timezone  string
+}
+
+type DailyClicks struct {
+	Day    time.Time `json:"day" db:"day"`
+	Clicks int64     `json:"clicks" db:"clicks"`
+}
+
+// HourlyClicks is one cell of the weekday/hour histogram. Weekday follows
+// ClickHouse: 1 is Monday and 7 is Sunday.
+type HourlyClicks struct {
+	Weekday int   `json:"weekday" db:"weekday"`
+	Hour    int   `json:"hour" db:"hour"`
+	Clicks  int64 `json:"clicks" db:"clicks"`
+}
+
+type CountStat struct {
+	Key    string `json:"key" db:"key"`
+	Clicks int64  `json:"clicks" db:"clicks"`
 }
@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSubscription schedules a digest at Hour:00 in the user's timezone,
+// every day or, for weekly digests, on Weekday (1 is Monday, 7 is Sunday).
+type DigestSubscription struct {
+	UserId    int64     `json:"user_id" db:"user_id"`
+	Period    string    `json:"period" db:"period"`
+	Hour      int       `json:"hour" db:"send_hour"`
+	Weekday   int       `json:"weekday" db:"weekday"`
+	NextRunAt time.Time `json:"next_run_at" db:"next_run_at"`
+	Timezone  string    `json:"timezone" db:"timezone"`
+}
+
+// Digest summarizes the clicks of a user in [From, To) and compares them with
+// the period of the same length right before it.
+type Digest struct {
+	UserId        int64
+	Period        string
+	From          time.Time
+	To            time.Time
+	Total         int64
+	PreviousTotal int64
+	TopLinks      []CountStat
+	NewCountries  []string
+}
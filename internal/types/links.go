@@ -14,4 +14,24 @@ type LinkData struct {
 type LinkCache struct {
 	OriginalLink string `json:"original_link" db:"original_link"`
 	UserID       int64  `json:"user_id" db:"user_id"`
+	CityOptOut   bool   `json:"city_opt_out" db:"city_opt_out"`
+	// CacheTTLSeconds overrides the global cache TTL for this link when set.
+	CacheTTLSeconds int64 `json:"-" db:"cache_ttl_seconds"`
+	// NotFound marks a cached lookup of a code that does not exist.
+	NotFound bool `json:"not_found,omitempty" db:"-"`
+}
+
+// ClickCounters are the real-time counters of a link kept in the cache.
+// Today counts the clicks on the requested date in the user's time zone.
+type ClickCounters struct {
+	Total    int64
+	Today    int64
+	Visitors int64
+}
+
+// Invalidation is a cache invalidation recorded in the SQL database together
+// with the change that made the cached entry of ShortCode stale.
+type Invalidation struct {
+	Id        int64  `db:"id"`
+	ShortCode string `db:"short_code"`
 }
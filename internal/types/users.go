@@ -0,0 +1,6 @@
+package types
+
+type UserSettings struct {
+	CityOptOut bool   `json:"city_opt_out" db:"city_opt_out"`
+	Timezone   string `json:"timezone" db:"timezone"`
+}
@@ -0,0 +1,85 @@
+package useragent
+
+import "strings"
+
+const Unknown = "Unknown"
+
+type Info struct {
+	Device  string
+	Browser string
+	OS      string
+}
+
+type rule struct {
+	token string
+	name  string
+}
+
+// Order matters: more specific tokens must come before the generic ones
+// they are usually combined with (e.g. "Edg/" before "Chrome/" before "Safari/").
+var browserRules = []rule{
+	{"edg/", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"yabrowser", "Yandex"},
+	{"samsungbrowser", "Samsung Internet"},
+	{"fxios", "Firefox"},
+	{"firefox/", "Firefox"},
+	{"crios", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"safari/", "Safari"},
+	{"curl/", "curl"},
+}
+
+var osRules = []rule{
+	{"windows", "Windows"},
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"ipod", "iOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+var botTokens = []string{"bot", "crawler", "spider", "preview", "facebookexternalhit", "curl/", "wget/"}
+
+func Parse(ua string) Info {
+	if strings.TrimSpace(ua) == "" {
+		return Info{Device: Unknown, Browser: Unknown, OS: Unknown}
+	}
+	lower := strings.ToLower(ua)
+	return Info{
+		Device:  device(lower),
+		Browser: match(lower, browserRules),
+		OS:      match(lower, osRules),
+	}
+}
+
+func device(ua string) string {
+	for _, token := range botTokens {
+		if strings.Contains(ua, token) {
+			return "Bot"
+		}
+	}
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return "Tablet"
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return "Tablet"
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "android"):
+		return "Mobile"
+	default:
+		return "Desktop"
+	}
+}
+
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		if strings.Contains(ua, r.token) {
+			return r.name
+		}
+	}
+	return "Other"
+}
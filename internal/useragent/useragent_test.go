@@ -0,0 +1,38 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Info
+	}{
+		{"empty", "", Info{Unknown, Unknown, Unknown}},
+		{"blank", "   ", Info{Unknown, Unknown, Unknown}},
+
+		{"Chrome on Windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", Info{"Desktop", "Chrome", "Windows"}},
+		{"Edge on Windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", Info{"Desktop", "Edge", "Windows"}},
+		{"Safari on macOS", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", Info{"Desktop", "Safari", "macOS"}},
+		{"Firefox on Linux", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", Info{"Desktop", "Firefox", "Linux"}},
+
+		{"Safari on iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", Info{"Mobile", "Safari", "iOS"}},
+		{"Chrome on iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1", Info{"Mobile", "Chrome", "iOS"}},
+		{"Chrome on Android phone", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", Info{"Mobile", "Chrome", "Android"}},
+		{"Android tablet", "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", Info{"Tablet", "Chrome", "Android"}},
+		{"iPad", "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", Info{"Tablet", "Safari", "iOS"}},
+
+		{"Googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", Info{"Bot", "Other", "Other"}},
+		{"Telegram link preview", "TelegramBot (like TwitterBot)", Info{"Bot", "Other", "Other"}},
+		{"Facebook crawler", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", Info{"Bot", "Other", "Other"}},
+		{"curl", "curl/8.4.0", Info{"Bot", "curl", "Other"}},
+		{"unknown client", "SomeClient/1.0", Info{"Desktop", "Other", "Other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.ua, got, tt.want)
+			}
+		})
+	}
+}